@@ -0,0 +1,519 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+const testJWTSecret = "access-test-jwt-secret-0123456789abcdef"
+
+// Actores de la matriz de acceso. "unknown" es el creador pidiendo un paciente que no existe.
+const (
+	actorCreator      = "creator"
+	actorCollaborator = "collaborator"
+	actorAdmin        = "admin"
+	actorOutsider     = "outsider"
+	actorUnknown      = "unknown"
+)
+
+var accessActors = []string{actorCreator, actorCollaborator, actorAdmin, actorOutsider, actorUnknown}
+
+// Expectativas más comunes: el equipo del paciente entra, el resto no
+func teamCanAccess(ok int) map[string]int {
+	return map[string]int{
+		actorCreator:      ok,
+		actorCollaborator: ok,
+		actorAdmin:        ok,
+		actorOutsider:     http.StatusForbidden,
+		actorUnknown:      http.StatusNotFound,
+	}
+}
+
+// Acciones de "dueño": solo el creador del paciente (o ADMIN)
+func ownerCanAccess(ok int) map[string]int {
+	want := teamCanAccess(ok)
+	want[actorCollaborator] = http.StatusForbidden
+	return want
+}
+
+type accessFixture struct {
+	t       *testing.T
+	db      *gorm.DB
+	router  *gin.Engine
+	users   map[string]domains.User
+	patient domains.Patient
+}
+
+type accessRoute struct {
+	name   string
+	method string
+	// request prepara los datos del actor (sesiones, reportes...) sobre patientID y arma la petición
+	request func(f *accessFixture, actor domains.User, patientID uuid.UUID) (path, body string)
+	want    map[string]int
+	// postgresOnly: el handler usa SQL propio de Postgres (series de signos vitales). Con SQLite solo
+	// se verifica el control de acceso: el equipo pasa la validación (ni 403 ni 404).
+	postgresOnly bool
+}
+
+func TestPatientAccessControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	routes := []accessRoute{
+		// --- Pacientes ---
+		{
+			name: "get patient profile", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/patients/" + patientID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "update patient", method: http.MethodPut,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/patients/" + patientID.String(), `{"care_notes":"Control semanal"}`
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "patient vitals", method: http.MethodGet, postgresOnly: true,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/patients/" + patientID.String() + "/vitals", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "list consents", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/patients/" + patientID.String() + "/consents", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "create consent version", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				key := f.pendingUpload(actor, services.BucketPatientsConsent)
+				return "/api/patients/" + patientID.String() + "/consents", `{"key":"` + key + `","signed_at":"2026-01-10"}`
+			},
+			want: ownerCanAccess(http.StatusCreated),
+		},
+		{
+			name: "revoke consent", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/patients/" + patientID.String() + "/consents/revoke", `{"reason":"Solicitud de la familia"}`
+			},
+			want: ownerCanAccess(http.StatusOK),
+		},
+
+		// --- Sesiones ---
+		{
+			name: "create session", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/", `{"patient_id":"` + patientID.String() + `","intervention_plan":"Marcha","description":"Sin novedades"}`
+			},
+			want: teamCanAccess(http.StatusCreated),
+		},
+		{
+			name: "list patient sessions", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				f.session(actor, patientID, false)
+				return "/api/sessions/?patient_id=" + patientID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "get session", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/" + f.session(actor, patientID, false).ID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "update session", method: http.MethodPut,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				session := f.session(actor, patientID, false)
+				return "/api/sessions/" + session.ID.String(), `{"patient_id":"` + patientID.String() + `","intervention_plan":"Marcha","description":"Mejor equilibrio"}`
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "delete session", method: http.MethodDelete,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/" + f.session(actor, patientID, false).ID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "list session revisions", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				session := f.session(actor, patientID, false)
+				f.revision(session, actor)
+				return "/api/sessions/" + session.ID.String() + "/revisions", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "diff session revisions", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				session := f.session(actor, patientID, false)
+				f.revision(session, actor)
+				return "/api/sessions/" + session.ID.String() + "/revisions/diff?from=1&to=current", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "sign session", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/" + f.session(actor, patientID, false).ID.String() + "/sign", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			// La co-firma es del creador del paciente: la sesión la firmó otro profesional
+			name: "cosign session", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				author := f.users[actorCollaborator]
+				if actor.ID == author.ID {
+					author = f.users[actorOutsider]
+				}
+				return "/api/sessions/" + f.session(author, patientID, true).ID.String() + "/cosign", ""
+			},
+			want: map[string]int{
+				actorCreator:      http.StatusOK,
+				actorCollaborator: http.StatusForbidden,
+				actorAdmin:        http.StatusForbidden,
+				actorOutsider:     http.StatusForbidden,
+				actorUnknown:      http.StatusNotFound,
+			},
+		},
+		{
+			name: "list addenda", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/" + f.session(actor, patientID, true).ID.String() + "/addenda", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "create addendum", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/sessions/" + f.session(actor, patientID, true).ID.String() + "/addenda", `{"content":"Se omitió registrar la SpO2"}`
+			},
+			want: teamCanAccess(http.StatusCreated),
+		},
+
+		// --- Reportes ---
+		{
+			name: "create report", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/", `{"patient_id":"` + patientID.String() + `","start_date":"2026-03-01","end_date":"2026-03-31","content":"Avance sostenido"}`
+			},
+			want: teamCanAccess(http.StatusCreated),
+		},
+		{
+			name: "list patient reports", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				f.report(actor, patientID, domains.ReportSubmitted)
+				return "/api/reports/?patient_id=" + patientID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "get report", method: http.MethodGet,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/" + f.report(actor, patientID, domains.ReportSubmitted).ID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "update report", method: http.MethodPut,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/" + f.report(actor, patientID, domains.ReportDraft).ID.String(), `{"content":"Avance sostenido"}`
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "delete report", method: http.MethodDelete,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/" + f.report(actor, patientID, domains.ReportDraft).ID.String(), ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			name: "submit report", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/" + f.report(actor, patientID, domains.ReportDraft).ID.String() + "/submit", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+		{
+			// Revisión: solo el creador del paciente (un ADMIN solo revisa los reportes del creador)
+			name: "approve report", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/" + f.report(f.users[actorCollaborator], patientID, domains.ReportSubmitted).ID.String() + "/approve", ""
+			},
+			want: map[string]int{
+				actorCreator:      http.StatusOK,
+				actorCollaborator: http.StatusForbidden,
+				actorAdmin:        http.StatusForbidden,
+				actorOutsider:     http.StatusForbidden,
+				actorUnknown:      http.StatusNotFound,
+			},
+		},
+		{
+			name: "return report", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				report := f.report(f.users[actorCollaborator], patientID, domains.ReportSubmitted)
+				return "/api/reports/" + report.ID.String() + "/return", `{"review_notes":"Falta el plan del próximo mes"}`
+			},
+			want: map[string]int{
+				actorCreator:      http.StatusOK,
+				actorCollaborator: http.StatusForbidden,
+				actorAdmin:        http.StatusForbidden,
+				actorOutsider:     http.StatusForbidden,
+				actorUnknown:      http.StatusNotFound,
+			},
+		},
+		{
+			name: "master report", method: http.MethodGet, postgresOnly: true,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				return "/api/reports/master?patient_id=" + patientID.String() + "&start_date=2026-03-01&end_date=2026-03-31", ""
+			},
+			want: teamCanAccess(http.StatusOK),
+		},
+
+		// --- Colaboraciones ---
+		{
+			name: "invite collaborator", method: http.MethodPost,
+			request: func(f *accessFixture, actor domains.User, patientID uuid.UUID) (string, string) {
+				invited := f.user("invited@test.local", domains.RoleProfessional)
+				return "/api/collaborations/invite", `{"patient_id":"` + patientID.String() + `","email":"` + invited.Email + `"}`
+			},
+			want: ownerCanAccess(http.StatusCreated),
+		},
+	}
+
+	for _, route := range routes {
+		for _, actorName := range accessActors {
+			t.Run(route.name+"/"+actorName, func(t *testing.T) {
+				f := newAccessFixture(t)
+
+				actor := f.users[actorName]
+				patientID := f.patient.ID
+				if actorName == actorUnknown {
+					actor = f.users[actorCreator]
+					patientID = uuid.New()
+				}
+
+				path, body := route.request(f, actor, patientID)
+				rec := f.do(actor, route.method, path, body)
+
+				want := route.want[actorName]
+				if route.postgresOnly && want < http.StatusBadRequest {
+					if rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden || rec.Code == http.StatusNotFound {
+						t.Fatalf("%s %s: status %d, the patient team must pass the access check: %s", route.method, path, rec.Code, rec.Body.String())
+					}
+					return
+				}
+				if rec.Code != want {
+					t.Fatalf("%s %s: status %d, want %d: %s", route.method, path, rec.Code, want, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+// newAccessFixture levanta el router real sobre una base SQLite nueva con un paciente,
+// su creador, un colaborador aceptado, un ADMIN y un profesional ajeno al equipo.
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	t.Setenv("STORAGE_DRIVER", "memory")
+	t.Setenv("SUPABASE_URL", "")
+
+	// _txlock=immediate: las notificaciones escriben en segundo plano; con BEGIN IMMEDIATE una
+	// transacción espera el lock (busy_timeout) en lugar de fallar con SQLITE_BUSY
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"),
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrateForTests(t, db)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	// Las notificaciones se crean en segundo plano: terminan antes de cerrar la base
+	t.Cleanup(services.WaitForNotifications)
+
+	cfg := config.LoadConfig()
+	f := &accessFixture{
+		t:      t,
+		db:     db,
+		router: setupRouter(cfg, services.NewDigestService(cfg)),
+		users:  make(map[string]domains.User),
+	}
+
+	f.users[actorCreator] = f.user("creator@test.local", domains.RoleProfessional)
+	f.users[actorCollaborator] = f.user("collaborator@test.local", domains.RoleProfessional)
+	f.users[actorAdmin] = f.user("admin@test.local", domains.RoleAdmin)
+	f.users[actorOutsider] = f.user("outsider@test.local", domains.RoleProfessional)
+
+	f.patient = domains.Patient{
+		ID:            uuid.New(),
+		CreatorID:     f.users[actorCreator].ID,
+		PersonalInfo:  datatypes.JSON(`{"first_name":"Ana","last_name":"Pérez","rut":"11.111.111-1"}`),
+		ConsentPDFUrl: "consent-v1.pdf",
+	}
+	f.create(&f.patient)
+	// Versión 1 del consentimiento (como la deja la migración de pacientes anteriores al historial)
+	f.create(&domains.ConsentDocument{
+		ID: uuid.New(), PatientID: f.patient.ID, Version: 1, ObjectKey: f.patient.ConsentPDFUrl,
+		UploadedByID: f.users[actorCreator].ID, SignedAt: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	f.create(&domains.Collaboration{
+		ID: uuid.New(), PatientID: f.patient.ID, ProfessionalID: f.users[actorCollaborator].ID, Status: domains.CollabAccepted,
+	})
+	// El ajeno fue invitado y rechazó: una colaboración no aceptada no da acceso
+	f.create(&domains.Collaboration{
+		ID: uuid.New(), PatientID: f.patient.ID, ProfessionalID: f.users[actorOutsider].ID, Status: domains.CollabRejected,
+	})
+	return f
+}
+
+// migrateForTests crea las tablas en SQLite. Los defaults de Postgres (uuid_generate_v4())
+// no son SQL válido en SQLite: se reemplazan por una expresión equivalente antes de migrar.
+func migrateForTests(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	models := []interface{}{
+		&domains.User{}, &domains.Patient{}, &domains.Collaboration{}, &domains.ConsentDocument{},
+		&domains.Session{}, &domains.SessionRevision{}, &domains.SessionAddendum{},
+		&domains.ProfessionalReport{}, &domains.Incident{}, &domains.IncidentAction{},
+		&domains.Attachment{}, &domains.AuditLog{}, &domains.Notification{}, &domains.NotificationPreference{},
+		&domains.EmailOutbox{}, &domains.DigestLog{}, &domains.SupportTicket{}, &domains.UploadSession{},
+	}
+
+	const sqliteUUID = "(lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-a' || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))))"
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DefaultValue == "uuid_generate_v4()" {
+				field.DefaultValue = sqliteUUID
+			}
+			// Arrays (text[]) y enums de Postgres se guardan como texto
+			if strings.HasSuffix(string(field.DataType), "[]") || field.DataType == "user_role" || field.DataType == "user_status" {
+				field.DataType = schema.String
+			}
+		}
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *accessFixture) create(value interface{}) {
+	f.t.Helper()
+	if err := f.db.Create(value).Error; err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *accessFixture) user(email string, role domains.UserRole) domains.User {
+	user := domains.User{ID: uuid.New(), Email: email, Role: role, Status: domains.StatusActive}
+	f.create(&user)
+	return user
+}
+
+func (f *accessFixture) session(author domains.User, patientID uuid.UUID, signed bool) domains.Session {
+	session := domains.Session{
+		ID: uuid.New(), PatientID: patientID, ProfessionalID: author.ID,
+		InterventionPlan: "Marcha", Description: "Sesión de prueba",
+	}
+	if signed {
+		now := time.Now()
+		session.SignedAt = &now
+		session.SignedByID = &author.ID
+	}
+	if err := f.db.Omit("Creator").Create(&session).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	return session
+}
+
+func (f *accessFixture) revision(session domains.Session, editor domains.User) {
+	f.create(&domains.SessionRevision{
+		ID: uuid.New(), SessionID: session.ID, Version: 1, Snapshot: datatypes.JSON(`{"Description":"Primera versión"}`),
+		ChangeType: domains.RevisionUpdate, EditedByID: editor.ID,
+	})
+}
+
+func (f *accessFixture) report(author domains.User, patientID uuid.UUID, status domains.ReportStatus) domains.ProfessionalReport {
+	report := domains.ProfessionalReport{
+		ID: uuid.New(), PatientID: patientID, AuthorID: author.ID, Status: status,
+		DateRangeStart: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), DateRangeEnd: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		Content: "Avance sostenido",
+	}
+	if err := f.db.Omit("Author").Create(&report).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	return report
+}
+
+// pendingUpload registra un archivo recién subido por el usuario (como /api/uploads/*)
+func (f *accessFixture) pendingUpload(owner domains.User, bucket string) string {
+	key := uuid.New().String() + ".pdf"
+	f.create(&domains.Attachment{
+		ID: uuid.New(), Bucket: bucket, ObjectKey: key, OwnerID: owner.ID, Status: domains.AttachmentPending,
+	})
+	return key
+}
+
+// do envía la petición autenticada con un JWT HS256 como los de Supabase
+func (f *accessFixture) do(user domains.User, method, path, body string) *httptest.ResponseRecorder {
+	f.t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   user.ID.String(),
+		"email": user.Email,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
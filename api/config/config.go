@@ -4,10 +4,18 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// VitalRange límites (inclusive) en la unidad canónica del signo vital
+type VitalRange struct {
+	Min float64
+	Max float64
+}
+
 type Config struct {
 	DBUrl        string
 	SupabaseURL  string
@@ -18,6 +26,70 @@ type Config struct {
 	SMTPPort     string
 	SMTPEmail    string
 	SMTPPassword string
+
+	// Transporte de correo: "smtp" (default), "log", "file" o "memory" (tests)
+	MailDriver  string
+	MailFrom    string
+	SMTPTLSMode string // "starttls", "implicit" (puerto 465) o "none"
+	MailFileDir string // Carpeta donde el driver "file" deja los .eml
+
+	// Outbox de correos: máximo de intentos antes de dead-letter y frecuencia del worker
+	EmailMaxAttempts    int
+	EmailWorkerInterval time.Duration
+
+	// Almacenamiento de archivos: "supabase" (default), "local" o "memory"
+	StorageDriver     string
+	StorageLocalDir   string
+	StorageSigningKey string // Firma de URLs temporales del driver local (obligatoria con STORAGE_DRIVER=local)
+	PublicBaseURL     string // URL pública de este backend (para armar links a /files)
+	SignedURLTTL      time.Duration
+
+	// Tamaño máximo de archivos subidos, por tipo. Las fotos además tienen un máximo de píxeles:
+	// se decodifican completas en memoria (unos 4 bytes por píxel por cada copia)
+	UploadMaxImageBytes  int64
+	UploadMaxPDFBytes    int64
+	UploadMaxImagePixels int
+
+	// Subidas por partes (reanudables): tamaño máximo del archivo (PDFs escaneados y videos, que se
+	// transmiten sin cargarlos en memoria), de cada parte y vigencia de la subida. Las fotos por partes
+	// mantienen UploadMaxImageBytes porque se decodifican en memoria. UploadProcessingTimeout es el
+	// plazo para armar y validar el archivo al completar: si el proceso se cae, pasado ese plazo la
+	// subida se da por expirada y sus partes se eliminan.
+	UploadMaxResumableBytes int64
+	UploadChunkMaxBytes     int64
+	UploadSessionTTL        time.Duration
+	UploadProcessingTimeout time.Duration
+
+	// Archivos subidos que nunca se asociaron (o se desvincularon) se eliminan tras este tiempo
+	AttachmentOrphanMaxAge time.Duration
+
+	// Fotos de sesión que quedaron en una revisión o en una sesión eliminada: forman parte de la ficha
+	// clínica y no se eliminan hasta cumplir este plazo (por defecto 15 años, el mínimo de la ficha clínica)
+	SessionEvidenceRetention time.Duration
+
+	// Sesiones: pasado este plazo desde su creación, editar exige un motivo de enmienda
+	SessionEditWindow time.Duration
+
+	// Signos vitales: rango esperado por signo (clave canónica, ej: "heart_rate").
+	// Un valor fuera del rango genera una alerta al equipo del paciente.
+	VitalsThresholds map[string]VitalRange
+
+	// Incidentes: plazo para resolver según severidad (LOW, MEDIUM, HIGH, CRITICAL).
+	// Vencido el plazo se escala, y se vuelve a escalar cada IncidentEscalationRepeat.
+	IncidentDeadlines        map[string]time.Duration
+	IncidentEscalationRepeat time.Duration
+
+	// Clave para seudonimizar pacientes en exportaciones (mismo paciente = mismo código).
+	// Sin ella las exportaciones seudonimizadas quedan deshabilitadas.
+	ExportPseudonymKey string
+
+	// Reporte maestro en PDF: nombre de la clínica y logo (PNG/JPG; vacío = logo incluido en el binario)
+	ClinicName     string
+	ClinicLogoPath string
+
+	// Resumen periódico: hora local de envío y zona horaria
+	DigestHour     int
+	DigestTimezone string
 }
 
 func LoadConfig() *Config {
@@ -47,19 +119,158 @@ func LoadConfig() *Config {
 		SMTPPort:     getEnv("SMTP_PORT", "587"),
 		SMTPEmail:    getEnv("SMTP_EMAIL", ""),
 		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		MailDriver:  getEnv("MAIL_DRIVER", "smtp"),
+		MailFrom:    getEnv("MAIL_FROM", ""),
+		SMTPTLSMode: getEnv("SMTP_TLS_MODE", ""),
+		MailFileDir: getEnv("MAIL_FILE_DIR", "./tmp/mails"),
+
+		EmailMaxAttempts:    getEnvInt("EMAIL_MAX_ATTEMPTS", 5),
+		EmailWorkerInterval: time.Duration(getEnvInt("EMAIL_WORKER_INTERVAL_SECONDS", 15)) * time.Second,
+
+		StorageDriver:     getEnv("STORAGE_DRIVER", "supabase"),
+		StorageLocalDir:   getEnv("STORAGE_LOCAL_DIR", "./tmp/storage"),
+		StorageSigningKey: getEnv("STORAGE_SIGNING_KEY", ""),
+		PublicBaseURL:     getEnv("PUBLIC_BASE_URL", ""),
+		SignedURLTTL:      time.Duration(getEnvInt("SIGNED_URL_TTL_SECONDS", 300)) * time.Second,
+
+		UploadMaxImageBytes:     int64(getEnvInt("UPLOAD_MAX_IMAGE_MB", 10)) << 20,
+		UploadMaxPDFBytes:       int64(getEnvInt("UPLOAD_MAX_PDF_MB", 20)) << 20,
+		UploadMaxImagePixels:    getEnvInt("UPLOAD_MAX_IMAGE_MEGAPIXELS", 16) * 1_000_000,
+		UploadMaxResumableBytes: int64(getEnvInt("UPLOAD_MAX_RESUMABLE_MB", 500)) << 20,
+		UploadChunkMaxBytes:     int64(getEnvInt("UPLOAD_CHUNK_MAX_MB", 5)) << 20,
+		UploadSessionTTL:        time.Duration(getEnvInt("UPLOAD_SESSION_TTL_HOURS", 24)) * time.Hour,
+		UploadProcessingTimeout: time.Duration(getEnvInt("UPLOAD_PROCESSING_TIMEOUT_MINUTES", 60)) * time.Minute,
+
+		AttachmentOrphanMaxAge:   time.Duration(getEnvInt("ATTACHMENT_ORPHAN_MAX_AGE_HOURS", 24)) * time.Hour,
+		SessionEvidenceRetention: time.Duration(getEnvInt("SESSION_EVIDENCE_RETENTION_DAYS", 15*365)) * 24 * time.Hour,
+
+		SessionEditWindow: time.Duration(getEnvInt("SESSION_EDIT_WINDOW_HOURS", 24)) * time.Hour,
+
+		VitalsThresholds: map[string]VitalRange{
+			"heart_rate":       getEnvRange("VITALS_HEART_RATE", 50, 120),
+			"systolic_bp":      getEnvRange("VITALS_SYSTOLIC_BP", 90, 160),
+			"diastolic_bp":     getEnvRange("VITALS_DIASTOLIC_BP", 50, 100),
+			"spo2":             getEnvRange("VITALS_SPO2", 92, 100),
+			"temperature":      getEnvRange("VITALS_TEMPERATURE", 35.5, 38),
+			"respiratory_rate": getEnvRange("VITALS_RESPIRATORY_RATE", 10, 24),
+			"pain_scale":       getEnvRange("VITALS_PAIN_SCALE", 0, 7),
+		},
+
+		IncidentDeadlines: map[string]time.Duration{
+			"LOW":      time.Duration(getEnvInt("INCIDENT_DEADLINE_LOW_HOURS", 168)) * time.Hour,
+			"MEDIUM":   time.Duration(getEnvInt("INCIDENT_DEADLINE_MEDIUM_HOURS", 72)) * time.Hour,
+			"HIGH":     time.Duration(getEnvInt("INCIDENT_DEADLINE_HIGH_HOURS", 24)) * time.Hour,
+			"CRITICAL": time.Duration(getEnvInt("INCIDENT_DEADLINE_CRITICAL_HOURS", 4)) * time.Hour,
+		},
+		IncidentEscalationRepeat: time.Duration(getEnvInt("INCIDENT_ESCALATION_REPEAT_HOURS", 24)) * time.Hour,
+
+		ExportPseudonymKey: getEnv("EXPORT_PSEUDONYM_KEY", ""),
+
+		ClinicName:     getEnv("CLINIC_NAME", "Bitácora Médica"),
+		ClinicLogoPath: getEnv("CLINIC_LOGO_PATH", ""),
+
+		DigestHour:     getEnvInt("DIGEST_HOUR", 7),
+		DigestTimezone: getEnv("DIGEST_TIMEZONE", "America/Santiago"),
+	}
+
+	// Defaults derivados
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = "Bitácora Médica <" + cfg.SMTPEmail + ">"
+	}
+	if cfg.PublicBaseURL == "" {
+		cfg.PublicBaseURL = "http://localhost:" + cfg.Port
+	}
+	if cfg.SMTPTLSMode == "" {
+		cfg.SMTPTLSMode = "starttls"
+		if cfg.SMTPPort == "465" {
+			cfg.SMTPTLSMode = "implicit"
+		}
 	}
 
 	// Validación de seguridad
 	if cfg.JwtSecret == "" {
 		slog.Warn("JWT_SECRET is missing. Auth verification might fail if not using JWKS.")
 	}
+	if cfg.ExportPseudonymKey == "" {
+		slog.Warn("EXPORT_PSEUDONYM_KEY is missing. Pseudonymized incident exports are disabled.")
+	}
 
 	return cfg
 }
 
+// Largo mínimo de las claves HMAC propias (firma de URLs, seudónimos)
+const minSecretKeyLength = 32
+
+// Validate revisa las claves de firma: cada propósito usa su propia clave, nunca JWT_SECRET.
+// Sin STORAGE_SIGNING_KEY cualquiera podría falsificar links del almacenamiento local, y con
+// una clave de seudónimos compartida cualquiera que la tenga puede revertir los seudónimos.
+func (c *Config) Validate() error {
+	if c.StorageDriver == "local" {
+		if err := validateSecretKey("STORAGE_SIGNING_KEY", c.StorageSigningKey, c.JwtSecret); err != nil {
+			return err
+		}
+	}
+	if c.ExportPseudonymKey != "" {
+		if err := validateSecretKey("EXPORT_PSEUDONYM_KEY", c.ExportPseudonymKey, c.JwtSecret); err != nil {
+			return err
+		}
+		if c.ExportPseudonymKey == c.StorageSigningKey {
+			return fmt.Errorf("EXPORT_PSEUDONYM_KEY must not reuse STORAGE_SIGNING_KEY")
+		}
+	}
+	return nil
+}
+
+func validateSecretKey(name, key, jwtSecret string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("%s is required", name)
+	case len(key) < minSecretKeyLength:
+		return fmt.Errorf("%s must be at least %d characters long", name, minSecretKeyLength)
+	case key == jwtSecret:
+		return fmt.Errorf("%s must not reuse JWT_SECRET", name)
+	}
+	return nil
+}
+
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer env var, using default", "key", key, "value", value)
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid number env var, using default", "key", key, "value", value)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvRange lee {PREFIX}_MIN y {PREFIX}_MAX
+func getEnvRange(prefix string, low, high float64) VitalRange {
+	return VitalRange{
+		Min: getEnvFloat(prefix+"_MIN", low),
+		Max: getEnvFloat(prefix+"_MAX", high),
+	}
+}
@@ -0,0 +1,52 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type AttachmentStatus string
+
+const (
+	AttachmentPending  AttachmentStatus = "PENDING"  // Subido, aún no asociado a una sesión/paciente
+	AttachmentLinked   AttachmentStatus = "LINKED"   // Referenciado por una entidad
+	AttachmentUnlinked AttachmentStatus = "UNLINKED" // La entidad lo quitó o fue eliminada: candidato a borrar
+	AttachmentDeleted  AttachmentStatus = "DELETED"  // Objeto eliminado del almacenamiento por el sweeper
+)
+
+// Entidades que pueden referenciar archivos
+const (
+	AttachmentEntitySession  = "SESSION"
+	AttachmentEntityConsent  = "CONSENT" // Versión del consentimiento (ConsentDocument)
+	AttachmentEntityIncident = "INCIDENT"
+)
+
+// Attachment registra cada objeto subido a los buckets privados, para saber a quién
+// pertenece y poder eliminar los archivos huérfanos.
+type Attachment struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Bucket      string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_attachment_object"`
+	ObjectKey   string         `gorm:"type:text;not null;uniqueIndex:idx_attachment_object"`
+	VariantKeys pq.StringArray `gorm:"type:text[]"`            // Otras claves derivadas (ej: miniaturas) que se borran junto al original
+	SHA256      string         `gorm:"type:varchar(64);index"` // Hash del contenido subido (integridad y deduplicación)
+	Size        int64
+
+	OwnerID    uuid.UUID  `gorm:"type:uuid;not null;index"` // Quién lo subió
+	PatientID  *uuid.UUID `gorm:"type:uuid;index"`
+	EntityType string     `gorm:"type:varchar(20)"` // SESSION, CONSENT
+	EntityID   *uuid.UUID `gorm:"type:uuid;index"`
+
+	Status     AttachmentStatus `gorm:"type:varchar(20);default:'PENDING';not null;index"`
+	LinkedAt   *time.Time
+	UnlinkedAt *time.Time
+	PurgedAt   *time.Time
+
+	// Hasta cuándo se conserva aunque quede UNLINKED: se fija al guardar una revisión (o eliminar
+	// la sesión) que referencia el archivo, ver AttachmentService.RetainForRecord
+	RetainedUntil *time.Time `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,66 @@
+package domains
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type AuditAction string
+
+const (
+	AuditView   AuditAction = "VIEW"
+	AuditCreate AuditAction = "CREATE"
+	AuditUpdate AuditAction = "UPDATE"
+	AuditDelete AuditAction = "DELETE"
+	AuditExport AuditAction = "EXPORT" // Datos clínicos entregados como archivo (ej: exportación de incidentes)
+)
+
+// Entidades auditadas
+const (
+	AuditEntityPatient        = "PATIENT"
+	AuditEntitySession        = "SESSION"
+	AuditEntityReport         = "REPORT"
+	AuditEntityCollaboration  = "COLLABORATION"
+	AuditEntityAddendum       = "SESSION_ADDENDUM"
+	AuditEntityIncident       = "INCIDENT"
+	AuditEntityConsent        = "CONSENT"              // Versión del consentimiento informado (ConsentDocument)
+	AuditEntityIncidentExport = "INCIDENT_EXPORT"      // Una exportación de incidentes (EntityID = id de la exportación)
+	AuditEntityMasterExport   = "MASTER_REPORT_EXPORT" // Un PDF del reporte maestro (EntityID = id de la exportación)
+)
+
+var ErrAuditLogImmutable = errors.New("audit log is append-only")
+
+// AuditLog registra quién vio o modificó datos clínicos (ficha clínica).
+// Es solo de inserción: los hooks de abajo impiden actualizar o borrar registros vía GORM
+// y un trigger lo exige en la base de datos (migrations/000013_audit_logs_append_only.up.sql).
+type AuditLog struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ActorID    uuid.UUID   `gorm:"type:uuid;not null;index"`
+	ActorEmail string      `gorm:"type:varchar(255)"`
+	ActorRole  string      `gorm:"type:varchar(20)"`
+	Action     AuditAction `gorm:"type:varchar(20);not null;index"`
+
+	EntityType string     `gorm:"type:varchar(30);not null;index:idx_audit_entity"`
+	EntityID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_audit_entity"`
+	PatientID  *uuid.UUID `gorm:"type:uuid;index"` // Paciente afectado (para filtrar la ficha completa)
+
+	// Cambios campo a campo: {"Campo": {"before": ..., "after": ...}}
+	Changes datatypes.JSON `gorm:"type:jsonb"`
+
+	IPAddress string `gorm:"type:varchar(64)"`
+	UserAgent string `gorm:"type:text"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
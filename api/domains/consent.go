@@ -0,0 +1,40 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsentDocument es una versión del consentimiento informado de un paciente.
+// Nunca se sobrescribe: cada PDF nuevo es una versión nueva y la revocación queda registrada.
+// Patient.ConsentPDFUrl apunta siempre a la última versión.
+type ConsentDocument struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PatientID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_consent_patient_version"`
+	Version      int       `gorm:"not null;uniqueIndex:idx_consent_patient_version"`
+	ObjectKey    string    `gorm:"type:text;not null"` // Clave en el bucket privado "patients-consent"
+	UploadedByID uuid.UUID `gorm:"type:uuid;not null"`
+	UploadedBy   User      `gorm:"foreignKey:UploadedByID"`
+
+	SignedAt  time.Time  `gorm:"type:date;not null"`
+	ExpiresAt *time.Time `gorm:"type:date"` // nil = sin vencimiento
+
+	Revoked          bool `gorm:"not null;default:false"`
+	RevokedAt        *time.Time
+	RevokedByID      *uuid.UUID `gorm:"type:uuid"`
+	RevocationReason string     `gorm:"type:text"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Input para subir una nueva versión (la clave viene de /api/uploads/consent)
+type CreateConsentInput struct {
+	Key       string `json:"key" binding:"required"`
+	SignedAt  string `json:"signed_at" binding:"required"` // YYYY-MM-DD
+	ExpiresAt string `json:"expires_at"`                   // YYYY-MM-DD (opcional)
+}
+
+type RevokeConsentInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
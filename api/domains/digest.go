@@ -0,0 +1,30 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DigestFrequency string
+
+const (
+	DigestDaily  DigestFrequency = "DAILY"
+	DigestWeekly DigestFrequency = "WEEKLY"
+)
+
+// NotifDigest es el tipo de plantilla del correo resumen
+const NotifDigest = "DIGEST"
+
+// DigestLog registra cada resumen enviado. El índice único por (usuario, frecuencia, periodo)
+// evita duplicados aunque el job se ejecute varias veces o en varias instancias.
+type DigestLog struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_digest_user_period"`
+	Frequency   DigestFrequency `gorm:"type:varchar(10);not null;uniqueIndex:idx_digest_user_period"`
+	PeriodStart time.Time       `gorm:"not null;uniqueIndex:idx_digest_user_period"`
+	PeriodEnd   time.Time       `gorm:"not null"`
+	ItemCount   int             `gorm:"not null;default:0"`
+
+	SentAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,35 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EmailStatus string
+
+const (
+	EmailPending EmailStatus = "PENDING" // En cola (o esperando el próximo reintento)
+	EmailSent    EmailStatus = "SENT"
+	EmailDead    EmailStatus = "DEAD" // Superó el máximo de intentos (dead-letter)
+)
+
+// EmailOutbox es la cola persistente de correos salientes.
+// El worker de services la procesa con reintentos y backoff exponencial.
+type EmailOutbox struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"` // Destinatario registrado (si aplica)
+	ToAddress string     `gorm:"type:varchar(255);not null"`
+	Subject   string     `gorm:"type:text;not null"`
+	Body      string     `gorm:"type:text;not null"` // Versión texto plano
+	HTMLBody  string     `gorm:"type:text"`          // Versión HTML (opcional)
+
+	Status        EmailStatus `gorm:"type:varchar(20);default:'PENDING';not null;index"`
+	Attempts      int         `gorm:"not null;default:0"`
+	LastError     string      `gorm:"type:text"`
+	NextAttemptAt time.Time   `gorm:"not null;index"`
+	SentAt        *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,109 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IncidentStatus string
+
+const (
+	IncidentOpen        IncidentStatus = "OPEN"
+	IncidentUnderReview IncidentStatus = "UNDER_REVIEW"
+	IncidentResolved    IncidentStatus = "RESOLVED"
+)
+
+type IncidentSeverity string
+
+const (
+	SeverityLow      IncidentSeverity = "LOW"
+	SeverityMedium   IncidentSeverity = "MEDIUM"
+	SeverityHigh     IncidentSeverity = "HIGH"
+	SeverityCritical IncidentSeverity = "CRITICAL"
+)
+
+// Categorías de incidente
+const (
+	IncidentCategoryFall       = "FALL"
+	IncidentCategoryMedication = "MEDICATION"
+	IncidentCategoryBehavioral = "BEHAVIORAL"
+	IncidentCategoryInjury     = "INJURY"
+	IncidentCategoryClinical   = "CLINICAL"
+	IncidentCategoryEquipment  = "EQUIPMENT"
+	IncidentCategoryOther      = "OTHER"
+)
+
+// Incident es un evento adverso con seguimiento propio (antes solo Session.HasIncident + texto).
+// Las sesiones con incidente generan uno automáticamente; también se pueden crear a mano.
+type Incident struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PatientID uuid.UUID  `gorm:"type:uuid;not null;index"`
+	SessionID *uuid.UUID `gorm:"type:uuid;uniqueIndex"` // Sesión donde se reportó (si aplica)
+
+	ReportedByID uuid.UUID `gorm:"type:uuid;not null"`
+	ReportedBy   User      `gorm:"foreignKey:ReportedByID"`
+
+	Category    string           `gorm:"type:varchar(30);not null;default:'OTHER'"`
+	Severity    IncidentSeverity `gorm:"type:varchar(20);not null;default:'MEDIUM'"`
+	Status      IncidentStatus   `gorm:"type:varchar(20);not null;default:'OPEN';index"`
+	Description string           `gorm:"type:text;not null"`
+	Photo       string           `gorm:"type:text"` // Clave en el bucket "session-evidence"
+
+	AssigneeID *uuid.UUID `gorm:"type:uuid;index"`
+	Assignee   *User      `gorm:"foreignKey:AssigneeID"`
+
+	// Plazo para resolver (según severidad) y escalamientos enviados al vencerse
+	DueAt           time.Time `gorm:"not null;index"`
+	EscalationLevel int       `gorm:"not null;default:0"`
+	LastEscalatedAt *time.Time
+
+	ResolutionNotes string `gorm:"type:text"`
+	ResolvedAt      *time.Time
+	ResolvedByID    *uuid.UUID `gorm:"type:uuid"`
+
+	Actions []IncidentAction `gorm:"foreignKey:IncidentID"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// IncidentAction es una acción de seguimiento (ej: "Control de signos cada 2 horas")
+type IncidentAction struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	IncidentID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	AuthorID    uuid.UUID  `gorm:"type:uuid;not null"`
+	Author      User       `gorm:"foreignKey:AuthorID"`
+	Description string     `gorm:"type:text;not null"`
+	DueAt       *time.Time // Fecha comprometida (opcional)
+	CompletedAt *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Input para reportar un incidente fuera de una sesión
+type CreateIncidentInput struct {
+	PatientID   string `json:"patient_id" binding:"required"`
+	Category    string `json:"category" binding:"omitempty,oneof=FALL MEDICATION BEHAVIORAL INJURY CLINICAL EQUIPMENT OTHER"`
+	Severity    string `json:"severity" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL"`
+	Description string `json:"description" binding:"required"`
+	Photo       string `json:"photo"`
+	AssigneeID  string `json:"assignee_id"`
+}
+
+// Input para clasificar, reasignar o cambiar el estado (no resuelve: para eso está /resolve)
+type UpdateIncidentInput struct {
+	Category   *string `json:"category" binding:"omitempty,oneof=FALL MEDICATION BEHAVIORAL INJURY CLINICAL EQUIPMENT OTHER"`
+	Severity   *string `json:"severity" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL"`
+	Status     *string `json:"status" binding:"omitempty,oneof=OPEN UNDER_REVIEW"`
+	AssigneeID *string `json:"assignee_id"` // "" quita la asignación
+}
+
+type CreateIncidentActionInput struct {
+	Description string `json:"description" binding:"required"`
+	DueAt       string `json:"due_at"` // RFC3339 (opcional)
+}
+
+type ResolveIncidentInput struct {
+	ResolutionNotes string `json:"resolution_notes" binding:"required"`
+}
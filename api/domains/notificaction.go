@@ -6,6 +6,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// Tipos de notificación (eventos de negocio)
+const (
+	NotifNewUser            = "NEW_USER"
+	NotifAccountStatus      = "ACCOUNT_STATUS"
+	NotifIncidentAlert      = "INCIDENT_ALERT"
+	NotifCollabInvite       = "COLLAB_INVITE"
+	NotifInviteResponse     = "INVITE_RESPONSE"
+	NotifVitalsAlert        = "VITALS_ALERT"
+	NotifIncidentEscalation = "INCIDENT_ESCALATION"
+	NotifReportReview       = "REPORT_REVIEW"
+)
+
 // Notification representa una alerta en el sistema
 type Notification struct {
 	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
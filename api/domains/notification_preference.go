@@ -0,0 +1,40 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationChannel string
+
+const (
+	ChannelInApp  NotificationChannel = "IN_APP" // Solo bandeja / campana
+	ChannelEmail  NotificationChannel = "EMAIL"  // Bandeja + email inmediato (default)
+	ChannelDigest NotificationChannel = "DIGEST" // Bandeja + incluido en el resumen periódico
+	ChannelOff    NotificationChannel = "OFF"    // No se genera nada
+)
+
+// NotificationPreference: cómo quiere recibir un usuario cada tipo de evento.
+// Si no existe fila para (usuario, evento) se usa ChannelEmail.
+type NotificationPreference struct {
+	ID        uuid.UUID           `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID           `gorm:"type:uuid;not null;uniqueIndex:idx_notification_pref_user_event"`
+	EventType string              `gorm:"type:varchar(50);not null;uniqueIndex:idx_notification_pref_user_event"`
+	Channel   NotificationChannel `gorm:"type:varchar(20);not null;default:'EMAIL'"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// Input para actualizar preferencias (PUT /api/notifications/preferences)
+type UpdatePreferencesInput struct {
+	Preferences []PreferenceInput `json:"preferences" binding:"dive"`
+	// Frecuencia del resumen para los eventos en DIGEST (se guarda en ProfileData)
+	DigestFrequency string `json:"digest_frequency" binding:"omitempty,oneof=DAILY WEEKLY"`
+}
+
+type PreferenceInput struct {
+	EventType string `json:"event_type" binding:"required"`
+	Channel   string `json:"channel" binding:"required,oneof=IN_APP EMAIL DIGEST OFF"`
+}
@@ -17,7 +17,7 @@ type Patient struct {
 
 	DisabilityReport string `gorm:"type:text"`
 	CareNotes        string `gorm:"type:text"`
-	ConsentPDFUrl    string `gorm:"type:text;not null"`
+	ConsentPDFUrl    string `gorm:"type:text;not null"` // Clave del objeto en el bucket privado "patients-consent"
 
 	CreatedAt time.Time      `gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
@@ -4,6 +4,17 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReportStatus string
+
+// Ciclo de vida: el autor redacta (DRAFT) y envía (SUBMITTED); el creador del paciente aprueba
+// (APPROVED) o devuelve a borrador con observaciones. Solo los aprobados entran al reporte maestro.
+const (
+	ReportDraft     ReportStatus = "DRAFT"
+	ReportSubmitted ReportStatus = "SUBMITTED"
+	ReportApproved  ReportStatus = "APPROVED"
 )
 
 // ProfessionalReport: El resumen periódico que escribe cada terapeuta
@@ -19,12 +30,44 @@ type ProfessionalReport struct {
 	Content            string `gorm:"type:text;not null"` // Resumen cualitativo
 	ObjectivesAchieved string `gorm:"type:text"`          // Objetivos logrados
 
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	// Revisión (los reportes previos al ciclo de vida quedan como enviados, pendientes de aprobar)
+	Status       ReportStatus `gorm:"type:varchar(20);not null;default:'SUBMITTED';index"`
+	SubmittedAt  *time.Time
+	ReviewedAt   *time.Time
+	ReviewedByID *uuid.UUID `gorm:"type:uuid"`
+	ReviewNotes  string     `gorm:"type:text"` // Observaciones al devolver a borrador
+
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
 	// Relaciones
 	Author User `gorm:"foreignKey:AuthorID"`
 }
 
+// Input para crear un reporte individual (por defecto queda como borrador)
+type CreateReportInput struct {
+	PatientID          string `json:"patient_id" binding:"required"`
+	DateRangeStart     string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	DateRangeEnd       string `json:"end_date" binding:"required"`
+	Content            string `json:"content" binding:"required"`
+	ObjectivesAchieved string `json:"objectives"`
+	Submit             bool   `json:"submit"` // true = enviar a revisión de inmediato
+}
+
+// Input para editar un borrador (solo los campos enviados)
+type UpdateReportInput struct {
+	DateRangeStart     *string `json:"start_date"`
+	DateRangeEnd       *string `json:"end_date"`
+	Content            *string `json:"content"`
+	ObjectivesAchieved *string `json:"objectives"`
+}
+
+// Input para devolver un reporte enviado a borrador
+type ReturnReportInput struct {
+	ReviewNotes string `json:"review_notes" binding:"required"`
+}
+
 // Input para generar el Reporte Maestro (Filtros)
 type MasterReportRequest struct {
 	PatientID string `form:"patient_id" binding:"required"`
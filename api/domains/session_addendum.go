@@ -0,0 +1,36 @@
+package domains
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAddendumImmutable = errors.New("session addenda are immutable")
+
+// SessionAddendum es una nota agregada a una sesión ya firmada (corrección o información posterior).
+// La sesión original no cambia; las adendas se muestran a continuación, en orden.
+type SessionAddendum struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	AuthorID  uuid.UUID `gorm:"type:uuid;not null"`
+	Author    User      `gorm:"foreignKey:AuthorID"`
+	Content   string    `gorm:"type:text;not null"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (a *SessionAddendum) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAddendumImmutable
+}
+
+func (a *SessionAddendum) BeforeDelete(tx *gorm.DB) error {
+	return ErrAddendumImmutable
+}
+
+// Input para agregar una adenda
+type CreateAddendumInput struct {
+	Content string `json:"content" binding:"required"`
+}
@@ -0,0 +1,48 @@
+package domains
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Tipo de cambio que originó la revisión
+const (
+	RevisionUpdate = "UPDATE"
+	RevisionDelete = "DELETE"
+)
+
+var ErrRevisionImmutable = errors.New("session revisions are immutable")
+
+// SessionRevision es una copia inmutable de cómo estaba una sesión antes de cada edición o
+// eliminación. La versión N es el estado previo al cambio N; el estado actual es la sesión misma.
+type SessionRevision struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SessionID  uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_revision_session_version"`
+	Version    int            `gorm:"not null;uniqueIndex:idx_revision_session_version"`
+	Snapshot   datatypes.JSON `gorm:"type:jsonb;not null"` // Campos clínicos de la versión anterior
+	ChangeType string         `gorm:"type:varchar(20);not null"`
+
+	EditedByID uuid.UUID `gorm:"type:uuid;not null"`
+	EditedBy   User      `gorm:"foreignKey:EditedByID"`
+	Reason     string    `gorm:"type:text"` // Motivo de la enmienda (obligatorio fuera de la ventana de edición)
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (r *SessionRevision) BeforeUpdate(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+func (r *SessionRevision) BeforeDelete(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+// Input de edición: los mismos campos de creación más el motivo de la enmienda
+type UpdateSessionInput struct {
+	CreateSessionInput
+	AmendmentReason string `json:"amendment_reason"`
+}
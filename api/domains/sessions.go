@@ -23,6 +23,7 @@ type Session struct {
 	PatientPerformance string         `gorm:"type:text"`
 
 	// Evidencia (Aquí usamos la librería pq)
+	// Claves de objeto en el bucket privado "session-evidence" (no URLs públicas)
 	Photos pq.StringArray `gorm:"type:text[]"`
 
 	// Lógica de Incidentes
@@ -33,11 +34,23 @@ type Session struct {
 	// Cierre
 	NextSessionNotes string `gorm:"type:text"`
 
+	// Firma clínica: una vez firmada la sesión no se edita ni elimina, solo admite adendas.
+	// La co-firma (opcional) la hace el creador del paciente.
+	SignedAt     *time.Time `gorm:"index"`
+	SignedByID   *uuid.UUID `gorm:"type:uuid"`
+	CosignedAt   *time.Time
+	CosignedByID *uuid.UUID `gorm:"type:uuid"`
+
 	CreatedAt time.Time `gorm:"index"`
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// IsSigned indica si la nota ya está cerrada
+func (s Session) IsSigned() bool {
+	return s.SignedAt != nil
+}
+
 // Estructura para el input del JSON
 type CreateSessionInput struct {
 	PatientID          string                 `json:"patient_id" binding:"required"`
@@ -51,6 +64,9 @@ type CreateSessionInput struct {
 	HasIncident     bool   `json:"has_incident"`
 	IncidentDetails string `json:"incident_details"`
 	IncidentPhoto   string `json:"incident_photo"`
+	// Clasificación del incidente (opcional; por defecto OTHER / MEDIUM)
+	IncidentCategory string `json:"incident_category" binding:"omitempty,oneof=FALL MEDICATION BEHAVIORAL INJURY CLINICAL EQUIPMENT OTHER"`
+	IncidentSeverity string `json:"incident_severity" binding:"omitempty,oneof=LOW MEDIUM HIGH CRITICAL"`
 
 	NextSessionNotes string `json:"next_session_notes"`
 }
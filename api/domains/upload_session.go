@@ -0,0 +1,50 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type UploadSessionStatus string
+
+const (
+	UploadSessionActive     UploadSessionStatus = "ACTIVE"     // Recibiendo partes
+	UploadSessionProcessing UploadSessionStatus = "PROCESSING" // Armando y validando el archivo (ExpiresAt = plazo del proceso)
+	UploadSessionCompleted  UploadSessionStatus = "COMPLETED"  // Archivo armado y validado
+	UploadSessionAborted    UploadSessionStatus = "ABORTED"    // Cancelada por el usuario o archivo inválido
+	UploadSessionExpired    UploadSessionStatus = "EXPIRED"    // No se completó a tiempo (o el proceso se cayó)
+)
+
+// Tipos de archivo que se pueden subir por partes
+const (
+	UploadKindImage   = "IMAGE"   // Evidencia de sesión (bucket session-evidence)
+	UploadKindConsent = "CONSENT" // PDF de consentimiento (bucket patients-consent)
+	UploadKindVideo   = "VIDEO"   // Video de evidencia de sesión, MP4/MOV (bucket session-evidence; solo por partes)
+)
+
+// UploadSession es una subida reanudable por partes (archivos grandes o conexiones inestables).
+// Cada parte se guarda como objeto temporal; al completar se concatenan, validan y suben como un archivo normal.
+type UploadSession struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OwnerID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Kind    string    `gorm:"type:varchar(20);not null"`
+
+	TotalSize    int64          `gorm:"not null"`
+	ReceivedSize int64          `gorm:"not null;default:0"` // Offset desde el que se espera la próxima parte
+	ChunkKeys    pq.StringArray `gorm:"type:text[]"`        // Objetos temporales, en orden
+
+	Status    UploadSessionStatus `gorm:"type:varchar(20);default:'ACTIVE';not null;index"`
+	ResultKey string              `gorm:"type:text"` // Clave del archivo final (al completar)
+	ExpiresAt time.Time           `gorm:"not null;index"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// Input para iniciar una subida por partes
+type StartUploadSessionInput struct {
+	Kind string `json:"kind" binding:"required,oneof=IMAGE CONSENT VIDEO"`
+	Size int64  `json:"size" binding:"required,gt=0"`
+}
@@ -0,0 +1,136 @@
+package domains
+
+import (
+	"encoding/json"
+)
+
+// Signos vitales reconocidos (claves en el JSONB de Session.Vitals).
+// Cada uno se guarda normalizado a su unidad canónica.
+const (
+	VitalHeartRate       = "heart_rate"       // lpm
+	VitalSystolicBP      = "systolic_bp"      // mmHg
+	VitalDiastolicBP     = "diastolic_bp"     // mmHg
+	VitalSpO2            = "spo2"             // %
+	VitalTemperature     = "temperature"      // °C
+	VitalRespiratoryRate = "respiratory_rate" // rpm
+	VitalPainScale       = "pain_scale"       // 0-10
+	VitalWeight          = "weight"           // kg
+)
+
+// VitalNames lista los signos vitales tipados, en el orden en que se muestran
+var VitalNames = []string{
+	VitalHeartRate, VitalSystolicBP, VitalDiastolicBP, VitalSpO2,
+	VitalTemperature, VitalRespiratoryRate, VitalPainScale, VitalWeight,
+}
+
+// VitalUnits unidad canónica de cada signo vital
+var VitalUnits = map[string]string{
+	VitalHeartRate:       "bpm",
+	VitalSystolicBP:      "mmHg",
+	VitalDiastolicBP:     "mmHg",
+	VitalSpO2:            "%",
+	VitalTemperature:     "C",
+	VitalRespiratoryRate: "rpm",
+	VitalPainScale:       "/10",
+	VitalWeight:          "kg",
+}
+
+// Vitals es el modelo tipado de Session.Vitals. Se guarda como un objeto JSON plano:
+// los signos conocidos con su clave canónica y, junto a ellos, cualquier otra clave
+// que envíe el cliente (Extra), para no perder datos de versiones anteriores.
+type Vitals struct {
+	HeartRate       *float64
+	SystolicBP      *float64
+	DiastolicBP     *float64
+	SpO2            *float64
+	Temperature     *float64
+	RespiratoryRate *float64
+	PainScale       *float64
+	Weight          *float64
+
+	Extra map[string]interface{}
+}
+
+// Get devuelve el valor de un signo vital por su clave canónica
+func (v Vitals) Get(name string) *float64 {
+	if field := v.field(name); field != nil {
+		return *field
+	}
+	return nil
+}
+
+// Set asigna un signo vital por su clave canónica (false si la clave no es un signo tipado)
+func (v *Vitals) Set(name string, value *float64) bool {
+	field := v.field(name)
+	if field == nil {
+		return false
+	}
+	*field = value
+	return true
+}
+
+func (v *Vitals) field(name string) **float64 {
+	switch name {
+	case VitalHeartRate:
+		return &v.HeartRate
+	case VitalSystolicBP:
+		return &v.SystolicBP
+	case VitalDiastolicBP:
+		return &v.DiastolicBP
+	case VitalSpO2:
+		return &v.SpO2
+	case VitalTemperature:
+		return &v.Temperature
+	case VitalRespiratoryRate:
+		return &v.RespiratoryRate
+	case VitalPainScale:
+		return &v.PainScale
+	case VitalWeight:
+		return &v.Weight
+	}
+	return nil
+}
+
+// IsEmpty indica si no hay ningún dato
+func (v Vitals) IsEmpty() bool {
+	for _, name := range VitalNames {
+		if v.Get(name) != nil {
+			return false
+		}
+	}
+	return len(v.Extra) == 0
+}
+
+func (v Vitals) MarshalJSON() ([]byte, error) {
+	flat := make(map[string]interface{}, len(v.Extra)+len(VitalNames))
+	for key, value := range v.Extra {
+		flat[key] = value
+	}
+	for _, name := range VitalNames {
+		if value := v.Get(name); value != nil {
+			flat[name] = *value
+		}
+	}
+	return json.Marshal(flat)
+}
+
+// UnmarshalJSON lee lo guardado en la base: los signos ya están normalizados.
+// Valores no numéricos en claves conocidas (datos antiguos) se conservan en Extra.
+func (v *Vitals) UnmarshalJSON(data []byte) error {
+	var flat map[string]interface{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return err
+	}
+
+	*v = Vitals{}
+	for key, raw := range flat {
+		if number, ok := raw.(float64); ok && v.Set(key, &number) {
+			continue
+		}
+		if v.Extra == nil {
+			v.Extra = make(map[string]interface{})
+		}
+		v.Extra[key] = raw
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListAuditLogsHandler: Consultar el registro de auditoría
+// (?patient_id=...&user_id=...&entity_type=SESSION&action=VIEW&from=2025-01-01&to=2025-01-31&page=1&limit=50)
+// Las fechas son YYYY-MM-DD (inclusive) o RFC3339.
+func ListAuditLogsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if limit < 1 || limit > 200 {
+			limit = 50
+		}
+
+		// 1. Armar filtros
+		filter := services.AuditFilter{
+			EntityType: c.Query("entity_type"),
+			Action:     c.Query("action"),
+		}
+
+		if raw := c.Query("patient_id"); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
+				return
+			}
+			filter.PatientID = &id
+		}
+		if raw := c.Query("user_id"); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+				return
+			}
+			filter.ActorID = &id
+		}
+
+		var ok bool
+		if filter.From, ok = parseAuditDate(c.Query("from"), false); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date"})
+			return
+		}
+		if filter.To, ok = parseAuditDate(c.Query("to"), true); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date"})
+			return
+		}
+
+		// 2. Consultar
+		logs, total, err := services.NewAuditService().List(filter, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": logs,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		})
+	}
+}
+
+// parseAuditDate filtro opcional: vacío = sin límite (ver services.ParseQueryDate)
+func parseAuditDate(raw string, endOfRange bool) (*time.Time, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	t, ok := services.ParseQueryDate(raw, endOfRange, time.UTC)
+	if !ok {
+		return nil, false
+	}
+	return &t, true
+}
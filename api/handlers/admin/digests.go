@@ -0,0 +1,27 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RunDigestHandler: Ejecuta ahora el resumen del último periodo completo.
+// Los usuarios que ya lo recibieron para ese periodo se omiten (DigestLog).
+func RunDigestHandler(digests *services.DigestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		frequency := domains.DigestFrequency(c.DefaultQuery("frequency", string(domains.DigestDaily)))
+		if frequency != domains.DigestDaily && frequency != domains.DigestWeekly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be DAILY or WEEKLY"})
+			return
+		}
+
+		result := digests.Run(frequency, time.Now())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Digest run completed", "data": result})
+	}
+}
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListOutboxEmailsHandler: Inspeccionar la cola de correos (?status=DEAD&page=1&limit=50)
+func ListOutboxEmailsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if limit < 1 || limit > 200 {
+			limit = 50
+		}
+
+		query := database.GetDB().Model(&domains.EmailOutbox{})
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emails"})
+			return
+		}
+
+		var emails []domains.EmailOutbox
+		if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&emails).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emails"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": emails,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		})
+	}
+}
+
+// RetryOutboxEmailHandler: Reencolar un correo fallido (reinicia los intentos)
+func RetryOutboxEmailHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email, err := services.RetryEmail(c.Param("id"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email queued for retry", "data": email})
+	}
+}
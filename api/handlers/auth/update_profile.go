@@ -16,9 +16,10 @@ type UpdateProfileInput struct {
 	FullName  string `json:"full_name"`
 	Specialty string `json:"specialty"`
 	Phone     string `json:"phone"`
-	Gender    string `json:"gender"`     // "Masculino", "Femenino", "Otro"
-	Bio       string `json:"bio"`        // "Experto en kinesiología deportiva..."
-	BirthDate string `json:"birth_date"` // YYYY-MM-DD
+	Gender    string `json:"gender"`                                 // "Masculino", "Femenino", "Otro"
+	Bio       string `json:"bio"`                                    // "Experto en kinesiología deportiva..."
+	BirthDate string `json:"birth_date"`                             // YYYY-MM-DD
+	Locale    string `json:"locale" binding:"omitempty,oneof=es en"` // Idioma de los correos
 }
 
 func UpdateProfileHandler() gin.HandlerFunc {
@@ -63,6 +64,9 @@ func UpdateProfileHandler() gin.HandlerFunc {
 		if input.BirthDate != "" {
 			currentProfile["birth_date"] = input.BirthDate
 		}
+		if input.Locale != "" {
+			currentProfile["locale"] = input.Locale
+		}
 
 		// Empaquetar y Guardar
 		newProfileJSON, err := json.Marshal(currentProfile)
@@ -6,9 +6,11 @@ import (
 	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
 	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // InviteCollabHandler ahora recibe la configuración para enviar correos
@@ -24,10 +26,15 @@ func InviteCollabHandler(cfg *config.Config) gin.HandlerFunc {
 
 		db := database.GetDB()
 
-		// 1. Validar que el paciente existe y YO soy el creador
+		// 1. Validar que el paciente existe y YO soy el creador (o ADMIN)
+		if err := services.NewAccessService().CheckPatientCreator(currentUser, input.PatientID); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
 		var patient domains.Patient
-		if err := db.Where("id = ? AND creator_id = ?", input.PatientID, currentUser.ID).First(&patient).Error; err != nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Patient not found or you are not the creator"})
+		if err := db.First(&patient, "id = ?", input.PatientID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
 			return
 		}
 
@@ -51,8 +58,17 @@ func InviteCollabHandler(cfg *config.Config) gin.HandlerFunc {
 			Status:         domains.CollabPending,
 		}
 
-		// Usamos FirstOrCreate para evitar duplicar invitaciones
-		if err := db.Where("patient_id = ? AND professional_id = ?", patient.ID, invitedUser.ID).FirstOrCreate(&collab).Error; err != nil {
+		// Usamos FirstOrCreate para evitar duplicar invitaciones (solo se audita si es nueva)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("patient_id = ? AND professional_id = ?", patient.ID, invitedUser.ID).FirstOrCreate(&collab)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityCollaboration, EntityID: collab.ID, PatientID: &collab.PatientID, After: collab,
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
 			return
 		}
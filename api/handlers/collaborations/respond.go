@@ -6,9 +6,11 @@ import (
 	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
 	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Input para el JSON del body
@@ -50,10 +52,20 @@ func RespondInvitationHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 4. Actualizar Estado
+		before := collab
 		newStatus := domains.CollabStatus(input.Status)
 		collab.Status = newStatus
 
-		if err := db.Save(&collab).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&collab).Error; err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityCollaboration, EntityID: collab.ID, PatientID: &collab.PatientID,
+				Before: before, After: collab,
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invitation status"})
 			return
 		}
@@ -63,7 +75,7 @@ func RespondInvitationHandler(cfg *config.Config) gin.HandlerFunc {
 		// Buscamos al creador usando el CreatorID que viene en collab.Patient (gracias al Preload)
 		if err := db.First(&creator, "id = ?", collab.Patient.CreatorID).Error; err == nil {
 			notifier := services.NewNotificationService(cfg)
-			notifier.NotifyInviteResponse(creator.ID, currentUser.Email, newStatus)
+			notifier.NotifyInviteResponse(creator.ID, collab.PatientID, currentUser.Email, newStatus)
 		}
 
 		c.JSON(http.StatusOK, gin.H{
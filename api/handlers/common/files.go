@@ -0,0 +1,114 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeLocalFileHandler sirve los archivos del driver de almacenamiento "local"
+// (GET /files/:bucket/*key?expires=...&signature=...), equivalente a las URLs firmadas de Supabase.
+func ServeLocalFileHandler(store *services.LocalBlobStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bucket := c.Param("bucket")
+		key := strings.TrimPrefix(c.Param("key"), "/")
+
+		// Sin firma válida no se entrega nada: los buckets son privados
+		if !store.VerifySignature(bucket, key, c.Query("expires"), c.Query("signature")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+			return
+		}
+
+		body, info, err := store.Get(c.Request.Context(), bucket, key)
+		if err != nil {
+			if errors.Is(err, services.ErrObjectNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+		defer body.Close()
+
+		c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, nil)
+	}
+}
+
+// GetFileHandler entrega un archivo clínico (GET /api/files/:bucket/*key) previa validación
+// de acceso al paciente dueño del archivo:
+// - Por defecto responde {"url": <link firmado>, "expires_at": ...}
+// - Con ?stream=true transmite el archivo a través de la API
+func GetFileHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		bucket := c.Param("bucket")
+		key := strings.TrimPrefix(c.Param("key"), "/")
+
+		storage := services.NewStorageService(cfg)
+
+		// 1. Un archivo recién subido (aún sin asociar) solo lo puede ver quien lo subió
+		attachment, err := services.NewAttachmentService(cfg).FindByObject(bucket, key)
+		if err != nil && !errors.Is(err, services.ErrObjectNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve file"})
+			return
+		}
+		ownPendingUpload := attachment != nil && attachment.Status == domains.AttachmentPending && attachment.OwnerID == currentUser.ID
+
+		if !ownPendingUpload {
+			// 2. ¿A qué paciente pertenece el archivo?
+			patientID, err := storage.ResolvePatientForObject(bucket, key)
+			if err != nil {
+				if errors.Is(err, services.ErrObjectNotFound) || errors.Is(err, services.ErrUnknownBucket) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve file"})
+				return
+			}
+
+			// 3. Seguridad: mismo criterio que el perfil del paciente
+			if err := services.NewAccessService().CheckPatientAccess(currentUser, patientID.String()); err != nil {
+				middleware.AbortWithAccessError(c, err)
+				return
+			}
+		}
+
+		// 4A. Transmitir el archivo por la API
+		if c.Query("stream") == "true" {
+			body, info, err := storage.Open(c.Request.Context(), bucket, key)
+			if err != nil {
+				if errors.Is(err, services.ErrObjectNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+				return
+			}
+			defer body.Close()
+
+			c.Header("Cache-Control", "private, no-store")
+			c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, nil)
+			return
+		}
+
+		// 4B. Link temporal
+		signedURL, expiresAt, err := storage.SignedURL(c.Request.Context(), bucket, key)
+		if err != nil {
+			if errors.Is(err, services.ErrObjectNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign file url"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"url": signedURL, "expires_at": expiresAt})
+	}
+}
@@ -1,60 +1,159 @@
 package common
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
 	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Margen para los encabezados y campos del multipart además del archivo
+const multipartOverhead = 1 << 20
+
+var errMissingFile = errors.New("file is mandatory")
+
 // UploadImageHandler maneja la subida de cualquier evidencia visual
 func UploadImageHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. Recibir archivo del form-data key "file"
-		file, err := c.FormFile("file")
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		// 1. Limitar el body (evita recibir archivos gigantes)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.UploadMaxImageBytes+multipartOverhead)
+
+		// 2. Leer el campo "file" del form-data en streaming (sin c.FormFile)
+		part, err := openFilePart(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "File is mandatory"})
+			respondFormFileError(c, err)
 			return
 		}
+		defer part.Close()
 
-		// 2. Subir usando el servicio
+		// 3. Subir usando el servicio (valida, quita EXIF y genera variantes)
 		storage := services.NewStorageService(cfg)
-		url, err := storage.UploadImage(file)
+		image, err := storage.UploadImage(c.Request.Context(), part)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image", "details": err.Error()})
+			respondUploadError(c, err, "Failed to upload image")
 			return
 		}
 
-		// 3. Devolver la URL para que el frontend la use
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Image uploaded successfully",
-			"url":     url,
-		})
+		// 4. Registrar y responder
+		respondUploaded(c, cfg, currentUser, services.BucketSessionEvidence, image, "Image uploaded successfully")
 	}
 }
 
 // UploadConsentHandler maneja la subida de PDFs de consentimiento
 func UploadConsentHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		file, err := c.FormFile("file")
+		currentUser := c.MustGet("currentUser").(domains.User)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.UploadMaxPDFBytes+multipartOverhead)
+
+		part, err := openFilePart(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "File is mandatory"})
+			respondFormFileError(c, err)
 			return
 		}
+		defer part.Close()
 
+		// El PDF se transmite directo al bucket (se valida mientras se sube)
 		storage := services.NewStorageService(cfg)
-		// Usamos el método específico para PDF que ya tenías en el servicio
-		url, err := storage.UploadConsentPDF(file)
+		consent, err := storage.UploadConsentPDF(c.Request.Context(), part, cfg.UploadMaxPDFBytes)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload PDF", "details": err.Error()})
+			respondUploadError(c, err, "Failed to upload PDF")
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Consent PDF uploaded successfully",
-			"url":     url,
-		})
+		respondUploaded(c, cfg, currentUser, services.BucketPatientsConsent, consent, "Consent PDF uploaded successfully")
+	}
+}
+
+// openFilePart busca el campo "file" del multipart sin cargar el archivo en memoria ni en disco
+func openFilePart(c *gin.Context) (io.ReadCloser, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errMissingFile
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errMissingFile
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// respondUploaded registra el archivo (PENDING hasta que se guarde en una sesión/paciente)
+// y devuelve la clave junto a links temporales para previsualizar
+func respondUploaded(c *gin.Context, cfg *config.Config, user domains.User, bucket string, file services.UploadedFile, message string) {
+	// 1. Registrar (si el usuario ya subió el mismo archivo y no lo usó, se reutiliza)
+	file, deduplicated, err := services.NewAttachmentService(cfg).RegisterUpload(c.Request.Context(), user.ID, bucket, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register upload"})
+		return
+	}
+
+	// 2. Links temporales
+	storage := services.NewStorageService(cfg)
+	previewURL, expiresAt, _ := storage.SignedURL(c.Request.Context(), bucket, file.Key)
+
+	response := gin.H{
+		"message":      message,
+		"key":          file.Key,
+		"url":          previewURL,
+		"expires_at":   expiresAt,
+		"sha256":       file.SHA256,
+		"size":         file.Size,
+		"deduplicated": deduplicated,
+	}
+
+	if len(file.Variants) > 0 {
+		variants := gin.H{}
+		for variant, key := range file.Variants {
+			variantURL, _, _ := storage.SignedURL(c.Request.Context(), bucket, key)
+			variants[variant] = gin.H{"key": key, "url": variantURL}
+		}
+		response["variants"] = variants
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondFormFileError distingue un body demasiado grande de un form sin archivo
+func respondFormFileError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "File is mandatory"})
+}
+
+// respondUploadError traduce los errores de validación del StorageService a códigos HTTP
+func respondUploadError(c *gin.Context, err error, fallback string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrFileTooLarge), errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+	case errors.Is(err, services.ErrHEICNotSupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "HEIC photos are not supported; convert them to JPEG before uploading"})
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type"})
+	case errors.Is(err, services.ErrUninspectablePDF):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "PDF uses stream filters that cannot be inspected"})
+	case errors.Is(err, services.ErrUnsafePDF):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "PDF contains embedded JavaScript"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback, "details": err.Error()})
 	}
 }
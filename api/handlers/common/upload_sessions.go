@@ -0,0 +1,132 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartUploadSessionHandler abre una subida por partes (POST /api/uploads/sessions)
+// Body: {"kind": "IMAGE"|"CONSENT"|"VIDEO", "size": <bytes totales>}
+func StartUploadSessionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.StartUploadSessionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		session, err := services.NewUploadSessionService(cfg).Start(currentUser, input.Kind, input.Size)
+		if err != nil {
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"data":           session,
+			"chunk_max_size": cfg.UploadChunkMaxBytes,
+		})
+	}
+}
+
+// GetUploadSessionHandler indica cuánto se recibió, para reanudar (GET /api/uploads/sessions/:id)
+func GetUploadSessionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		session, err := services.NewUploadSessionService(cfg).Get(currentUser, c.Param("id"))
+		if err != nil {
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": session})
+	}
+}
+
+// UploadChunkHandler recibe una parte en el body crudo (PUT /api/uploads/sessions/:id/chunks?offset=N)
+// El offset debe coincidir con lo ya recibido; si no, responde 409 con el offset esperado.
+func UploadChunkHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query param 'offset' is required"})
+			return
+		}
+
+		// La parte se transmite directo al almacenamiento
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.UploadChunkMaxBytes)
+		uploads := services.NewUploadSessionService(cfg)
+		session, err := uploads.AppendChunk(c.Request.Context(), currentUser, c.Param("id"), offset, c.Request.Body)
+		if err != nil {
+			if errors.Is(err, services.ErrUploadOffsetMismatch) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Chunk offset does not match", "expected_offset": session.ReceivedSize})
+				return
+			}
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": session})
+	}
+}
+
+// CompleteUploadSessionHandler arma el archivo y lo procesa como una subida normal
+// (POST /api/uploads/sessions/:id/complete). Responde igual que /uploads/image o /uploads/consent.
+func CompleteUploadSessionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		uploads := services.NewUploadSessionService(cfg)
+
+		session, err := uploads.Get(currentUser, c.Param("id"))
+		if err != nil {
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		file, err := uploads.Complete(c.Request.Context(), currentUser, c.Param("id"))
+		if err != nil {
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		respondUploaded(c, cfg, currentUser, services.BucketForUploadKind(session.Kind), file, "File uploaded successfully")
+	}
+}
+
+// AbortUploadSessionHandler cancela la subida y elimina las partes (DELETE /api/uploads/sessions/:id)
+func AbortUploadSessionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		if err := services.NewUploadSessionService(cfg).Abort(currentUser, c.Param("id")); err != nil {
+			respondUploadSessionError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+	}
+}
+
+func respondUploadSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload session not found"})
+	case errors.Is(err, services.ErrUploadSessionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload session is not active"})
+	case errors.Is(err, services.ErrUploadIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is incomplete"})
+	default:
+		respondUploadError(c, err, "Failed to process upload")
+	}
+}
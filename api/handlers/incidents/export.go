@@ -0,0 +1,187 @@
+package incidents
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Periodo por defecto cuando no se indica "from"
+const defaultExportRangeDays = 30
+
+// ExportIncidentsHandler exporta los incidentes del periodo para reportes regulatorios
+// GET /api/incidents/export?from=2025-01-01&to=2025-03-31&format=csv|jsonl&patient=pseudonym|full|none
+// Incluye todos los pacientes visibles para el usuario (ADMIN: todos). Por defecto el paciente va seudonimizado
+// y sin la descripción (texto libre); solo patient=full la incluye. Cada exportación queda en la auditoría.
+func ExportIncidentsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		filter, ok := exportFilter(c, currentUser)
+		if !ok {
+			return
+		}
+
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "jsonl" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'format' must be csv or jsonl"})
+			return
+		}
+
+		// 1. Encabezados de descarga: se envían con la primera fila (o al final si no hay filas),
+		// así un error de la consulta todavía puede responderse como JSON
+		filename := fmt.Sprintf("incidents_%s_%s.%s", filter.From.Format("20060102"), filter.To.Format("20060102"), format)
+		writer := csv.NewWriter(c.Writer)
+		encoder := json.NewEncoder(c.Writer)
+		started := false
+		begin := func() {
+			if started {
+				return
+			}
+			started = true
+			c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+			if format == "csv" {
+				c.Header("Content-Type", "text/csv; charset=utf-8")
+				writer.Write(services.IncidentExportColumns)
+			} else {
+				c.Header("Content-Type", "application/x-ndjson")
+			}
+			c.Status(http.StatusOK)
+		}
+
+		// 2. Escribir filas a medida que se leen (sin cargar todo en memoria)
+		count := 0
+		patients := map[uuid.UUID]bool{}
+		err := services.NewIncidentExportService(cfg).Each(filter, func(row services.IncidentExportRow) error {
+			begin()
+			count++
+			if filter.Identity == services.PatientIdentityFull {
+				if id, err := uuid.Parse(row.Patient); err == nil {
+					patients[id] = true
+				}
+			}
+			if format == "csv" {
+				return writer.Write(csvSafe(row.Values()))
+			}
+			return encoder.Encode(row)
+		})
+		if errors.Is(err, services.ErrPseudonymKeyMissing) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Pseudonymized exports are not configured; use patient=none"})
+			return
+		}
+		if err != nil && !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export incidents"})
+			return
+		}
+		begin()
+		writer.Flush()
+
+		// 3. Auditoría (también si la descarga se cortó: parte de los datos ya se entregó)
+		detail := services.AuditExportDetail{
+			ExportID: uuid.New(),
+			Format:   format,
+			Identity: filter.Identity,
+			From:     filter.From,
+			To:       filter.To,
+			Rows:     count,
+			Complete: err == nil,
+		}
+		patientIDs := make([]uuid.UUID, 0, len(patients))
+		for id := range patients {
+			patientIDs = append(patientIDs, id)
+		}
+		if auditErr := services.NewAuditService().RecordExport(middleware.AuditActor(c), domains.AuditEntityIncidentExport, detail, patientIDs); auditErr != nil {
+			slog.Error("Failed to record incident export audit", "userID", currentUser.ID, "exportID", detail.ExportID, "error", auditErr)
+		}
+
+		// Ya se enviaron los encabezados: un error a mitad de camino solo puede registrarse
+		if err != nil {
+			slog.Error("Incident export failed", "userID", currentUser.ID, "rows", count, "error", err)
+			return
+		}
+		slog.Info("Incidents exported", "userID", currentUser.ID, "format", format, "patient", filter.Identity, "rows", count)
+	}
+}
+
+// IncidentsSummaryHandler totales del periodo por categoría, severidad y profesional
+// GET /api/incidents/export/summary?from=2025-01-01&to=2025-03-31
+func IncidentsSummaryHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		filter, ok := exportFilter(c, currentUser)
+		if !ok {
+			return
+		}
+
+		summary, err := services.NewIncidentExportService(cfg).Summary(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize incidents"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": summary})
+	}
+}
+
+// exportFilter arma el periodo y el alcance (pacientes visibles) desde la query
+func exportFilter(c *gin.Context, user domains.User) (services.IncidentExportFilter, bool) {
+	filter := services.IncidentExportFilter{
+		To:         time.Now(),
+		PatientIDs: services.NewAccessService().AccessiblePatientIDs(user),
+		Identity:   c.DefaultQuery("patient", services.PatientIdentityPseudonym),
+	}
+
+	switch filter.Identity {
+	case services.PatientIdentityFull, services.PatientIdentityPseudonym, services.PatientIdentityNone:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'patient' must be full, pseudonym or none"})
+		return filter, false
+	}
+
+	if raw := c.Query("to"); raw != "" {
+		to, ok := services.ParseQueryDate(raw, true, time.UTC)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date"})
+			return filter, false
+		}
+		filter.To = to
+	}
+	filter.From = filter.To.AddDate(0, 0, -defaultExportRangeDays)
+	if raw := c.Query("from"); raw != "" {
+		from, ok := services.ParseQueryDate(raw, false, time.UTC)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date"})
+			return filter, false
+		}
+		filter.From = from
+	}
+	if !filter.From.Before(filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+		return filter, false
+	}
+	return filter, true
+}
+
+// csvSafe evita que una hoja de cálculo interprete texto libre como fórmula (=, +, -, @, tab, CR)
+func csvSafe(values []string) []string {
+	for i, value := range values {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			values[i] = "'" + value
+		}
+	}
+	return values
+}
@@ -0,0 +1,14 @@
+package incidents
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVSafe(t *testing.T) {
+	got := csvSafe([]string{"=SUM(A1)", "+1", "-1", "@cmd", "\t=1", "\r=1", "Caída", ""})
+	want := []string{"'=SUM(A1)", "'+1", "'-1", "'@cmd", "'\t=1", "'\r=1", "Caída", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("csvSafe = %q, want %q", got, want)
+	}
+}
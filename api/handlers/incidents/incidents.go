@@ -0,0 +1,347 @@
+package incidents
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateIncidentHandler reporta un incidente fuera de una sesión (POST /api/incidents)
+func CreateIncidentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.CreateIncidentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		patientID, err := uuid.Parse(input.PatientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Patient ID"})
+			return
+		}
+
+		// Seguridad: Solo el equipo del paciente puede reportar incidentes
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, patientID.String()); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		incident := domains.Incident{
+			PatientID:    patientID,
+			ReportedByID: currentUser.ID,
+			Category:     input.Category,
+			Severity:     domains.IncidentSeverity(input.Severity),
+			Description:  input.Description,
+			Photo:        services.ObjectKeyFromReference(services.BucketSessionEvidence, input.Photo),
+		}
+
+		incidentService := services.NewIncidentService(cfg)
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if input.AssigneeID != "" {
+				if err := incidentService.Assign(tx, &incident, input.AssigneeID); err != nil {
+					return err
+				}
+			}
+			if err := incidentService.Create(tx, &incident); err != nil {
+				return err
+			}
+			var photos []string
+			if incident.Photo != "" {
+				photos = []string{incident.Photo}
+			}
+			if err := services.NewAttachmentService(cfg).LinkToEntity(tx, currentUser, services.BucketSessionEvidence, photos, nil,
+				patientID, domains.AttachmentEntityIncident, incident.ID); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityIncident, EntityID: incident.ID, PatientID: &patientID, After: incident,
+			})
+		})
+		if err != nil {
+			respondIncidentError(c, err, "Failed to create incident")
+			return
+		}
+
+		// El equipo recibe la misma alerta que con un incidente reportado en sesión
+		services.NewNotificationService(cfg).NotifyIncident(patientID, incident.Description)
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Incident reported", "data": incident})
+	}
+}
+
+// ListIncidentsHandler lista incidentes de los pacientes del equipo (GET /api/incidents)
+// Filtros: ?patient_id=...&status=OPEN,UNDER_REVIEW (por defecto: sin resolver; "all" = todos)
+// &severity=HIGH&assigned=me&page=1&limit=20
+func ListIncidentsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		access := services.NewAccessService()
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		var filter services.IncidentFilter
+
+		// 1. Por paciente o por equipo
+		if raw := c.Query("patient_id"); raw != "" {
+			if err := access.CheckPatientAccess(currentUser, raw); err != nil {
+				middleware.AbortWithAccessError(c, err)
+				return
+			}
+			patientID, _ := uuid.Parse(raw)
+			filter.PatientID = &patientID
+		} else {
+			filter.PatientIDs = access.AccessiblePatientIDs(currentUser)
+		}
+
+		// 2. Estado (por defecto, los abiertos)
+		switch raw := c.Query("status"); raw {
+		case "":
+			filter.Statuses = []domains.IncidentStatus{domains.IncidentOpen, domains.IncidentUnderReview}
+		case "all":
+		default:
+			for _, status := range strings.Split(raw, ",") {
+				filter.Statuses = append(filter.Statuses, domains.IncidentStatus(strings.ToUpper(strings.TrimSpace(status))))
+			}
+		}
+
+		// 3. Severidad y responsable
+		filter.Severity = strings.ToUpper(c.Query("severity"))
+		if c.Query("assigned") == "me" {
+			filter.AssigneeID = &currentUser.ID
+		}
+
+		incidents, total, err := services.NewIncidentService(cfg).List(filter, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incidents"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": incidents,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		})
+	}
+}
+
+// GetIncidentHandler detalle con acciones de seguimiento (GET /api/incidents/:id)
+func GetIncidentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		incident, ok := loadIncident(c, cfg, currentUser)
+		if !ok {
+			return
+		}
+
+		services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntityIncident, incident.ID, &incident.PatientID)
+
+		c.JSON(http.StatusOK, gin.H{"data": incident})
+	}
+}
+
+// UpdateIncidentHandler clasifica, reasigna o cambia el estado (PATCH /api/incidents/:id)
+// Body: {"category", "severity", "status": "OPEN"|"UNDER_REVIEW", "assignee_id"} (todos opcionales)
+func UpdateIncidentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.UpdateIncidentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		incident, ok := loadIncident(c, cfg, currentUser)
+		if !ok {
+			return
+		}
+
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			// El "antes" es la fila bloqueada: un resolve o un escalamiento pudo guardarse entretanto
+			before, err := services.NewIncidentService(cfg).Update(tx, &incident, input)
+			if err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityIncident, EntityID: incident.ID, PatientID: &incident.PatientID,
+				Before: before, After: incident,
+			})
+		})
+		if err != nil {
+			respondIncidentError(c, err, "Failed to update incident")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Incident updated", "data": incident})
+	}
+}
+
+// AddIncidentActionHandler agrega una acción de seguimiento (POST /api/incidents/:id/actions)
+func AddIncidentActionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.CreateIncidentActionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var dueAt *time.Time
+		if input.DueAt != "" {
+			parsed, err := time.Parse(time.RFC3339, input.DueAt)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be an RFC3339 date"})
+				return
+			}
+			dueAt = &parsed
+		}
+
+		incident, ok := loadIncident(c, cfg, currentUser)
+		if !ok {
+			return
+		}
+
+		var action domains.IncidentAction
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			if action, err = services.NewIncidentService(cfg).AddAction(tx, incident, currentUser, input.Description, dueAt); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityIncident, EntityID: incident.ID, PatientID: &incident.PatientID,
+				After: action,
+			})
+		})
+		if err != nil {
+			respondIncidentError(c, err, "Failed to add follow-up action")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Follow-up action added", "data": action})
+	}
+}
+
+// CompleteIncidentActionHandler marca una acción como realizada (POST /api/incidents/:id/actions/:actionId/complete)
+func CompleteIncidentActionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		incident, ok := loadIncident(c, cfg, currentUser)
+		if !ok {
+			return
+		}
+
+		var action domains.IncidentAction
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			if action, err = services.NewIncidentService(cfg).CompleteAction(tx, incident, c.Param("actionId")); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityIncident, EntityID: incident.ID, PatientID: &incident.PatientID,
+				After: action,
+			})
+		})
+		if err != nil {
+			respondIncidentError(c, err, "Failed to complete follow-up action")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Follow-up action completed", "data": action})
+	}
+}
+
+// ResolveIncidentHandler cierra el incidente con notas de resolución (POST /api/incidents/:id/resolve)
+func ResolveIncidentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.ResolveIncidentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		incident, ok := loadIncident(c, cfg, currentUser)
+		if !ok {
+			return
+		}
+
+		before := incident
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := services.NewIncidentService(cfg).Resolve(tx, &incident, currentUser, input.ResolutionNotes); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityIncident, EntityID: incident.ID, PatientID: &incident.PatientID,
+				Before: before, After: incident,
+			})
+		})
+		if err != nil {
+			respondIncidentError(c, err, "Failed to resolve incident")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Incident resolved", "data": incident})
+	}
+}
+
+// loadIncident busca el incidente y valida el acceso al paciente
+func loadIncident(c *gin.Context, cfg *config.Config, user domains.User) (domains.Incident, bool) {
+	incident, err := services.NewIncidentService(cfg).Get(c.Param("id"))
+	if err != nil {
+		respondIncidentError(c, err, "Failed to fetch incident")
+		return incident, false
+	}
+
+	if err := services.NewAccessService().CheckPatientAccess(user, incident.PatientID.String()); err != nil {
+		middleware.AbortWithAccessError(c, err)
+		return incident, false
+	}
+	return incident, true
+}
+
+func respondIncidentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrIncidentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+	case errors.Is(err, services.ErrIncidentActionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Follow-up action not found"})
+	case errors.Is(err, services.ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee must be a member of the patient's care team"})
+	case errors.Is(err, services.ErrAttachmentUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Photo is not available"})
+	case errors.Is(err, services.ErrIncidentResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": "Incident is already resolved"})
+	case errors.Is(err, services.ErrIncidentActionDone):
+		c.JSON(http.StatusConflict, gin.H{"error": "Follow-up action is already completed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
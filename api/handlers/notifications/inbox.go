@@ -0,0 +1,142 @@
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListNotificationsHandler devuelve la bandeja del usuario actual
+// Filtros: ?unread=true  Paginación: ?page=1&limit=20
+func ListNotificationsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+		if limit < 1 || limit > maxPageSize {
+			limit = defaultPageSize
+		}
+
+		query := database.GetDB().Model(&domains.Notification{}).Where("user_id = ?", currentUser.ID)
+		if c.Query("unread") == "true" {
+			query = query.Where("is_read = ?", false)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		var notifications []domains.Notification
+		if err := query.Order("created_at DESC").
+			Offset((page - 1) * limit).
+			Limit(limit).
+			Find(&notifications).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": notifications,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		})
+	}
+}
+
+// UnreadCountHandler devuelve el número de notificaciones sin leer (para el ícono de campana)
+func UnreadCountHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var count int64
+		if err := database.GetDB().Model(&domains.Notification{}).
+			Where("user_id = ? AND is_read = ?", currentUser.ID, false).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"unread_count": count})
+	}
+}
+
+// MarkAsReadHandler marca una notificación propia como leída
+func MarkAsReadHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		id := c.Param("id")
+
+		// Filtramos por user_id para que nadie pueda tocar notificaciones ajenas
+		result := database.GetDB().Model(&domains.Notification{}).
+			Where("id = ? AND user_id = ?", id, currentUser.ID).
+			Update("is_read", true)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+	}
+}
+
+// MarkAllAsReadHandler marca todas las notificaciones del usuario como leídas
+func MarkAllAsReadHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		result := database.GetDB().Model(&domains.Notification{}).
+			Where("user_id = ? AND is_read = ?", currentUser.ID, false).
+			Update("is_read", true)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "All notifications marked as read",
+			"updated": result.RowsAffected,
+		})
+	}
+}
+
+// DeleteNotificationHandler elimina una notificación propia
+func DeleteNotificationHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		id := c.Param("id")
+
+		result := database.GetDB().Where("id = ? AND user_id = ?", id, currentUser.ID).Delete(&domains.Notification{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification deleted successfully"})
+	}
+}
@@ -0,0 +1,77 @@
+package notifications
+
+import (
+	"errors"
+	"net/http"
+
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPreferencesHandler devuelve el canal elegido para cada tipo de evento
+func GetPreferencesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		prefs, err := services.GetPreferences(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":             prefs,
+			"digest_frequency": services.UserDigestFrequency(currentUser),
+		})
+	}
+}
+
+// UpdatePreferencesHandler actualiza uno o varios eventos y/o la frecuencia del resumen:
+// {"preferences": [{"event_type": "NEW_USER", "channel": "DIGEST"}], "digest_frequency": "DAILY"}
+func UpdatePreferencesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.UpdatePreferencesInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.UpdatePreferences(currentUser.ID, input.Preferences); err != nil {
+			if errors.Is(err, services.ErrInvalidPreference) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+			return
+		}
+
+		if input.DigestFrequency != "" {
+			frequency := domains.DigestFrequency(input.DigestFrequency)
+			if err := services.SetDigestFrequency(currentUser, frequency); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update digest frequency"})
+				return
+			}
+		}
+
+		prefs, err := services.GetPreferences(currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+			return
+		}
+
+		digestFrequency := services.UserDigestFrequency(currentUser)
+		if input.DigestFrequency != "" {
+			digestFrequency = domains.DigestFrequency(input.DigestFrequency)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Preferences updated successfully",
+			"data":             prefs,
+			"digest_frequency": digestFrequency,
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package notifications
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Intervalo de "ping" para que proxies/balanceadores no corten la conexión por inactividad
+const heartbeatInterval = 25 * time.Second
+
+// StreamNotificationsHandler abre un canal Server-Sent Events con las notificaciones
+// nuevas del usuario autenticado (evento "notification") y un "ping" periódico.
+//
+// Reconexión: el ticket de ?ticket= se consume al abrir el stream, así que la reconexión
+// automática de EventSource (que repite la misma URL) recibe 401 y el navegador deja de
+// reintentar. Cuando el stream se corta (evento "error"), el cliente debe cerrar el
+// EventSource, pedir un ticket nuevo (POST /api/notifications/stream-ticket) y reconectar por su cuenta; lo que llegó mientras
+// tanto se recupera desde la bandeja (GET /api/notifications).
+func StreamNotificationsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		events, unsubscribe := services.GetNotificationBroker().Subscribe(currentUser.ID)
+		defer unsubscribe()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // Desactiva el buffering en Nginx
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		// Evento inicial para que el frontend sepa que la conexión quedó abierta
+		c.SSEvent("ready", gin.H{"user_id": currentUser.ID})
+		c.Writer.Flush()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case notif, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent("notification", notif)
+				return true
+			case <-heartbeat.C:
+				c.SSEvent("ping", time.Now().Unix())
+				return true
+			}
+		})
+	}
+}
+
+// IssueStreamTicketHandler entrega un ticket de un solo uso para abrir el stream:
+// GET /api/notifications/stream?ticket=... (EventSource no puede enviar el header Authorization).
+// Cada apertura o reconexión del stream necesita un ticket nuevo.
+func IssueStreamTicketHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		ticket, err := services.GetStreamTickets().Issue(currentUser.ID)
+		if err != nil {
+			slog.Error("Failed to issue stream ticket", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue stream ticket"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"ticket":     ticket,
+			"expires_in": int(services.StreamTicketTTL.Seconds()),
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package patients
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListConsentsHandler muestra el historial de consentimientos (GET /api/patients/:id/consents)
+// El acceso (equipo del paciente) ya fue validado por middleware.RequirePatientAccess
+func ListConsentsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		patientID, _ := uuid.Parse(c.Param("id"))
+		consents := services.NewConsentService(cfg)
+
+		docs, err := consents.History(patientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent history"})
+			return
+		}
+
+		status, err := consents.Status(patientID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent status"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": docs, "status": status})
+	}
+}
+
+// CreateConsentVersionHandler registra un nuevo PDF de consentimiento (POST /api/patients/:id/consents)
+// Solo el creador del paciente o un ADMIN. El PDF se sube antes vía /api/uploads/consent.
+func CreateConsentVersionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		// 1. Seguridad: acción de "dueño"
+		if err := services.NewAccessService().CheckPatientCreator(currentUser, c.Param("id")); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+		patientID, _ := uuid.Parse(c.Param("id"))
+
+		// 2. Validar input
+		var input domains.CreateConsentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		signedAt, expiresAt, err := services.ParseConsentDates(input.SignedAt, input.ExpiresAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Consent dates must be YYYY-MM-DD and expiry after signature"})
+			return
+		}
+		key := services.ObjectKeyFromReference(services.BucketPatientsConsent, input.Key)
+
+		// 3. Crear versión (y actualizar Patient.ConsentPDFUrl)
+		consents := services.NewConsentService(cfg)
+		var doc domains.ConsentDocument
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			doc, err = consents.AddVersion(tx, patientID, currentUser, key, signedAt, expiresAt)
+			if err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityConsent, EntityID: doc.ID, PatientID: &patientID, After: doc,
+			})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrAttachmentUnavailable) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Consent file is not available"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save consent version"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Consent version created", "data": doc})
+	}
+}
+
+// RevokeConsentHandler revoca el consentimiento vigente (POST /api/patients/:id/consents/revoke)
+// Mientras no se suba una nueva versión, no se pueden registrar sesiones.
+func RevokeConsentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		if err := services.NewAccessService().CheckPatientCreator(currentUser, c.Param("id")); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+		patientID, _ := uuid.Parse(c.Param("id"))
+
+		var input domains.RevokeConsentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Revocar y auditar en la misma transacción
+		consents := services.NewConsentService(cfg)
+		var doc domains.ConsentDocument
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			revoked, before, err := consents.Revoke(tx, patientID, currentUser, input.Reason)
+			if err != nil {
+				return err
+			}
+			doc = revoked
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityConsent, EntityID: doc.ID, PatientID: &patientID,
+				Before: before, After: doc,
+			})
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrConsentNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Patient has no consent document"})
+			case errors.Is(err, services.ErrConsentAlreadyRevoked):
+				c.JSON(http.StatusConflict, gin.H{"error": "Consent is already revoked"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke consent"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Consent revoked", "data": doc})
+	}
+}
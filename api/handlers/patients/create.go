@@ -2,15 +2,19 @@ package patients
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // Input Validado
@@ -22,8 +26,10 @@ type CreatePatientInput struct {
 	Email          string `json:"email" binding:"required,email"`
 	Phone          string `json:"phone"`
 	Diagnosis      string `json:"diagnosis"`
-	ConsentPDFUrl  string `json:"consent_pdf_url" binding:"required"`
-	Sex            string `json:"sex" binding:"required"` // "Masculino", "Femenino"
+	ConsentPDFUrl  string `json:"consent_pdf_url" binding:"required"` // Clave devuelta por /uploads/consent
+	ConsentSigned  string `json:"consent_signed_at"`                  // YYYY-MM-DD (default: hoy)
+	ConsentExpires string `json:"consent_expires_at"`                 // YYYY-MM-DD (opcional)
+	Sex            string `json:"sex" binding:"required"`             // "Masculino", "Femenino"
 	EmergencyPhone string `json:"emergency_phone"`
 }
 
@@ -59,6 +65,12 @@ func CreatePatientHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		consentSignedAt, consentExpiresAt, err := services.ParseConsentDates(input.ConsentSigned, input.ConsentExpires)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Consent dates must be YYYY-MM-DD and expiry after signature"})
+			return
+		}
+
 		// CALCULAR EDAD
 		age := calculateAge(input.BirthDate)
 
@@ -85,10 +97,34 @@ func CreatePatientHandler(cfg *config.Config) gin.HandlerFunc {
 		patient := domains.Patient{
 			CreatorID:     currentUser.ID,
 			PersonalInfo:  datatypes.JSON(personalInfoBytes),
-			ConsentPDFUrl: input.ConsentPDFUrl,
+			ConsentPDFUrl: services.ObjectKeyFromReference(services.BucketPatientsConsent, input.ConsentPDFUrl),
 		}
 
-		if err := database.GetDB().Create(&patient).Error; err != nil {
+		// Guardar y registrar el PDF como versión 1 del consentimiento
+		consents := services.NewConsentService(cfg)
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&patient).Error; err != nil {
+				return err
+			}
+			consent, err := consents.AddVersion(tx, patient.ID, currentUser, patient.ConsentPDFUrl, consentSignedAt, consentExpiresAt)
+			if err != nil {
+				return err
+			}
+			audit := services.NewAuditService()
+			if err := audit.Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityPatient, EntityID: patient.ID, PatientID: &patient.ID, After: patient,
+			}); err != nil {
+				return err
+			}
+			return audit.Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityConsent, EntityID: consent.ID, PatientID: &patient.ID, After: consent,
+			})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrAttachmentUnavailable) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Consent file is not available"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create patient"})
 			return
 		}
@@ -5,6 +5,8 @@ import (
 
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,9 +22,8 @@ type PatientProfileResponse struct {
 func GetPatientProfileHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		// Nota: En producción, aquí deberíamos validar si el usuario que consulta
-		// TIENE permiso (es creador O colaborador aceptado).
-		// Por brevedad, omitimos esa validación de lectura (Middleware check role).
+		// El permiso de lectura (creador, colaborador aceptado o admin)
+		// ya fue validado por middleware.RequirePatientAccess
 
 		db := database.GetDB()
 
@@ -54,7 +55,10 @@ func GetPatientProfileHandler() gin.HandlerFunc {
 		var incidentCount int64
 		db.Model(&domains.Session{}).Where("patient_id = ? AND has_incident = ?", id, true).Count(&incidentCount)
 
-		// 5. Armar Respuesta
+		// 5. Auditoría: lectura de la ficha clínica
+		services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntityPatient, patient.ID, &patient.ID)
+
+		// 6. Armar Respuesta
 		response := PatientProfileResponse{
 			Patient:        patient,
 			Team:           collaborators,
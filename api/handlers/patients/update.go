@@ -5,8 +5,11 @@ import (
 
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UpdatePatientInput struct {
@@ -35,10 +38,21 @@ func UpdatePatientHandler() gin.HandlerFunc {
 
 		// 2. Actualizar campos específicos
 		// Nota: Solo actualizamos lo que necesitamos para no sobrescribir datos sensibles accidentalmente
+		before := patient
 		patient.DisabilityReport = input.DisabilityReport
 		patient.CareNotes = input.CareNotes
 
-		if err := db.Save(&patient).Error; err != nil {
+		// 3. Guardar junto con el registro de auditoría
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&patient).Error; err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityPatient, EntityID: patient.ID, PatientID: &patient.ID,
+				Before: before, After: patient,
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update patient"})
 			return
 		}
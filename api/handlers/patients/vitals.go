@@ -0,0 +1,118 @@
+package patients
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Rango por defecto cuando no se indica "from"
+const defaultVitalsRangeDays = 90
+
+// GetPatientVitalsHandler: evolución de signos vitales del paciente para gráficos
+// GET /api/patients/:id/vitals?from=2025-01-01&to=2025-03-31&metrics=heart_rate,spo2&bucket=day&tz=America/Santiago
+// - metrics: claves canónicas separadas por coma (por defecto todas)
+// - bucket: none (un punto por sesión, por defecto), day o week
+// - Las fechas son YYYY-MM-DD (inclusive, en la zona tz) o RFC3339
+func GetPatientVitalsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// El acceso al paciente ya fue validado por middleware.RequirePatientAccess
+		patientID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+			return
+		}
+
+		// 1. Zona horaria (para cortar días/semanas y leer fechas sin hora)
+		location, err := time.LoadLocation(c.DefaultQuery("tz", cfg.DigestTimezone))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'tz' timezone"})
+			return
+		}
+
+		// 2. Rango de fechas
+		to := time.Now()
+		if raw := c.Query("to"); raw != "" {
+			parsed, ok := services.ParseQueryDate(raw, true, location)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date"})
+				return
+			}
+			to = parsed
+		}
+		from := to.AddDate(0, 0, -defaultVitalsRangeDays)
+		if raw := c.Query("from"); raw != "" {
+			parsed, ok := services.ParseQueryDate(raw, false, location)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date"})
+				return
+			}
+			from = parsed
+		}
+		if !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+			return
+		}
+
+		// 3. Signos pedidos (sin repetir: cada signo es una fila más del CROSS JOIN en Postgres)
+		metrics := domains.VitalNames
+		if raw := c.Query("metrics"); raw != "" {
+			metrics = nil
+			requested := map[string]bool{}
+			for _, metric := range strings.Split(raw, ",") {
+				metric = strings.TrimSpace(metric)
+				if _, ok := domains.VitalUnits[metric]; !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown vital metric: " + metric})
+					return
+				}
+				if !requested[metric] {
+					requested[metric] = true
+					metrics = append(metrics, metric)
+				}
+			}
+		}
+
+		// 4. Agrupación
+		bucket := c.DefaultQuery("bucket", services.VitalsBucketNone)
+		switch bucket {
+		case services.VitalsBucketNone, services.VitalsBucketDay, services.VitalsBucketWeek:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'bucket' must be none, day or week"})
+			return
+		}
+
+		// 5. Consultar (agregado en Postgres sobre el JSONB)
+		series, err := services.NewVitalsSeriesService().Series(services.VitalsQuery{
+			PatientID: patientID,
+			Metrics:   metrics,
+			From:      from,
+			To:        to,
+			Bucket:    bucket,
+			Location:  location,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vitals"})
+			return
+		}
+
+		// 6. Auditoría: lectura de datos clínicos
+		services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntityPatient, patientID, &patientID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"from":   from,
+				"to":     to,
+				"bucket": bucket,
+				"series": series,
+			},
+		})
+	}
+}
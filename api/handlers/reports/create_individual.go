@@ -2,35 +2,41 @@ package reports
 
 import (
 	"net/http"
-	"time"
 
+	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-type CreateReportInput struct {
-	PatientID          string `json:"patient_id" binding:"required"`
-	DateRangeStart     string `json:"start_date" binding:"required"` // YYYY-MM-DD
-	DateRangeEnd       string `json:"end_date" binding:"required"`
-	Content            string `json:"content" binding:"required"`
-	ObjectivesAchieved string `json:"objectives"`
-}
-
-func CreateIndividualReportHandler() gin.HandlerFunc {
+// CreateIndividualReportHandler crea el reporte del profesional como borrador ("submit": true lo envía a revisión)
+func CreateIndividualReportHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		currentUser := c.MustGet("currentUser").(domains.User)
-		var input CreateReportInput
+		var input domains.CreateReportInput
 		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Parsing fechas
-		start, _ := time.Parse("2006-01-02", input.DateRangeStart)
-		end, _ := time.Parse("2006-01-02", input.DateRangeEnd)
+		// Seguridad: Solo el equipo del paciente puede reportar sobre él
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, input.PatientID); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		// Validación del periodo
+		reportService := services.NewReportService()
+		start, end, err := reportService.ParseRange(input.DateRangeStart, input.DateRangeEnd)
+		if err != nil {
+			respondReportError(c, err, "Failed to create report")
+			return
+		}
 
 		report := domains.ProfessionalReport{
 			PatientID:          uuid.MustParse(input.PatientID),
@@ -41,11 +47,24 @@ func CreateIndividualReportHandler() gin.HandlerFunc {
 			ObjectivesAchieved: input.ObjectivesAchieved,
 		}
 
-		if err := database.GetDB().Create(&report).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := reportService.Create(tx, &report, input.Submit); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityReport, EntityID: report.ID, PatientID: &report.PatientID, After: report,
+			})
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to create report")
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{"message": "Report submitted", "id": report.ID})
+		if report.Status == domains.ReportSubmitted {
+			services.NewNotificationService(cfg).NotifyReportReview(report, currentUser)
+			c.JSON(http.StatusCreated, gin.H{"message": "Report submitted", "id": report.ID, "status": report.Status})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"message": "Report saved as draft", "id": report.ID, "status": report.Status})
 	}
 }
@@ -1,92 +1,99 @@
 package reports
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
-	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// Estructura de Salida (El Resumen Global)
-type MasterReportResponse struct {
-	GeneratedAt time.Time `json:"generated_at"`
-	DateRange   string    `json:"date_range"`
-
-	// Métricas Cuantitativas (Calculadas desde Sesiones)
-	TotalSessions  int64 `json:"total_sessions"`
-	TotalIncidents int64 `json:"total_incidents"`
-
-	// Resumen por Área/Profesional (Agregado desde Reportes)
-	ProfessionalSummaries []ProfessionalSummary `json:"professional_summaries"`
-}
-
-type ProfessionalSummary struct {
-	ProfessionalName string `json:"professional_name"`
-	Role             string `json:"role"` // Ej: Fonoaudiólogo
-	Summary          string `json:"summary"`
-	Objectives       string `json:"objectives"`
-}
-
-func GenerateMasterReportHandler() gin.HandlerFunc {
+// GenerateMasterReportHandler resumen global del paciente en el periodo
+// GET /api/reports/master?patient_id=...&start_date=...&end_date=...&format=json|pdf
+func GenerateMasterReportHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
 		var req domains.MasterReportRequest
 		if err := c.ShouldBindQuery(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		db := database.GetDB()
-
-		// 1. Obtener Reportes Individuales en el rango
-		var reports []domains.ProfessionalReport
-		if err := db.Preload("Author").
-			Where("patient_id = ? AND date_range_start >= ? AND date_range_end <= ?",
-				req.PatientID, req.StartDate, req.EndDate).
-			Find(&reports).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'format' must be json or pdf"})
 			return
 		}
 
-		// 2. Calcular Métricas desde Sesiones (Hard Data)
-		var totalSessions int64
-		var totalIncidents int64
-
-		// Count sesiones
-		db.Model(&domains.Session{}).
-			Where("patient_id = ? AND created_at BETWEEN ? AND ?", req.PatientID, req.StartDate, req.EndDate).
-			Count(&totalSessions)
+		// Seguridad: Solo el equipo del paciente (o admin) obtiene la visión global
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, req.PatientID); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
 
-		// Count incidentes
-		db.Model(&domains.Session{}).
-			Where("patient_id = ? AND created_at BETWEEN ? AND ? AND has_incident = ?", req.PatientID, req.StartDate, req.EndDate, true).
-			Count(&totalIncidents)
+		// 1. Armar el reporte (reportes individuales + métricas de sesiones)
+		reportService := services.NewMasterReportService(cfg)
+		response, err := reportService.Build(req)
+		if errors.Is(err, services.ErrInvalidReportRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+			return
+		}
 
-		// 3. Consolidar Información (Algoritmo de Agregación)
-		var summaries []ProfessionalSummary
+		// 2. La visión global expone la ficha clínica completa: se audita como lectura del paciente
+		auditService := services.NewAuditService()
+		if format == "json" {
+			auditService.RecordView(middleware.AuditActor(c), domains.AuditEntityPatient, response.Patient.ID, &response.Patient.ID)
+			c.JSON(http.StatusOK, gin.H{"data": response})
+			return
+		}
 
-		for _, r := range reports {
-			// Aquí extraemos lo valioso de cada experto
-			// Podríamos concatenar si un mismo experto tiene 2 reportes en el periodo
-			summaries = append(summaries, ProfessionalSummary{
-				ProfessionalName: r.Author.Email, // Idealmente usar Name del ProfileData
-				Role:             string(r.Author.Role),
-				Summary:          r.Content,
-				Objectives:       r.ObjectivesAchieved,
-			})
+		// 3. PDF descargable: se genera completo antes de enviar encabezados
+		var buf bytes.Buffer
+		if err := reportService.RenderPDF(response, &buf); err != nil {
+			slog.Error("Failed to render master report PDF", "patientID", req.PatientID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render report"})
+			return
 		}
 
-		// 4. Construir Respuesta Final
-		response := MasterReportResponse{
-			GeneratedAt:           time.Now(),
-			DateRange:             req.StartDate + " to " + req.EndDate,
-			TotalSessions:         totalSessions,
-			TotalIncidents:        totalIncidents,
-			ProfessionalSummaries: summaries,
+		// 4. El archivo sale del sistema: sin registro de la exportación no se entrega
+		from, _ := time.Parse("2006-01-02", req.StartDate)
+		to, _ := time.Parse("2006-01-02", req.EndDate)
+		detail := services.AuditExportDetail{
+			ExportID: uuid.New(),
+			Format:   format,
+			Identity: services.PatientIdentityFull,
+			From:     from,
+			To:       to,
+			Rows:     len(response.Timeline),
+			Complete: true,
+		}
+		if err := auditService.RecordExport(middleware.AuditActor(c), domains.AuditEntityMasterExport, detail, []uuid.UUID{response.Patient.ID}); err != nil {
+			slog.Error("Failed to record master report export audit", "patientID", req.PatientID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record export"})
+			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"data": response})
+		filename := fmt.Sprintf("master_report_%s_%s.pdf", response.Patient.ID, response.GeneratedAt.Format("20060102"))
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "application/pdf", buf.Bytes())
 	}
 }
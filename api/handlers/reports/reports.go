@@ -0,0 +1,300 @@
+package reports
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListReportsHandler lista reportes individuales de los pacientes del equipo (GET /api/reports)
+// Filtros: ?patient_id=...&author=me|<id>&status=DRAFT|SUBMITTED|APPROVED&page=1&limit=20
+// Los borradores solo aparecen para su autor.
+func ListReportsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+		access := services.NewAccessService()
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		filter := services.ReportFilter{Viewer: currentUser.ID}
+
+		// 1. Por paciente o por equipo
+		if raw := c.Query("patient_id"); raw != "" {
+			if err := access.CheckPatientAccess(currentUser, raw); err != nil {
+				middleware.AbortWithAccessError(c, err)
+				return
+			}
+			patientID, _ := uuid.Parse(raw)
+			filter.PatientID = &patientID
+		} else {
+			filter.PatientIDs = access.AccessiblePatientIDs(currentUser)
+		}
+
+		// 2. Autor y estado
+		switch raw := c.Query("author"); raw {
+		case "":
+		case "me":
+			filter.AuthorID = &currentUser.ID
+		default:
+			authorID, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+				return
+			}
+			filter.AuthorID = &authorID
+		}
+
+		switch status := domains.ReportStatus(strings.ToUpper(c.Query("status"))); status {
+		case "":
+		case domains.ReportDraft, domains.ReportSubmitted, domains.ReportApproved:
+			filter.Status = string(status)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'status' must be DRAFT, SUBMITTED or APPROVED"})
+			return
+		}
+
+		reports, total, err := services.NewReportService().List(filter, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": reports,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		})
+	}
+}
+
+// GetReportHandler detalle de un reporte (GET /api/reports/:id)
+func GetReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+
+		services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntityReport, report.ID, &report.PatientID)
+		c.JSON(http.StatusOK, gin.H{"data": report})
+	}
+}
+
+// UpdateReportHandler edita un borrador propio (PUT /api/reports/:id)
+func UpdateReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.UpdateReportInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+		before := report
+
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := services.NewReportService().Update(tx, &report, currentUser, input); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntityReport, EntityID: report.ID, PatientID: &report.PatientID, Before: before, After: report,
+			})
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to update report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Report updated", "data": report})
+	}
+}
+
+// DeleteReportHandler elimina un borrador o un reporte enviado (autor o Admin) (DELETE /api/reports/:id)
+func DeleteReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := services.NewReportService().Delete(tx, report, currentUser); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditDelete, EntityType: domains.AuditEntityReport, EntityID: report.ID, PatientID: &report.PatientID, Before: report,
+			})
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to delete report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Report deleted successfully"})
+	}
+}
+
+// SubmitReportHandler envía el borrador a revisión (POST /api/reports/:id/submit)
+func SubmitReportHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+
+		err := transitionReport(c, report, func(tx *gorm.DB) error {
+			return services.NewReportService().Submit(tx, &report, currentUser)
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to submit report")
+			return
+		}
+
+		services.NewNotificationService(cfg).NotifyReportReview(report, report.Author)
+		c.JSON(http.StatusOK, gin.H{"message": "Report submitted", "data": report})
+	}
+}
+
+// ApproveReportHandler el creador del paciente (o un ADMIN, si el autor es el creador) aprueba el reporte
+// (POST /api/reports/:id/approve)
+func ApproveReportHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+
+		err := transitionReport(c, report, func(tx *gorm.DB) error {
+			return services.NewReportService().Approve(tx, &report, currentUser)
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to approve report")
+			return
+		}
+
+		services.NewNotificationService(cfg).NotifyReportReview(report, report.Author)
+		c.JSON(http.StatusOK, gin.H{"message": "Report approved", "data": report})
+	}
+}
+
+// ReturnReportHandler el revisor devuelve el reporte a borrador con observaciones
+// (POST /api/reports/:id/return)
+func ReturnReportHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.ReturnReportInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report, ok := loadReport(c, currentUser)
+		if !ok {
+			return
+		}
+
+		err := transitionReport(c, report, func(tx *gorm.DB) error {
+			return services.NewReportService().Return(tx, &report, currentUser, strings.TrimSpace(input.ReviewNotes))
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to return report")
+			return
+		}
+
+		services.NewNotificationService(cfg).NotifyReportReview(report, report.Author)
+		c.JSON(http.StatusOK, gin.H{"message": "Report returned to draft", "data": report})
+	}
+}
+
+// transitionReport aplica un cambio de estado y lo audita en la misma transacción
+func transitionReport(c *gin.Context, report domains.ProfessionalReport, apply func(tx *gorm.DB) error) error {
+	before := report
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx); err != nil {
+			return err
+		}
+		var after domains.ProfessionalReport
+		if err := tx.First(&after, "id = ?", report.ID).Error; err != nil {
+			return err
+		}
+		return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+			Action: domains.AuditUpdate, EntityType: domains.AuditEntityReport, EntityID: report.ID, PatientID: &report.PatientID, Before: before, After: after,
+		})
+	})
+}
+
+// loadReport carga el reporte de la URL y valida el acceso al paciente
+func loadReport(c *gin.Context, user domains.User) (domains.ProfessionalReport, bool) {
+	report, err := services.NewReportService().Get(c.Param("id"), user.ID)
+	if err != nil {
+		respondReportError(c, err, "Failed to fetch report")
+		return report, false
+	}
+	if err := services.NewAccessService().CheckPatientAccess(user, report.PatientID.String()); err != nil {
+		middleware.AbortWithAccessError(c, err)
+		return report, false
+	}
+	return report, true
+}
+
+func respondReportError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+	case errors.Is(err, services.ErrInvalidReportDates):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dates must be YYYY-MM-DD and start_date cannot be after end_date"})
+	case errors.Is(err, services.ErrReportEmptyContent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Report content cannot be empty"})
+	case errors.Is(err, services.ErrReportNotAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can modify this report"})
+	case errors.Is(err, services.ErrReviewNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the patient creator can review reports; the creator's own reports are reviewed by an admin"})
+	case errors.Is(err, services.ErrSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot review your own report"})
+	case errors.Is(err, services.ErrReportOverlap):
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a report for this patient covering part of this period"})
+	case errors.Is(err, services.ErrReportNotDraft):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft reports can be edited or submitted"})
+	case errors.Is(err, services.ErrReportNotSubmitted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only submitted reports can be reviewed"})
+	case errors.Is(err, services.ErrReportApproved):
+		c.JSON(http.StatusConflict, gin.H{"error": "Approved reports can no longer be deleted"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -2,18 +2,22 @@ package sessions
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"bitacora-medica-backend/api/config" // Import necesario
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
 	"bitacora-medica-backend/api/services" // Import necesario
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // CreateSessionHandler ahora requiere la configuración para enviar correos
@@ -45,7 +49,37 @@ func CreateSessionHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Seguridad: Solo el equipo del paciente puede registrar sesiones
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, patientID.String()); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		// Regla legal: sin consentimiento vigente (revocado o vencido) no se registran sesiones
+		if err := services.NewConsentService(cfg).CheckSessionAllowed(patientID, time.Now()); err != nil {
+			switch {
+			case errors.Is(err, services.ErrConsentRevoked):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Patient consent has been revoked"})
+			case errors.Is(err, services.ErrConsentExpired):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Patient consent has expired"})
+			case errors.Is(err, services.ErrConsentNotFound):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Patient has no consent document"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient consent"})
+			}
+			return
+		}
+
+		// Signos vitales: validar y normalizar a unidades canónicas (las claves extra se conservan)
 		vitalsJSON, _ := json.Marshal(input.Vitals)
+		var vitals domains.Vitals
+		if input.Vitals != nil {
+			if vitals, err = services.NormalizeVitals(input.Vitals); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			vitalsJSON, _ = json.Marshal(vitals)
+		}
 
 		// 5. Crear Modelo
 		session := domains.Session{
@@ -56,24 +90,47 @@ func CreateSessionHandler(cfg *config.Config) gin.HandlerFunc {
 			Description:        input.Description,
 			Achievements:       input.Achievements,
 			PatientPerformance: input.PatientPerformance,
-			Photos:             pq.StringArray(input.Photos),
+			Photos:             pq.StringArray(services.ObjectKeysFromReferences(services.BucketSessionEvidence, input.Photos)),
 			HasIncident:        input.HasIncident,
 			IncidentDetails:    input.IncidentDetails,
-			IncidentPhoto:      input.IncidentPhoto,
+			IncidentPhoto:      services.ObjectKeyFromReference(services.BucketSessionEvidence, input.IncidentPhoto),
 			NextSessionNotes:   input.NextSessionNotes,
 		}
 
-		// 6. Guardar en DB
-		if err := database.DB.Create(&session).Error; err != nil {
+		// 6. Guardar en DB y asociar las fotos subidas (registro de archivos)
+		attachments := services.NewAttachmentService(cfg)
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&session).Error; err != nil {
+				return err
+			}
+			if err := attachments.LinkToEntity(tx, currentUser, services.BucketSessionEvidence, services.SessionObjectKeys(session), nil,
+				session.PatientID, domains.AttachmentEntitySession, session.ID); err != nil {
+				return err
+			}
+			// El incidente reportado pasa a tener su propio seguimiento
+			if session.HasIncident {
+				if _, err := services.NewIncidentService(cfg).CreateFromSession(tx, session, input.IncidentCategory, input.IncidentSeverity); err != nil {
+					return err
+				}
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntitySession, EntityID: session.ID, PatientID: &session.PatientID, After: session,
+			})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrAttachmentUnavailable) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "One or more photos are not available"})
+				return
+			}
 			slog.Error("Failed to create session", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
 			return
 		}
 
 		// 7. REGLA DE NEGOCIO: Disparar Notificación de Incidente
+		notifier := services.NewNotificationService(cfg)
 		if session.HasIncident {
-			// Instanciamos el servicio y notificamos al equipo
-			notifier := services.NewNotificationService(cfg)
+			// Notificamos al equipo
 			notifier.NotifyIncident(session.PatientID, session.IncidentDetails)
 
 			slog.Warn("INCIDENT REPORTED - Notifications triggered",
@@ -81,6 +138,11 @@ func CreateSessionHandler(cfg *config.Config) gin.HandlerFunc {
 				"professional", currentUser.Email)
 		}
 
+		// 8. Signos vitales fuera de los umbrales configurados: alerta al equipo
+		if alerts := services.AbnormalVitals(vitals, cfg.VitalsThresholds); len(alerts) > 0 {
+			notifier.NotifyAbnormalVitals(session.PatientID, alerts)
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Session recorded successfully",
 			"data":    session,
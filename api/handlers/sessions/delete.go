@@ -1,15 +1,22 @@
 package sessions
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func DeleteSessionHandler() gin.HandlerFunc {
+func DeleteSessionHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		currentUser := c.MustGet("currentUser").(domains.User)
@@ -28,8 +35,53 @@ func DeleteSessionHandler() gin.HandlerFunc {
 			return
 		}
 
-		// Soft Delete
-		if err := db.Delete(&session).Error; err != nil {
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, session.PatientID.String()); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		// Una sesión firmada forma parte de la ficha clínica y no se puede eliminar
+		if session.IsSigned() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Session is signed and can no longer be deleted"})
+			return
+		}
+
+		// Soft Delete: se guarda la última versión como revisión (motivo opcional en ?reason=);
+		// sus fotos quedan desvinculadas pero retenidas el plazo de la ficha clínica
+		reason := strings.TrimSpace(c.Query("reason"))
+		attachments := services.NewAttachmentService(cfg)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Se archiva la fila bloqueada, no la leída arriba: otra edición pudo guardarse entretanto
+			locked, err := services.NewSessionSigningService().LockEditable(tx, session.ID)
+			if err != nil {
+				return err
+			}
+			session = locked
+			if _, err := services.NewSessionRevisionService().Record(tx, session, currentUser, reason, domains.RevisionDelete); err != nil {
+				return err
+			}
+			if err := attachments.RetainForRecord(tx, services.SessionObjectKeys(session), time.Now()); err != nil {
+				return err
+			}
+			if err := tx.Delete(&session).Error; err != nil {
+				return err
+			}
+			if err := attachments.ReleaseEntity(tx, domains.AttachmentEntitySession, session.ID); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditDelete, EntityType: domains.AuditEntitySession, EntityID: session.ID, PatientID: &session.PatientID, Before: session,
+			})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrSessionSigned) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Session is signed and can no longer be deleted"})
+				return
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session"})
 			return
 		}
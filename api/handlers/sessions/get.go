@@ -5,6 +5,8 @@ import (
 
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +15,7 @@ import (
 func GetSessionHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
+		currentUser := c.MustGet("currentUser").(domains.User)
 
 		var session domains.Session
 		if err := database.GetDB().First(&session, "id = ?", id).Error; err != nil {
@@ -20,6 +23,15 @@ func GetSessionHandler() gin.HandlerFunc {
 			return
 		}
 
+		// Seguridad: Solo quien tiene acceso al paciente puede ver la sesión
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, session.PatientID.String()); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		// Auditoría: lectura de registro clínico
+		services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntitySession, session.ID, &session.PatientID)
+
 		c.JSON(http.StatusOK, gin.H{"data": session})
 	}
 }
@@ -5,6 +5,8 @@ import (
 
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,8 +14,10 @@ import (
 // ListSessionsHandler obtiene sesiones con filtros
 func ListSessionsHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
 		db := database.GetDB()
 		var sessions []domains.Session
+		access := services.NewAccessService()
 
 		// MODIFICACIÓN CLAVE: Agregamos .Preload("Creator")
 		// Esto carga la relación "Creator" (el usuario profesional) para tener sus datos (nombre, foto, etc.)
@@ -22,7 +26,14 @@ func ListSessionsHandler() gin.HandlerFunc {
 		// 1. Filtro por Paciente (El más común)
 		patientID := c.Query("patient_id")
 		if patientID != "" {
+			if err := access.CheckPatientAccess(currentUser, patientID); err != nil {
+				middleware.AbortWithAccessError(c, err)
+				return
+			}
 			query = query.Where("patient_id = ?", patientID)
+		} else if visible := access.AccessiblePatientIDs(currentUser); visible != nil {
+			// Sin filtro de paciente: restringimos a los pacientes visibles para el usuario
+			query = query.Where("patient_id IN (?)", visible)
 		}
 
 		// 2. Filtro por Profesional (Para reportes individuales)
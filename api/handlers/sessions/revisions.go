@@ -0,0 +1,88 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessionRevisionsHandler devuelve el historial de versiones de una sesión (GET /api/sessions/:id/revisions)
+func ListSessionRevisionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		session, ok := loadSessionForHistory(c, currentUser)
+		if !ok {
+			return
+		}
+
+		revisions, err := services.NewSessionRevisionService().List(session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":            revisions,
+			"current_version": len(revisions) + 1,
+			"deleted":         session.DeletedAt.Valid,
+		})
+	}
+}
+
+// DiffSessionRevisionsHandler compara dos versiones (GET /api/sessions/:id/revisions/diff?from=1&to=current)
+// "to" acepta un número de versión o "current" (por defecto)
+func DiffSessionRevisionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		from, err := strconv.Atoi(c.Query("from"))
+		if err != nil || from < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query param 'from' must be a version number"})
+			return
+		}
+		to := c.DefaultQuery("to", services.RevisionCurrent)
+
+		session, ok := loadSessionForHistory(c, currentUser)
+		if !ok {
+			return
+		}
+
+		diff, err := services.NewSessionRevisionService().Diff(session, from, to)
+		if err != nil {
+			if errors.Is(err, services.ErrRevisionNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare revisions"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "changes": diff})
+	}
+}
+
+// loadSessionForHistory busca la sesión (incluso eliminada) y valida el acceso al paciente
+func loadSessionForHistory(c *gin.Context, user domains.User) (domains.Session, bool) {
+	var session domains.Session
+	if err := database.GetDB().Unscoped().First(&session, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return session, false
+	}
+
+	if err := services.NewAccessService().CheckPatientAccess(user, session.PatientID.String()); err != nil {
+		middleware.AbortWithAccessError(c, err)
+		return session, false
+	}
+
+	// Auditoría: el historial también es lectura de registro clínico
+	services.NewAuditService().RecordView(middleware.AuditActor(c), domains.AuditEntitySession, session.ID, &session.PatientID)
+	return session, true
+}
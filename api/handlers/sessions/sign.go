@@ -0,0 +1,171 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SignSessionHandler firma (cierra) la sesión: desde ahí solo admite adendas (POST /api/sessions/:id/sign)
+func SignSessionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		session, ok := loadSessionWithAccess(c, currentUser)
+		if !ok {
+			return
+		}
+
+		before := session
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := services.NewSessionSigningService().Sign(tx, &session, currentUser, time.Now()); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntitySession, EntityID: session.ID, PatientID: &session.PatientID,
+				Before: before, After: session,
+			})
+		})
+		if err != nil {
+			respondSigningError(c, err, "Failed to sign session")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session signed", "data": session})
+	}
+}
+
+// CosignSessionHandler agrega la co-firma del creador del paciente (POST /api/sessions/:id/cosign)
+func CosignSessionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		session, ok := loadSessionWithAccess(c, currentUser)
+		if !ok {
+			return
+		}
+
+		before := session
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := services.NewSessionSigningService().Cosign(tx, &session, currentUser, time.Now()); err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntitySession, EntityID: session.ID, PatientID: &session.PatientID,
+				Before: before, After: session,
+			})
+		})
+		if err != nil {
+			respondSigningError(c, err, "Failed to co-sign session")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session co-signed", "data": session})
+	}
+}
+
+// ListAddendaHandler lista las adendas de una sesión (GET /api/sessions/:id/addenda)
+func ListAddendaHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		session, ok := loadSessionWithAccess(c, currentUser)
+		if !ok {
+			return
+		}
+
+		addenda, err := services.NewSessionSigningService().Addenda(session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addenda"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": addenda})
+	}
+}
+
+// CreateAddendumHandler agrega una adenda a una sesión firmada (POST /api/sessions/:id/addenda)
+// Cualquier profesional del equipo del paciente puede agregarla.
+func CreateAddendumHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(domains.User)
+
+		var input domains.CreateAddendumInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		content := strings.TrimSpace(input.Content)
+		if content == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Addendum content is mandatory"})
+			return
+		}
+
+		session, ok := loadSessionWithAccess(c, currentUser)
+		if !ok {
+			return
+		}
+
+		var addendum domains.SessionAddendum
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			addendum, err = services.NewSessionSigningService().AddAddendum(tx, session, currentUser, content)
+			if err != nil {
+				return err
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditCreate, EntityType: domains.AuditEntityAddendum, EntityID: addendum.ID, PatientID: &session.PatientID,
+				After: addendum,
+			})
+		})
+		if err != nil {
+			respondSigningError(c, err, "Failed to add addendum")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Addendum added", "data": addendum})
+	}
+}
+
+// loadSessionWithAccess busca la sesión y valida el acceso al paciente
+func loadSessionWithAccess(c *gin.Context, user domains.User) (domains.Session, bool) {
+	var session domains.Session
+	if err := database.GetDB().First(&session, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return session, false
+	}
+
+	if err := services.NewAccessService().CheckPatientAccess(user, session.PatientID.String()); err != nil {
+		middleware.AbortWithAccessError(c, err)
+		return session, false
+	}
+	return session, true
+}
+
+func respondSigningError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrSignNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the session author can sign it"})
+	case errors.Is(err, services.ErrCosignNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the patient creator can co-sign sessions"})
+	case errors.Is(err, services.ErrCosignBySignerDenied):
+		c.JSON(http.StatusConflict, gin.H{"error": "The signer cannot co-sign their own session"})
+	case errors.Is(err, services.ErrSessionSigned):
+		c.JSON(http.StatusConflict, gin.H{"error": "Session is already signed"})
+	case errors.Is(err, services.ErrSessionCosigned):
+		c.JSON(http.StatusConflict, gin.H{"error": "Session is already co-signed"})
+	case errors.Is(err, services.ErrSessionNotSigned):
+		c.JSON(http.StatusConflict, gin.H{"error": "Session must be signed first"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -2,18 +2,25 @@ package sessions
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // UpdateSessionHandler permite editar una sesión (Solo el autor)
-func UpdateSessionHandler() gin.HandlerFunc {
+func UpdateSessionHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		currentUser := c.MustGet("currentUser").(domains.User)
@@ -32,43 +39,133 @@ func UpdateSessionHandler() gin.HandlerFunc {
 			return
 		}
 
+		// El autor debe seguir teniendo acceso al paciente (ej: colaboración revocada)
+		if err := services.NewAccessService().CheckPatientAccess(currentUser, session.PatientID.String()); err != nil {
+			middleware.AbortWithAccessError(c, err)
+			return
+		}
+
+		// Una sesión firmada es inmutable: los cambios van como adenda
+		if session.IsSigned() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Session is signed and can no longer be edited; add an addendum instead"})
+			return
+		}
+
 		// 3. Bind de los nuevos datos
-		var input domains.CreateSessionInput
+		var input domains.UpdateSessionInput
 		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 4. Actualizar campos
+		if input.HasIncident && input.IncidentDetails == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Incident details are mandatory when an incident is reported."})
+			return
+		}
 
-		// Vitals: Convertir map a JSONB
-		if input.Vitals != nil {
-			vitalsJSON, _ := json.Marshal(input.Vitals)
-			session.Vitals = datatypes.JSON(vitalsJSON)
+		// Pasada la ventana de edición, el cambio es una enmienda y debe justificarse
+		reason := strings.TrimSpace(input.AmendmentReason)
+		if reason == "" && time.Since(session.CreatedAt) > cfg.SessionEditWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amendment_reason is required to edit sessions older than the edit window"})
+			return
 		}
 
-		session.InterventionPlan = input.InterventionPlan
-		session.Description = input.Description
-		session.Achievements = input.Achievements
-		session.PatientPerformance = input.PatientPerformance
-		session.NextSessionNotes = input.NextSessionNotes
+		// 4. Validar y normalizar los datos nuevos (se aplican dentro de la transacción)
+		var vitals *domains.Vitals
+		if input.Vitals != nil {
+			normalized, err := services.NormalizeVitals(input.Vitals)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			vitals = &normalized
+		}
 
-		// Incidentes
-		session.HasIncident = input.HasIncident
-		session.IncidentDetails = input.IncidentDetails
-		// Solo actualizamos la foto del incidente si viene una nueva o si se limpió explícitamente?
-		// Generalmente en updates, reemplazamos el valor:
-		session.IncidentPhoto = input.IncidentPhoto
+		// 5. Guardar cambios junto a la revisión con la versión anterior
+		// (las fotos quitadas quedan como candidatas a eliminar)
+		var vitalAlerts []services.VitalAlert
+		attachments := services.NewAttachmentService(cfg)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// El "antes" es la fila bloqueada, no la leída arriba: otra edición pudo guardarse entretanto
+			before, err := services.NewSessionSigningService().LockEditable(tx, session.ID)
+			if err != nil {
+				return err
+			}
+			session = before
+			applySessionUpdate(&session, input)
 
-		// Fotos: Asignamos directamente para permitir borrar todas (array vacío)
-		session.Photos = pq.StringArray(input.Photos)
+			// Vitals: solo se alerta por valores anormales nuevos o que cambiaron
+			if vitals != nil {
+				vitalsJSON, _ := json.Marshal(*vitals)
+				session.Vitals = datatypes.JSON(vitalsJSON)
+				vitalAlerts = services.NewVitalAlerts(services.ParseSessionVitals(before.Vitals), *vitals, cfg.VitalsThresholds)
+			}
 
-		// 5. Guardar cambios
-		if err := db.Save(&session).Error; err != nil {
+			if _, err := services.NewSessionRevisionService().Record(tx, before, currentUser, reason, domains.RevisionUpdate); err != nil {
+				return err
+			}
+			if err := attachments.RetainForRecord(tx, services.SessionObjectKeys(before), time.Now()); err != nil {
+				return err
+			}
+			if err := tx.Save(&session).Error; err != nil {
+				return err
+			}
+			if err := attachments.LinkToEntity(tx, currentUser, services.BucketSessionEvidence, services.SessionObjectKeys(session), services.SessionObjectKeys(before),
+				session.PatientID, domains.AttachmentEntitySession, session.ID); err != nil {
+				return err
+			}
+			// Un incidente agregado en la edición también pasa a seguimiento (si ya existía, no se duplica)
+			if session.HasIncident {
+				if _, err := services.NewIncidentService(cfg).CreateFromSession(tx, session, input.IncidentCategory, input.IncidentSeverity); err != nil {
+					return err
+				}
+			}
+			return services.NewAuditService().Record(tx, middleware.AuditActor(c), services.AuditEntry{
+				Action: domains.AuditUpdate, EntityType: domains.AuditEntitySession, EntityID: session.ID, PatientID: &session.PatientID,
+				Before: before, After: session,
+			})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrAttachmentUnavailable) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "One or more photos are not available"})
+				return
+			}
+			if errors.Is(err, services.ErrSessionSigned) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Session is signed and can no longer be edited; add an addendum instead"})
+				return
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
 			return
 		}
 
+		if len(vitalAlerts) > 0 {
+			services.NewNotificationService(cfg).NotifyAbnormalVitals(session.PatientID, vitalAlerts)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Session updated", "data": session})
 	}
 }
+
+// applySessionUpdate copia los campos editables del input a la sesión (las vitals se tratan aparte)
+func applySessionUpdate(session *domains.Session, input domains.UpdateSessionInput) {
+	session.InterventionPlan = input.InterventionPlan
+	session.Description = input.Description
+	session.Achievements = input.Achievements
+	session.PatientPerformance = input.PatientPerformance
+	session.NextSessionNotes = input.NextSessionNotes
+
+	// Incidentes
+	session.HasIncident = input.HasIncident
+	session.IncidentDetails = input.IncidentDetails
+	// Solo actualizamos la foto del incidente si viene una nueva o si se limpió explícitamente?
+	// Generalmente en updates, reemplazamos el valor:
+	session.IncidentPhoto = services.ObjectKeyFromReference(services.BucketSessionEvidence, input.IncidentPhoto)
+
+	// Fotos: Asignamos directamente para permitir borrar todas (array vacío)
+	// Solo guardamos claves de objeto: los archivos se leen vía /api/files con URL firmada
+	session.Photos = pq.StringArray(services.ObjectKeysFromReferences(services.BucketSessionEvidence, input.Photos))
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditActor arma el actor del registro de auditoría desde el request autenticado
+func AuditActor(c *gin.Context) services.AuditActor {
+	actor := services.AuditActor{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if user, ok := c.Get("currentUser"); ok {
+		currentUser := user.(domains.User)
+		actor.UserID = currentUser.ID
+		actor.Email = currentUser.Email
+		actor.Role = string(currentUser.Role)
+	}
+	return actor
+}
@@ -39,6 +39,26 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		authHeader := c.GetHeader("Authorization")
+
+		// EventSource (SSE) del navegador no permite cabeceras personalizadas: el stream de
+		// notificaciones acepta un ticket de un solo uso (?ticket=, ver POST /notifications/stream-ticket).
+		// Nunca el JWT en la URL: terminaría en los logs de acceso y de los proxies.
+		if authHeader == "" && strings.HasSuffix(c.Request.URL.Path, "/notifications/stream") {
+			userID, ok := services.GetStreamTickets().Redeem(c.Query("ticket"))
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+				return
+			}
+
+			var user domains.User
+			if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+				return
+			}
+			authorizeUser(c, user)
+			return
+		}
+
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			return
@@ -144,31 +164,36 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 5. Validar Status
-		if user.Status == domains.StatusRejected {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account REJECTED", "reason": user.RejectReason})
-			return
-		}
+		authorizeUser(c, user)
+	}
+}
 
-		if user.Status == domains.StatusInactive {
-			// Permisos especiales para usuarios INACTIVOS:
-			// 1. Completar su perfil (PUT /profile)
-			// 2. Consultar sus propios datos para ver qué han llenado (GET /me) <--- ESTO FALTABA
+// authorizeUser aplica las reglas de estado de la cuenta y deja al usuario en el contexto
+func authorizeUser(c *gin.Context, user domains.User) {
+	if user.Status == domains.StatusRejected {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account REJECTED", "reason": user.RejectReason})
+		return
+	}
 
-			isProfileUpdate := c.Request.Method == "PUT" && strings.Contains(c.Request.URL.Path, "/api/auth/profile")
-			isGetMe := c.Request.Method == "GET" && strings.Contains(c.Request.URL.Path, "/api/auth/me")
+	if user.Status == domains.StatusInactive {
+		// Permisos especiales para usuarios INACTIVOS:
+		// 1. Completar su perfil (PUT /profile)
+		// 2. Consultar sus propios datos para ver qué han llenado (GET /me) <--- ESTO FALTABA
 
-			if isProfileUpdate || isGetMe {
-				c.Set("currentUser", user)
-				c.Next()
-				return
-			}
+		isProfileUpdate := c.Request.Method == "PUT" && strings.Contains(c.Request.URL.Path, "/api/auth/profile")
+		isGetMe := c.Request.Method == "GET" && strings.Contains(c.Request.URL.Path, "/api/auth/me")
 
-			// Cualquier otra ruta (pacientes, sesiones, etc.) sigue prohibida
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account Pending Approval"})
+		if isProfileUpdate || isGetMe {
+			c.Set("currentUser", user)
+			c.Next()
 			return
 		}
 
-		c.Set("currentUser", user)
-		c.Next()
+		// Cualquier otra ruta (pacientes, sesiones, etc.) sigue prohibida
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account Pending Approval"})
+		return
 	}
+
+	c.Set("currentUser", user)
+	c.Next()
 }
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePatientAccess valida que el usuario autenticado pueda acceder al paciente
+// cuyo ID viene en el parámetro de ruta indicado (ej: "id" para /patients/:id)
+func RequirePatientAccess(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInterface, exists := c.Get("currentUser")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		user := userInterface.(domains.User)
+
+		if err := services.NewAccessService().CheckPatientAccess(user, c.Param(param)); err != nil {
+			AbortWithAccessError(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AbortWithAccessError traduce los errores de AccessService a la respuesta HTTP correspondiente
+func AbortWithAccessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPatientNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+	case errors.Is(err, services.ErrPatientAccessDenied):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have access to this patient"})
+	default:
+		slog.Error("Failed to check patient access", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check patient access"})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger log de acceso con el mismo formato que gin.Logger(), pero sin el query string:
+// los parámetros pueden traer tickets, tokens o datos de pacientes (filtros de búsqueda).
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			path, _, _ := strings.Cut(param.Path, "?")
+
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				path,
+				param.ErrorMessage,
+			)
+		},
+	})
+}
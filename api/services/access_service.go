@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Errores de autorización sobre pacientes
+var (
+	ErrPatientNotFound     = errors.New("patient not found")
+	ErrPatientAccessDenied = errors.New("patient access denied")
+)
+
+// AccessService centraliza la regla "¿puede este usuario acceder al paciente X?"
+// Un usuario tiene acceso si:
+// 1. Es ADMIN
+// 2. Es el creador del paciente (Patient.CreatorID)
+// 3. Tiene una colaboración ACEPTADA sobre el paciente
+type AccessService struct {
+	db *gorm.DB
+}
+
+func NewAccessService() *AccessService {
+	return &AccessService{db: database.GetDB()}
+}
+
+// CheckPatientAccess retorna nil si el usuario puede ver/editar el paciente.
+// ErrPatientNotFound si el paciente no existe, ErrPatientAccessDenied si no tiene permiso.
+func (s *AccessService) CheckPatientAccess(user domains.User, patientID string) error {
+	id, err := uuid.Parse(patientID)
+	if err != nil {
+		return ErrPatientNotFound
+	}
+
+	var patient domains.Patient
+	if err := s.db.Select("id", "creator_id").First(&patient, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPatientNotFound
+		}
+		return err
+	}
+
+	if user.Role == domains.RoleAdmin || patient.CreatorID == user.ID {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&domains.Collaboration{}).
+		Where("patient_id = ? AND professional_id = ? AND status = ?", id, user.ID, domains.CollabAccepted).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrPatientAccessDenied
+	}
+	return nil
+}
+
+// CheckPatientCreator exige que el usuario sea el creador del paciente (o ADMIN).
+// Se usa para acciones de "dueño" como invitar colaboradores.
+func (s *AccessService) CheckPatientCreator(user domains.User, patientID string) error {
+	id, err := uuid.Parse(patientID)
+	if err != nil {
+		return ErrPatientNotFound
+	}
+
+	var patient domains.Patient
+	if err := s.db.Select("id", "creator_id").First(&patient, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPatientNotFound
+		}
+		return err
+	}
+
+	if user.Role != domains.RoleAdmin && patient.CreatorID != user.ID {
+		return ErrPatientAccessDenied
+	}
+	return nil
+}
+
+// AccessiblePatientIDs devuelve una subquery con los IDs de pacientes visibles para el usuario.
+// Pensada para usarse en filtros: query.Where("patient_id IN (?)", access.AccessiblePatientIDs(user))
+// Para ADMIN devuelve nil (sin restricción); el llamador debe omitir el filtro.
+func (s *AccessService) AccessiblePatientIDs(user domains.User) *gorm.DB {
+	if user.Role == domains.RoleAdmin {
+		return nil
+	}
+	return s.TeamPatientIDs(user.ID)
+}
+
+// TeamPatientIDs devuelve una subquery con los pacientes donde el usuario es parte del equipo
+// (creador o colaborador aceptado), sin el atajo de ADMIN.
+func (s *AccessService) TeamPatientIDs(userID uuid.UUID) *gorm.DB {
+	return s.db.Model(&domains.Patient{}).
+		Select("id").
+		Where("creator_id = ?", userID).
+		Or("id IN (?)", s.db.Table("collaborations").
+			Select("patient_id").
+			Where("professional_id = ? AND status = ?", userID, domains.CollabAccepted))
+}
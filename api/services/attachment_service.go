@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ErrAttachmentUnavailable: la clave pertenece a otro usuario/entidad o ya fue eliminada
+var ErrAttachmentUnavailable = errors.New("attachment not available")
+
+// Cantidad máxima de archivos eliminados por pasada del sweeper
+const attachmentSweepBatchSize = 100
+
+type AttachmentService struct {
+	Config *config.Config
+	store  BlobStore
+}
+
+func NewAttachmentService(cfg *config.Config) *AttachmentService {
+	return &AttachmentService{Config: cfg, store: GetBlobStore(cfg)}
+}
+
+// RegisterUpload deja constancia de un objeto recién subido (estado PENDING hasta que se asocie).
+// Si el mismo usuario ya subió un archivo idéntico (mismo SHA-256) que aún no usa, se reutiliza
+// ese y se elimina el nuevo: retorna el archivo vigente y deduplicated=true.
+func (s *AttachmentService) RegisterUpload(ctx context.Context, ownerID uuid.UUID, bucket string, file UploadedFile) (UploadedFile, bool, error) {
+	db := database.GetDB()
+
+	// 1. Buscar un duplicado pendiente del mismo usuario
+	var existing domains.Attachment
+	err := db.Where("bucket = ? AND owner_id = ? AND sha256 = ? AND status = ?", bucket, ownerID, file.SHA256, domains.AttachmentPending).
+		Order("created_at DESC").
+		First(&existing).Error
+	if err == nil {
+		NewStorageService(s.Config).DeleteUploaded(ctx, bucket, file)
+
+		reused := file
+		reused.Key = existing.ObjectKey
+		if len(file.Variants) > 0 {
+			reused.Variants = make(map[string]string, len(file.Variants))
+			for variant := range file.Variants {
+				reused.Variants[variant] = ImageVariantKey(existing.ObjectKey, variant)
+			}
+		}
+		return reused, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return UploadedFile{}, false, err
+	}
+
+	// 2. Registrar
+	var variantKeys []string
+	for variant, key := range file.Variants {
+		if variant != ImageVariantOriginal {
+			variantKeys = append(variantKeys, key)
+		}
+	}
+
+	attachment := domains.Attachment{
+		Bucket:      bucket,
+		ObjectKey:   file.Key,
+		VariantKeys: pq.StringArray(variantKeys),
+		SHA256:      file.SHA256,
+		Size:        file.Size,
+		OwnerID:     ownerID,
+		Status:      domains.AttachmentPending,
+	}
+	if err := db.Create(&attachment).Error; err != nil {
+		return UploadedFile{}, false, err
+	}
+	return file, false, nil
+}
+
+// FindByObject busca el registro de un objeto (las variantes resuelven a su original)
+func (s *AttachmentService) FindByObject(bucket, key string) (*domains.Attachment, error) {
+	if bucket == BucketSessionEvidence {
+		key = OriginalImageKey(key)
+	}
+
+	var attachment domains.Attachment
+	if err := database.GetDB().Where("bucket = ? AND object_key = ?", bucket, key).First(&attachment).Error; err != nil {
+		return nil, notFoundAsObjectError(err)
+	}
+	return &attachment, nil
+}
+
+// LinkToEntity asocia los archivos referenciados por una entidad (sesión o paciente) y marca como
+// UNLINKED los que la entidad dejó de referenciar. Debe llamarse dentro de la transacción que guarda la entidad.
+// Solo se pueden asociar archivos propios pendientes o que ya pertenecían a la misma entidad.
+// Una clave sin registro (archivo anterior al registro) solo se acepta si la entidad ya la guardaba
+// (stored): así nadie puede apuntar una entidad propia a un archivo ajeno y obtener acceso a él.
+func (s *AttachmentService) LinkToEntity(tx *gorm.DB, uploader domains.User, bucket string, keys []string, stored []string, patientID uuid.UUID, entityType string, entityID uuid.UUID) error {
+	now := time.Now()
+
+	if len(keys) > 0 {
+		// 1. Validar que cada archivo registrado esté disponible para esta entidad
+		var attachments []domains.Attachment
+		if err := tx.Where("bucket = ? AND object_key IN ?", bucket, keys).Find(&attachments).Error; err != nil {
+			return err
+		}
+		registered := make(map[string]bool, len(attachments))
+		for _, a := range attachments {
+			sameEntity := a.EntityType == entityType && a.EntityID != nil && *a.EntityID == entityID
+			ownPending := a.Status == domains.AttachmentPending && a.OwnerID == uploader.ID
+			if a.Status == domains.AttachmentDeleted || (!sameEntity && !ownPending) {
+				return ErrAttachmentUnavailable
+			}
+			registered[a.ObjectKey] = true
+		}
+
+		// Claves sin registro: solo las que la entidad ya referenciaba
+		previous := make(map[string]bool, len(stored))
+		for _, key := range stored {
+			previous[key] = true
+		}
+		for _, key := range keys {
+			if !registered[key] && !previous[key] {
+				return ErrAttachmentUnavailable
+			}
+		}
+
+		// 2. Asociar
+		if err := tx.Model(&domains.Attachment{}).
+			Where("bucket = ? AND object_key IN ? AND status <> ?", bucket, keys, domains.AttachmentDeleted).
+			Updates(map[string]interface{}{
+				"status":      domains.AttachmentLinked,
+				"patient_id":  patientID,
+				"entity_type": entityType,
+				"entity_id":   entityID,
+				"linked_at":   now,
+				"unlinked_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	// 3. Lo que la entidad ya no referencia queda como candidato a eliminar
+	query := tx.Model(&domains.Attachment{}).
+		Where("bucket = ? AND entity_type = ? AND entity_id = ? AND status = ?", bucket, entityType, entityID, domains.AttachmentLinked)
+	if len(keys) > 0 {
+		query = query.Where("object_key NOT IN ?", keys)
+	}
+	return query.Updates(map[string]interface{}{
+		"status":      domains.AttachmentUnlinked,
+		"unlinked_at": now,
+	}).Error
+}
+
+// SessionObjectKeys lista los archivos que referencia una sesión (fotos + foto del incidente)
+func SessionObjectKeys(session domains.Session) []string {
+	keys := append([]string{}, session.Photos...)
+	if session.IncidentPhoto != "" {
+		keys = append(keys, session.IncidentPhoto)
+	}
+	return keys
+}
+
+// ReleaseEntity marca como UNLINKED todos los archivos de una entidad eliminada
+func (s *AttachmentService) ReleaseEntity(tx *gorm.DB, entityType string, entityID uuid.UUID) error {
+	return tx.Model(&domains.Attachment{}).
+		Where("entity_type = ? AND entity_id = ? AND status = ?", entityType, entityID, domains.AttachmentLinked).
+		Updates(map[string]interface{}{
+			"status":      domains.AttachmentUnlinked,
+			"unlinked_at": time.Now(),
+		}).Error
+}
+
+// RetainForRecord marca las fotos de una versión archivada de la sesión (revisión o sesión eliminada)
+// para conservarlas cfg.SessionEvidenceRetention aunque la sesión deje de referenciarlas.
+// Debe llamarse dentro de la transacción que guarda la revisión. Un plazo ya más largo no se acorta.
+func (s *AttachmentService) RetainForRecord(tx *gorm.DB, keys []string, now time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	until := now.Add(s.Config.SessionEvidenceRetention)
+	return tx.Model(&domains.Attachment{}).
+		Where("bucket = ? AND object_key IN ? AND (retained_until IS NULL OR retained_until < ?)", BucketSessionEvidence, keys, until).
+		Update("retained_until", until).Error
+}
+
+// SweepOrphans elimina del almacenamiento los archivos nunca asociados o desvinculados
+// hace más de cfg.AttachmentOrphanMaxAge, salvo los retenidos por la ficha clínica
+// (RetainForRecord) mientras no venza su plazo. Retorna cuántos se eliminaron.
+func (s *AttachmentService) SweepOrphans(ctx context.Context, now time.Time) (int, error) {
+	db := database.GetDB()
+	cutoff := now.Add(-s.Config.AttachmentOrphanMaxAge)
+
+	// 1. Candidatos
+	var orphans []domains.Attachment
+	if err := db.
+		Where("(status = ? AND created_at < ?) OR (status = ? AND unlinked_at < ?)",
+			domains.AttachmentPending, cutoff, domains.AttachmentUnlinked, cutoff).
+		Where("retained_until IS NULL OR retained_until < ?", now).
+		Order("created_at ASC").
+		Limit(attachmentSweepBatchSize).
+		Find(&orphans).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, orphan := range orphans {
+		// 2. Reclamar el registro antes de borrar: si justo se asoció a una entidad, no se toca
+		result := db.Model(&domains.Attachment{}).
+			Where("id = ? AND status = ?", orphan.ID, orphan.Status).
+			Updates(map[string]interface{}{"status": domains.AttachmentDeleted, "purged_at": now})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		// 3. Borrar original y variantes; si algo falla se devuelve al estado anterior para reintentar
+		failed := false
+		for _, key := range append([]string{orphan.ObjectKey}, orphan.VariantKeys...) {
+			if err := s.store.Delete(ctx, orphan.Bucket, key); err != nil && !errors.Is(err, ErrObjectNotFound) {
+				slog.Error("Failed to delete orphaned object", "bucket", orphan.Bucket, "key", key, "error", err)
+				failed = true
+			}
+		}
+		if failed {
+			db.Model(&domains.Attachment{}).Where("id = ?", orphan.ID).
+				Updates(map[string]interface{}{"status": orphan.Status, "purged_at": nil})
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		slog.Info("Orphaned attachments purged", "count", purged)
+	}
+	return purged, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// AuditActor identifica quién realiza la acción y desde dónde
+type AuditActor struct {
+	UserID    uuid.UUID
+	Email     string
+	Role      string
+	IPAddress string
+	UserAgent string
+}
+
+// AuditEntry describe un cambio: Before es nil en CREATE y After es nil en DELETE
+type AuditEntry struct {
+	Action     domains.AuditAction
+	EntityType string
+	EntityID   uuid.UUID
+	PatientID  *uuid.UUID
+	Before     interface{}
+	After      interface{}
+}
+
+// AuditExportDetail describe una exportación (queda en Changes como "after").
+// Complete es false si la descarga se cortó después de enviar parte de las filas.
+type AuditExportDetail struct {
+	ExportID uuid.UUID
+	Format   string
+	Identity string // Cómo se identificó a los pacientes: full, pseudonym o none
+	From     time.Time
+	To       time.Time
+	Rows     int
+	Complete bool
+}
+
+// AuditFilter filtros de la consulta de administración
+type AuditFilter struct {
+	PatientID  *uuid.UUID
+	ActorID    *uuid.UUID
+	EntityType string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// Campos que no aportan al diff (timestamps automáticos)
+var auditIgnoredFields = map[string]bool{
+	"CreatedAt": true,
+	"UpdatedAt": true,
+	"DeletedAt": true,
+	"InvitedAt": true,
+}
+
+type AuditService struct{}
+
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// Record guarda un cambio. Debe llamarse dentro de la misma transacción que el cambio,
+// así no existe un cambio sin su registro de auditoría (ni al revés).
+func (s *AuditService) Record(tx *gorm.DB, actor AuditActor, entry AuditEntry) error {
+	changes, err := json.Marshal(AuditDiff(entry.Before, entry.After))
+	if err != nil {
+		return err
+	}
+
+	log := domains.AuditLog{
+		ActorID:    actor.UserID,
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		PatientID:  entry.PatientID,
+		Changes:    datatypes.JSON(changes),
+		IPAddress:  actor.IPAddress,
+		UserAgent:  actor.UserAgent,
+	}
+	return tx.Create(&log).Error
+}
+
+// RecordView registra una lectura de datos clínicos. Un fallo se registra en logs pero no
+// bloquea la respuesta al usuario.
+func (s *AuditService) RecordView(actor AuditActor, entityType string, entityID uuid.UUID, patientID *uuid.UUID) {
+	entry := AuditEntry{Action: domains.AuditView, EntityType: entityType, EntityID: entityID, PatientID: patientID}
+	if err := s.Record(database.GetDB(), actor, entry); err != nil {
+		slog.Error("Failed to record audit view", "entity", entityType, "id", entityID, "error", err)
+	}
+}
+
+// RecordExport registra una exportación: una entrada por la exportación y, si entregó datos
+// identificados, una por cada paciente incluido (así aparece en la auditoría de su ficha).
+func (s *AuditService) RecordExport(actor AuditActor, entityType string, detail AuditExportDetail, patientIDs []uuid.UUID) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		entry := AuditEntry{Action: domains.AuditExport, EntityType: entityType, EntityID: detail.ExportID, After: detail}
+		if err := s.Record(tx, actor, entry); err != nil {
+			return err
+		}
+		for _, patientID := range patientIDs {
+			entry := AuditEntry{Action: domains.AuditExport, EntityType: domains.AuditEntityPatient, EntityID: patientID, PatientID: &patientID, After: detail}
+			if err := s.Record(tx, actor, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List consulta el registro (más reciente primero)
+func (s *AuditService) List(filter AuditFilter, page, limit int) ([]domains.AuditLog, int64, error) {
+	query := database.GetDB().Model(&domains.AuditLog{})
+
+	if filter.PatientID != nil {
+		query = query.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []domains.AuditLog
+	err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error
+	return logs, total, err
+}
+
+// AuditDiff compara dos versiones de una entidad campo a campo.
+// Las relaciones precargadas (objetos con "ID", ej: Session.Creator) se omiten.
+func AuditDiff(before, after interface{}) map[string]map[string]interface{} {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	diff := make(map[string]map[string]interface{})
+	for field, value := range afterFields {
+		if old, ok := beforeFields[field]; !ok || !reflect.DeepEqual(old, value) {
+			diff[field] = map[string]interface{}{"before": beforeFields[field], "after": value}
+		}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			diff[field] = map[string]interface{}{"before": old, "after": nil}
+		}
+	}
+	return diff
+}
+
+func auditFields(entity interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if entity == nil {
+		return fields
+	}
+
+	raw, err := json.Marshal(entity)
+	if err != nil {
+		return fields
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return fields
+	}
+
+	for field, value := range decoded {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			if _, isRelation := nested["ID"]; isRelation {
+				continue
+			}
+		}
+		fields[field] = value
+	}
+	return fields
+}
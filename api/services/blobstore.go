@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describe un archivo almacenado
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// BlobStore abstrae dónde viven los archivos (Supabase Storage, disco local, memoria).
+// Las claves (key) son rutas relativas dentro del bucket, ej: "uuid_1700000000.pdf".
+// Los buckets son privados: el acceso a datos clínicos es solo vía SignedURL o Get.
+type BlobStore interface {
+	// Put guarda el contenido; size puede ser -1 si no se conoce
+	Put(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error)
+	Delete(ctx context.Context, bucket, key string) error
+	// SignedURL genera un link temporal de descarga que expira tras ttl
+	SignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error)
+}
+
+// NewBlobStore elige la implementación según cfg.StorageDriver
+func NewBlobStore(cfg *config.Config) BlobStore {
+	switch cfg.StorageDriver {
+	case "local":
+		return NewLocalBlobStore(cfg.StorageLocalDir, cfg.PublicBaseURL, cfg.StorageSigningKey)
+	case "memory":
+		return NewMemoryBlobStore()
+	case "supabase", "":
+		return NewSupabaseBlobStore(cfg.SupabaseURL, cfg.SupabaseKey)
+	default:
+		slog.Warn("Unknown STORAGE_DRIVER, falling back to supabase", "driver", cfg.StorageDriver)
+		return NewSupabaseBlobStore(cfg.SupabaseURL, cfg.SupabaseKey)
+	}
+}
+
+var (
+	sharedBlobStore     BlobStore
+	sharedBlobStoreOnce sync.Once
+)
+
+// GetBlobStore devuelve la instancia compartida por todo el proceso
+// (necesario para "memory" y "local", que guardan estado).
+func GetBlobStore(cfg *config.Config) BlobStore {
+	sharedBlobStoreOnce.Do(func() {
+		sharedBlobStore = NewBlobStore(cfg)
+		slog.Info("Blob store initialized", "driver", cfg.StorageDriver)
+	})
+	return sharedBlobStore
+}
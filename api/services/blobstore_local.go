@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalBlobStore guarda los archivos en disco: {dir}/{bucket}/{key}.
+// Pensado para desarrollo offline, tests de integración o self-hosting.
+// Los archivos se sirven por GET /files/{bucket}/{key} solo con firma válida (ver handlers/common/files.go).
+type LocalBlobStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+}
+
+var errSigningKeyMissing = errors.New("storage signing key is not configured")
+
+func NewLocalBlobStore(dir string, baseURL string, signingKey string) *LocalBlobStore {
+	return &LocalBlobStore{
+		dir:        dir,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		signingKey: []byte(signingKey),
+	}
+}
+
+// path valida bucket/key (sin "..", sin rutas absolutas) y arma la ruta en disco
+func (s *LocalBlobStore) path(bucket, key string) (string, error) {
+	clean := path.Clean("/" + bucket + "/" + key)
+	if bucket == "" || key == "" || strings.Contains(bucket, "/") || clean != "/"+bucket+"/"+key {
+		return "", fmt.Errorf("invalid object key: %s/%s", bucket, key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error {
+	fullPath, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+
+	// Escribimos a un temporal y renombramos: nunca queda un archivo a medias
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	fullPath, err := s.path(bucket, key)
+	if err != nil {
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ObjectInfo{}, ErrObjectNotFound
+		}
+		return nil, ObjectInfo{}, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, ObjectInfo{}, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return file, ObjectInfo{Size: stat.Size(), ContentType: contentType}, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, bucket, key string) error {
+	fullPath, err := s.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL: {baseURL}/files/{bucket}/{key}?expires={unix}&signature={hmac}
+func (s *LocalBlobStore) SignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error) {
+	if len(s.signingKey) == 0 {
+		return "", errSigningKeyMissing
+	}
+	fullPath, err := s.path(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		return "", ErrObjectNotFound
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	fileURL := fmt.Sprintf("%s/files/%s/%s", s.baseURL, bucket, escapeKey(key))
+	return fmt.Sprintf("%s?expires=%s&signature=%s", fileURL, expires, s.sign(bucket, key, expires)), nil
+}
+
+// VerifySignature valida un link generado por SignedURL (firma y expiración).
+// Sin clave no se acepta ningún link: con una clave vacía cualquiera podría firmarlos.
+func (s *LocalBlobStore) VerifySignature(bucket, key, expires, signature string) bool {
+	if len(s.signingKey) == 0 {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	expected := s.sign(bucket, key, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func (s *LocalBlobStore) sign(bucket, key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(bucket + "/" + key + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
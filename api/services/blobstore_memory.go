@@ -0,0 +1,65 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryBlobStore guarda los archivos en RAM. Solo para tests: se pierde al reiniciar.
+type MemoryBlobStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{objects: make(map[string]memoryObject)}
+}
+
+func (s *MemoryBlobStore) Put(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucket+"/"+key] = memoryObject{data: data, contentType: contentType}
+	return nil
+}
+
+func (s *MemoryBlobStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), ObjectInfo{Size: int64(len(obj.data)), ContentType: obj.contentType}, nil
+}
+
+func (s *MemoryBlobStore) Delete(ctx context.Context, bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, bucket+"/"+key)
+	return nil
+}
+
+func (s *MemoryBlobStore) SignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.objects[bucket+"/"+key]; !ok {
+		return "", ErrObjectNotFound
+	}
+	return fmt.Sprintf("memory://%s/%s?expires=%d", bucket, key, time.Now().Add(ttl).Unix()), nil
+}
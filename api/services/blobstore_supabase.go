@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SupabaseBlobStore usa la REST API de Supabase Storage
+type SupabaseBlobStore struct {
+	baseURL    string
+	serviceKey string
+	client     *http.Client
+}
+
+func NewSupabaseBlobStore(baseURL string, serviceKey string) *SupabaseBlobStore {
+	return &SupabaseBlobStore{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		serviceKey: serviceKey,
+		client:     &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (s *SupabaseBlobStore) objectURL(bucket, key string) string {
+	return fmt.Sprintf("%s/storage/v1/object/%s/%s", s.baseURL, bucket, escapeKey(key))
+}
+
+func (s *SupabaseBlobStore) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	// Headers requeridos por Supabase
+	req.Header.Set("Authorization", "Bearer "+s.serviceKey)
+	return req, nil
+}
+
+// Put: POST /storage/v1/object/{bucket}/{path}
+func (s *SupabaseBlobStore) Put(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPost, s.objectURL(bucket, key), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if size >= 0 {
+		req.ContentLength = size
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		slog.Error("Failed to request Supabase Storage", "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		slog.Error("Supabase Storage Error", "status", resp.StatusCode, "body", string(respBody))
+		return fmt.Errorf("failed to upload file, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Get: GET /storage/v1/object/{bucket}/{path} (autenticado, sirve para buckets privados)
+func (s *SupabaseBlobStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, s.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, ObjectInfo{Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}, nil
+	case http.StatusNotFound, http.StatusBadRequest: // Supabase responde 400 "Object not found"
+		resp.Body.Close()
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	default:
+		resp.Body.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("failed to download file, status: %d", resp.StatusCode)
+	}
+}
+
+// Delete: DELETE /storage/v1/object/{bucket}/{path}
+func (s *SupabaseBlobStore) Delete(ctx context.Context, bucket, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, s.objectURL(bucket, key), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete file, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignedURL: POST /storage/v1/object/sign/{bucket}/{path} {"expiresIn": segundos}
+func (s *SupabaseBlobStore) SignedURL(ctx context.Context, bucket, key string, ttl time.Duration) (string, error) {
+	payload, _ := json.Marshal(map[string]int{"expiresIn": int(ttl.Seconds())})
+	signURL := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s", s.baseURL, bucket, escapeKey(key))
+
+	req, err := s.newRequest(ctx, http.MethodPost, signURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+			return "", ErrObjectNotFound
+		}
+		return "", fmt.Errorf("failed to sign url, status: %d", resp.StatusCode)
+	}
+
+	var signed struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&signed); err != nil {
+		return "", err
+	}
+
+	// Supabase retorna una ruta relativa: /object/sign/{bucket}/{path}?token=...
+	return s.baseURL + "/storage/v1" + signed.SignedURL, nil
+}
+
+// escapeKey escapa cada segmento de la ruta conservando los "/"
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Estado del consentimiento vigente de un paciente
+const (
+	ConsentValid   = "VALID"
+	ConsentRevoked = "REVOKED"
+	ConsentExpired = "EXPIRED"
+	ConsentMissing = "MISSING"
+)
+
+var (
+	ErrConsentRevoked        = errors.New("patient consent is revoked")
+	ErrConsentExpired        = errors.New("patient consent has expired")
+	ErrConsentNotFound       = errors.New("patient has no consent document")
+	ErrConsentAlreadyRevoked = errors.New("consent already revoked")
+	ErrInvalidConsentDates   = errors.New("invalid consent dates")
+)
+
+type ConsentService struct {
+	Config      *config.Config
+	attachments *AttachmentService
+}
+
+func NewConsentService(cfg *config.Config) *ConsentService {
+	return &ConsentService{Config: cfg, attachments: NewAttachmentService(cfg)}
+}
+
+// ParseConsentDates valida las fechas del input (YYYY-MM-DD). signed vacío = hoy.
+func ParseConsentDates(signed, expires string) (time.Time, *time.Time, error) {
+	if signed == "" {
+		signed = time.Now().Format("2006-01-02")
+	}
+
+	signedAt, err := time.Parse("2006-01-02", signed)
+	if err != nil {
+		return time.Time{}, nil, ErrInvalidConsentDates
+	}
+	if expires == "" {
+		return signedAt, nil, nil
+	}
+
+	expiresAt, err := time.Parse("2006-01-02", expires)
+	if err != nil || expiresAt.Before(signedAt) {
+		return time.Time{}, nil, ErrInvalidConsentDates
+	}
+	return signedAt, &expiresAt, nil
+}
+
+// AddVersion registra una nueva versión del consentimiento y la deja como vigente.
+// Debe llamarse dentro de una transacción (ej: junto con la creación del paciente).
+func (s *ConsentService) AddVersion(tx *gorm.DB, patientID uuid.UUID, uploader domains.User, key string, signedAt time.Time, expiresAt *time.Time) (domains.ConsentDocument, error) {
+	// 1. Bloquear al paciente para numerar versiones sin colisiones
+	var patient domains.Patient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&patient, "id = ?", patientID).Error; err != nil {
+		return domains.ConsentDocument{}, err
+	}
+	var lastVersion int
+	if err := tx.Model(&domains.ConsentDocument{}).
+		Where("patient_id = ?", patientID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&lastVersion).Error; err != nil {
+		return domains.ConsentDocument{}, err
+	}
+
+	// 2. Crear la versión
+	doc := domains.ConsentDocument{
+		PatientID:    patientID,
+		Version:      lastVersion + 1,
+		ObjectKey:    key,
+		UploadedByID: uploader.ID,
+		SignedAt:     signedAt,
+		ExpiresAt:    expiresAt,
+	}
+	if err := tx.Create(&doc).Error; err != nil {
+		return domains.ConsentDocument{}, err
+	}
+
+	// 3. El archivo queda asociado a esta versión (las anteriores conservan el suyo)
+	if err := s.attachments.LinkToEntity(tx, uploader, BucketPatientsConsent, []string{key}, nil, patientID, domains.AttachmentEntityConsent, doc.ID); err != nil {
+		return domains.ConsentDocument{}, err
+	}
+
+	// 4. Puntero a la versión vigente (compatibilidad con el frontend actual)
+	if err := tx.Model(&domains.Patient{}).Where("id = ?", patientID).Update("consent_pdf_url", key).Error; err != nil {
+		return domains.ConsentDocument{}, err
+	}
+	return doc, nil
+}
+
+// Revoke revoca la versión vigente. No se pueden registrar sesiones hasta subir una nueva versión.
+// Debe llamarse dentro de una transacción (junto con su registro de auditoría); retorna la
+// versión revocada y su estado anterior.
+func (s *ConsentService) Revoke(tx *gorm.DB, patientID uuid.UUID, user domains.User, reason string) (domains.ConsentDocument, domains.ConsentDocument, error) {
+	var doc domains.ConsentDocument
+	var patient domains.Patient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&patient, "id = ?", patientID).Error; err != nil {
+		return doc, doc, err
+	}
+	if err := tx.Where("patient_id = ?", patientID).Order("version DESC").First(&doc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return doc, doc, ErrConsentNotFound
+		}
+		return doc, doc, err
+	}
+	if doc.Revoked {
+		return doc, doc, ErrConsentAlreadyRevoked
+	}
+
+	before := doc
+	now := time.Now()
+	doc.Revoked = true
+	doc.RevokedAt = &now
+	doc.RevokedByID = &user.ID
+	doc.RevocationReason = reason
+	if err := tx.Omit("UploadedBy").Save(&doc).Error; err != nil {
+		return doc, before, err
+	}
+	return doc, before, nil
+}
+
+// History lista todas las versiones (la más reciente primero). Solo lee: la versión 1 de los
+// pacientes anteriores al historial la creó la migración 000016.
+func (s *ConsentService) History(patientID uuid.UUID) ([]domains.ConsentDocument, error) {
+	db := database.GetDB()
+
+	var patient domains.Patient
+	if err := db.Select("id").First(&patient, "id = ?", patientID).Error; err != nil {
+		return nil, err
+	}
+
+	var docs []domains.ConsentDocument
+	err := db.Preload("UploadedBy").Where("patient_id = ?", patientID).Order("version DESC").Find(&docs).Error
+	return docs, err
+}
+
+// Status retorna VALID, REVOKED, EXPIRED o MISSING según la última versión
+func (s *ConsentService) Status(patientID uuid.UUID, now time.Time) (string, error) {
+	var doc domains.ConsentDocument
+	err := database.GetDB().Where("patient_id = ?", patientID).Order("version DESC").First(&doc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ConsentMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case doc.Revoked:
+		return ConsentRevoked, nil
+	case doc.ExpiresAt != nil && now.Format("2006-01-02") > doc.ExpiresAt.Format("2006-01-02"):
+		return ConsentExpired, nil
+	}
+	return ConsentValid, nil
+}
+
+// CheckSessionAllowed bloquea nuevas sesiones si el consentimiento está revocado o vencido
+func (s *ConsentService) CheckSessionAllowed(patientID uuid.UUID, now time.Time) error {
+	status, err := s.Status(patientID, now)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case ConsentRevoked:
+		return ErrConsentRevoked
+	case ConsentExpired:
+		return ErrConsentExpired
+	case ConsentMissing:
+		return ErrConsentNotFound
+	}
+	return nil
+}
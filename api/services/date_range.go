@@ -0,0 +1,20 @@
+package services
+
+import "time"
+
+// ParseQueryDate interpreta las fechas de los filtros por periodo (?from= / ?to=): acepta RFC3339
+// o YYYY-MM-DD en la zona indicada. Con endOfRange, una fecha sin hora incluye todo ese día
+// (retorna el inicio del día siguiente, para usar como límite exclusivo).
+func ParseQueryDate(raw string, endOfRange bool, location *time.Location) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
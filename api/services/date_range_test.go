@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQueryDate(t *testing.T) {
+	santiago := time.FixedZone("CLT", -3*3600)
+
+	tests := []struct {
+		raw        string
+		endOfRange bool
+		location   *time.Location
+		want       time.Time
+		ok         bool
+	}{
+		{raw: "2025-03-10", location: time.UTC, want: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2025-03-10", endOfRange: true, location: time.UTC, want: time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2025-03-10", endOfRange: true, location: santiago, want: time.Date(2025, 3, 11, 0, 0, 0, 0, santiago), ok: true},
+		{raw: "2025-03-10T15:04:05Z", endOfRange: true, location: santiago, want: time.Date(2025, 3, 10, 15, 4, 5, 0, time.UTC), ok: true},
+		{raw: "10/03/2025", location: time.UTC},
+		{raw: "", location: time.UTC},
+	}
+	for _, tt := range tests {
+		got, ok := ParseQueryDate(tt.raw, tt.endOfRange, tt.location)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseQueryDate(%q, %v) = %v, %v; want %v, %v", tt.raw, tt.endOfRange, got, ok, tt.want, tt.ok)
+		}
+	}
+}
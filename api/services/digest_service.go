@@ -0,0 +1,327 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Máximo de elementos por sección en el correo (el resto se resume como "y N más")
+const digestSectionLimit = 20
+
+// DigestService arma y encola el correo resumen (diario o semanal) de cada usuario
+// que eligió el canal DIGEST para al menos un tipo de evento.
+type DigestService struct {
+	cfg      *config.Config
+	location *time.Location
+}
+
+func NewDigestService(cfg *config.Config) *DigestService {
+	location, err := time.LoadLocation(cfg.DigestTimezone)
+	if err != nil {
+		slog.Warn("Invalid DIGEST_TIMEZONE, using UTC", "timezone", cfg.DigestTimezone, "error", err)
+		location = time.UTC
+	}
+	return &DigestService{cfg: cfg, location: location}
+}
+
+// DigestRunResult resume una ejecución (útil para el endpoint "run now")
+type DigestRunResult struct {
+	Frequency   domains.DigestFrequency `json:"frequency"`
+	PeriodStart time.Time               `json:"period_start"`
+	PeriodEnd   time.Time               `json:"period_end"`
+	Sent        int                     `json:"sent"`
+	Skipped     int                     `json:"skipped"` // Ya enviado en este periodo o sin novedades
+	Failed      int                     `json:"failed"`
+}
+
+// RunScheduled es llamado por el Scheduler: envía los resúmenes cuyo periodo ya cerró
+// y cuya hora de envío (cfg.DigestHour) ya pasó. Los duplicados se descartan vía DigestLog.
+// El semanal se revisa todos los días: si el lunes no hubo ejecución (instancia caída),
+// el siguiente chequeo de la semana envía lo que falte de la última semana completa.
+func (s *DigestService) RunScheduled(now time.Time) {
+	local := now.In(s.location)
+	if local.Hour() < s.cfg.DigestHour {
+		return
+	}
+
+	s.Run(domains.DigestDaily, now)
+	s.Run(domains.DigestWeekly, now)
+}
+
+// Run envía los resúmenes del último periodo completo para la frecuencia indicada
+func (s *DigestService) Run(frequency domains.DigestFrequency, now time.Time) DigestRunResult {
+	start, end := s.lastPeriod(frequency, now)
+	result := DigestRunResult{Frequency: frequency, PeriodStart: start, PeriodEnd: end}
+
+	users, err := s.subscribers(frequency)
+	if err != nil {
+		slog.Error("Failed to load digest subscribers", "error", err)
+		return result
+	}
+
+	for _, user := range users {
+		sent, err := s.sendDigest(user, frequency, start, end)
+		switch {
+		case err != nil:
+			result.Failed++
+			slog.Error("Failed to send digest", "userID", user.ID, "error", err)
+		case sent:
+			result.Sent++
+		default:
+			result.Skipped++
+		}
+	}
+
+	slog.Info("Digest run finished", "frequency", frequency, "sent", result.Sent, "skipped", result.Skipped, "failed", result.Failed)
+	return result
+}
+
+// lastPeriod calcula el último día (00:00 a 00:00) o semana (lunes a lunes) completo
+func (s *DigestService) lastPeriod(frequency domains.DigestFrequency, now time.Time) (time.Time, time.Time) {
+	local := now.In(s.location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location)
+
+	if frequency == domains.DigestWeekly {
+		// Días desde el lunes (Go: domingo = 0)
+		offset := (int(today.Weekday()) + 6) % 7
+		thisMonday := today.AddDate(0, 0, -offset)
+		return thisMonday.AddDate(0, 0, -7), thisMonday
+	}
+	return today.AddDate(0, 0, -1), today
+}
+
+// subscribers: usuarios activos con al menos un evento en DIGEST y la frecuencia pedida
+// (ProfileData.digest_frequency, DAILY por defecto)
+func (s *DigestService) subscribers(frequency domains.DigestFrequency) ([]domains.User, error) {
+	db := database.GetDB()
+
+	var users []domains.User
+	if err := db.Where("status = ?", domains.StatusActive).
+		Where("id IN (?)", db.Model(&domains.NotificationPreference{}).
+			Select("user_id").
+			Where("channel = ?", domains.ChannelDigest)).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	filtered := users[:0]
+	for _, u := range users {
+		if UserDigestFrequency(u) == frequency {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered, nil
+}
+
+// UserDigestFrequency lee la frecuencia elegida desde ProfileData
+func UserDigestFrequency(user domains.User) domains.DigestFrequency {
+	var profile map[string]interface{}
+	if len(user.ProfileData) > 0 && json.Unmarshal(user.ProfileData, &profile) == nil {
+		if value, ok := profile["digest_frequency"].(string); ok && domains.DigestFrequency(value) == domains.DigestWeekly {
+			return domains.DigestWeekly
+		}
+	}
+	return domains.DigestDaily
+}
+
+// --- Datos del resumen ---
+
+type digestLine struct {
+	Time string
+	Text string
+}
+
+type digestData struct {
+	PeriodStart string
+	PeriodEnd   string
+	Weekly      bool
+
+	Notifications      []digestLine
+	NewSessions        []digestLine
+	PendingInvitations []digestLine
+	PendingUsers       []digestLine
+	Omitted            int // Elementos que no entraron por el límite por sección
+}
+
+func (d digestData) itemCount() int {
+	return len(d.Notifications) + len(d.NewSessions) + len(d.PendingInvitations) + len(d.PendingUsers) + d.Omitted
+}
+
+// sendDigest arma el resumen de un usuario y lo encola. Retorna false si ya se había
+// enviado para este periodo o si no hay novedades.
+func (s *DigestService) sendDigest(user domains.User, frequency domains.DigestFrequency, start, end time.Time) (bool, error) {
+	db := database.GetDB()
+
+	// 1. ¿Ya se envió este periodo? (chequeo rápido, la garantía real es el índice único)
+	var existing int64
+	db.Model(&domains.DigestLog{}).
+		Where("user_id = ? AND frequency = ? AND period_start = ?", user.ID, frequency, start).
+		Count(&existing)
+	if existing > 0 {
+		return false, nil
+	}
+
+	data, err := s.collect(user, start, end)
+	if err != nil {
+		return false, err
+	}
+	data.Weekly = frequency == domains.DigestWeekly
+
+	if data.itemCount() == 0 {
+		return false, nil
+	}
+
+	rendered, err := renderEmail(domains.NotifDigest, userLocale(user), data)
+	if err != nil {
+		return false, fmt.Errorf("render digest: %w", err)
+	}
+
+	sent := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 2. Reclamar el periodo: si otra ejecución lo tomó primero, no se inserta nada
+		logEntry := domains.DigestLog{
+			UserID:      user.ID,
+			Frequency:   frequency,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			ItemCount:   data.itemCount(),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&logEntry)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// 3. Encolar el correo (lo envía EmailOutboxWorker)
+		sent = true
+		return EnqueueEmail(tx, &user.ID, user.Email, rendered)
+	})
+	return sent, err
+}
+
+// collect reúne las secciones del resumen para el periodo [start, end)
+func (s *DigestService) collect(user domains.User, start, end time.Time) (digestData, error) {
+	db := database.GetDB()
+	data := digestData{
+		PeriodStart: start.Format("2006-01-02"),
+		PeriodEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+
+	// A. Notificaciones sin leer de los eventos que el usuario pidió en DIGEST
+	var notifications []domains.Notification
+	if err := db.Where("user_id = ? AND is_read = ? AND created_at >= ? AND created_at < ?", user.ID, false, start, end).
+		Where("type IN (?)", db.Model(&domains.NotificationPreference{}).
+			Select("event_type").
+			Where("user_id = ? AND channel = ?", user.ID, domains.ChannelDigest)).
+		Order("created_at ASC").
+		Find(&notifications).Error; err != nil {
+		return data, err
+	}
+	for _, n := range notifications {
+		data.Notifications = append(data.Notifications, digestLine{
+			Time: n.CreatedAt.In(s.location).Format("02/01 15:04"),
+			Text: n.Message,
+		})
+	}
+
+	// B. Sesiones nuevas en sus pacientes (registradas por otros profesionales)
+	var sessions []domains.Session
+	if err := db.Preload("Creator").
+		Where("patient_id IN (?)", NewAccessService().TeamPatientIDs(user.ID)).
+		Where("professional_id <> ? AND created_at >= ? AND created_at < ?", user.ID, start, end).
+		Order("created_at ASC").
+		Find(&sessions).Error; err != nil {
+		return data, err
+	}
+	patientNames := s.patientNames(sessions)
+	for _, session := range sessions {
+		text := fmt.Sprintf("%s — %s", patientNames[session.PatientID], UserDisplayName(session.Creator))
+		if session.HasIncident {
+			text += " ⚠️"
+		}
+		data.NewSessions = append(data.NewSessions, digestLine{
+			Time: session.CreatedAt.In(s.location).Format("02/01 15:04"),
+			Text: text,
+		})
+	}
+
+	// C. Invitaciones pendientes (foto actual, igual que GetPendingInvitationsHandler)
+	var invitations []domains.Collaboration
+	if err := db.Preload("Patient").
+		Where("professional_id = ? AND status = ?", user.ID, domains.CollabPending).
+		Find(&invitations).Error; err != nil {
+		return data, err
+	}
+	for _, inv := range invitations {
+		data.PendingInvitations = append(data.PendingInvitations, digestLine{
+			Time: inv.InvitedAt.In(s.location).Format("02/01 15:04"),
+			Text: PatientDisplayName(inv.Patient),
+		})
+	}
+
+	// D. Usuarios esperando aprobación (solo ADMIN)
+	if user.Role == domains.RoleAdmin {
+		var pending []domains.User
+		if err := db.Where("status = ?", domains.StatusInactive).Order("created_at ASC").Find(&pending).Error; err != nil {
+			return data, err
+		}
+		for _, u := range pending {
+			data.PendingUsers = append(data.PendingUsers, digestLine{
+				Time: u.CreatedAt.In(s.location).Format("02/01 15:04"),
+				Text: u.Email,
+			})
+		}
+	}
+
+	data.Notifications = data.truncate(data.Notifications)
+	data.NewSessions = data.truncate(data.NewSessions)
+	data.PendingInvitations = data.truncate(data.PendingInvitations)
+	data.PendingUsers = data.truncate(data.PendingUsers)
+
+	return data, nil
+}
+
+func (d *digestData) truncate(lines []digestLine) []digestLine {
+	if len(lines) <= digestSectionLimit {
+		return lines
+	}
+	d.Omitted += len(lines) - digestSectionLimit
+	return lines[:digestSectionLimit]
+}
+
+func (s *DigestService) patientNames(sessions []domains.Session) map[uuid.UUID]string {
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.PatientID)
+	}
+
+	names := make(map[uuid.UUID]string, len(ids))
+	if len(ids) == 0 {
+		return names
+	}
+
+	var patients []domains.Patient
+	database.GetDB().Select("id", "personal_info").Where("id IN ?", ids).Find(&patients)
+	for _, p := range patients {
+		names[p.ID] = PatientDisplayName(p)
+	}
+	for _, id := range ids {
+		if _, ok := names[id]; !ok {
+			names[id] = "Paciente " + strings.ToUpper(id.String()[:8])
+		}
+	}
+	return names
+}
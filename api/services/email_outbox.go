@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Correos reclamados por ciclo del worker
+	outboxBatchSize = 20
+	// Mientras un worker envía un lote, los correos quedan "arrendados" para que otra
+	// instancia no los tome. Si el proceso muere, vuelven a la cola al expirar.
+	// El arriendo se renueva antes de cada envío, así que solo debe cubrir un envío (outboxSendTimeout).
+	outboxLeaseDuration = 5 * time.Minute
+	// Backoff exponencial: 30s, 1m, 2m, 4m... con tope de 1 hora
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	// Tiempo máximo por envío individual
+	outboxSendTimeout = 30 * time.Second
+)
+
+// EnqueueEmail agrega un correo a la cola persistente. Acepta un *gorm.DB para poder
+// encolar dentro de la misma transacción que genera el evento.
+func EnqueueEmail(tx *gorm.DB, userID *uuid.UUID, to string, email RenderedEmail) error {
+	outbox := domains.EmailOutbox{
+		UserID:        userID,
+		ToAddress:     to,
+		Subject:       email.Subject,
+		Body:          email.Text,
+		HTMLBody:      email.HTML,
+		Status:        domains.EmailPending,
+		NextAttemptAt: time.Now(),
+	}
+	return tx.Create(&outbox).Error
+}
+
+// RetryEmail devuelve un correo (normalmente DEAD) a la cola con los intentos reiniciados
+func RetryEmail(id string) (*domains.EmailOutbox, error) {
+	db := database.GetDB()
+
+	var email domains.EmailOutbox
+	if err := db.First(&email, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	if email.Status == domains.EmailSent {
+		return nil, fmt.Errorf("email already sent")
+	}
+
+	email.Status = domains.EmailPending
+	email.Attempts = 0
+	email.NextAttemptAt = time.Now()
+
+	if err := db.Save(&email).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// EmailOutboxWorker envía los correos pendientes en segundo plano
+type EmailOutboxWorker struct {
+	cfg    *config.Config
+	mailer Mailer
+}
+
+func NewEmailOutboxWorker(cfg *config.Config, mailer Mailer) *EmailOutboxWorker {
+	return &EmailOutboxWorker{cfg: cfg, mailer: mailer}
+}
+
+// Start bloquea procesando la cola cada cfg.EmailWorkerInterval hasta que ctx se cancela
+func (w *EmailOutboxWorker) Start(ctx context.Context) {
+	slog.Info("Email outbox worker started", "interval", w.cfg.EmailWorkerInterval, "max_attempts", w.cfg.EmailMaxAttempts)
+
+	ticker := time.NewTicker(w.cfg.EmailWorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		w.ProcessBatch()
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Email outbox worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch reclama un lote de correos vencidos y los intenta enviar
+func (w *EmailOutboxWorker) ProcessBatch() {
+	emails, err := w.claimBatch()
+	if err != nil {
+		slog.Error("Failed to claim outbox batch", "error", err)
+		return
+	}
+
+	for _, email := range emails {
+		w.deliver(email)
+	}
+}
+
+// claimBatch toma correos PENDING vencidos con FOR UPDATE SKIP LOCKED y los arrienda,
+// de modo que varias instancias del backend pueden correr el worker sin duplicar envíos
+func (w *EmailOutboxWorker) claimBatch() ([]domains.EmailOutbox, error) {
+	var emails []domains.EmailOutbox
+	now := time.Now()
+	lease := leaseUntil(now)
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domains.EmailPending, now).
+			Order("next_attempt_at ASC").
+			Limit(outboxBatchSize).
+			Find(&emails).Error; err != nil {
+			return err
+		}
+
+		if len(emails) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(emails))
+		for _, e := range emails {
+			ids = append(ids, e.ID)
+		}
+
+		if err := tx.Model(&domains.EmailOutbox{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", lease).Error; err != nil {
+			return err
+		}
+		for i := range emails {
+			emails[i].NextAttemptAt = lease
+		}
+		return nil
+	})
+
+	return emails, err
+}
+
+// leaseUntil calcula el vencimiento de un arriendo. Se trunca a microsegundos (precisión de
+// timestamptz) porque el arriendo vigente se compara por igualdad en las actualizaciones.
+func leaseUntil(now time.Time) time.Time {
+	return now.Add(outboxLeaseDuration).Truncate(time.Microsecond)
+}
+
+// renewLease extiende el arriendo de un correo justo antes de enviarlo. Retorna false si el
+// arriendo del lote ya venció y otra instancia reclamó el correo (no se debe enviar dos veces).
+func renewLease(db *gorm.DB, email *domains.EmailOutbox) (bool, error) {
+	lease := leaseUntil(time.Now())
+	result := db.Model(&domains.EmailOutbox{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", email.ID, domains.EmailPending, email.NextAttemptAt).
+		Update("next_attempt_at", lease)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	email.NextAttemptAt = lease
+	return true, nil
+}
+
+func (w *EmailOutboxWorker) deliver(email domains.EmailOutbox) {
+	db := database.GetDB()
+
+	// 1. Renovar el arriendo: un lote completo puede tardar más que outboxLeaseDuration
+	leased, err := renewLease(db, &email)
+	if err != nil {
+		slog.Error("Failed to renew outbox lease", "id", email.ID, "error", err)
+		return
+	}
+	if !leased {
+		slog.Warn("Outbox email lease lost, skipping", "id", email.ID)
+		return
+	}
+	lease := email.NextAttemptAt
+
+	// 2. Enviar
+	email.Attempts++
+
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	sendErr := w.mailer.Send(ctx, MailMessage{
+		From:     w.cfg.MailFrom,
+		To:       email.ToAddress,
+		Subject:  email.Subject,
+		TextBody: email.Body,
+		HTMLBody: email.HTMLBody,
+	})
+	cancel()
+	if sendErr == nil {
+		sentAt := time.Now()
+		email.Status = domains.EmailSent
+		email.SentAt = &sentAt
+		email.LastError = ""
+		slog.Info("✅ Email sent successfully", "to", email.ToAddress, "subject", email.Subject, "attempt", email.Attempts)
+	} else {
+		email.LastError = sendErr.Error()
+		if email.Attempts >= w.cfg.EmailMaxAttempts {
+			email.Status = domains.EmailDead
+			slog.Error("❌ Email moved to dead-letter", "to", email.ToAddress, "attempts", email.Attempts, "error", sendErr)
+		} else {
+			email.NextAttemptAt = time.Now().Add(backoffFor(email.Attempts))
+			slog.Warn("Email delivery failed, will retry", "to", email.ToAddress, "attempt", email.Attempts, "next_attempt_at", email.NextAttemptAt, "error", sendErr)
+		}
+	}
+
+	// 3. Guardar el resultado solo si el arriendo sigue siendo nuestro (no un Save de la fila completa)
+	result := db.Model(&domains.EmailOutbox{}).
+		Where("id = ? AND next_attempt_at = ?", email.ID, lease).
+		Updates(map[string]interface{}{
+			"status":          email.Status,
+			"attempts":        email.Attempts,
+			"last_error":      email.LastError,
+			"sent_at":         email.SentAt,
+			"next_attempt_at": email.NextAttemptAt,
+		})
+	if result.Error != nil {
+		slog.Error("Failed to update outbox email", "id", email.ID, "error", result.Error)
+	} else if result.RowsAffected == 0 {
+		slog.Warn("Outbox email lease expired before saving the delivery result", "id", email.ID, "status", email.Status)
+	}
+}
+
+// backoffFor calcula la espera antes del siguiente intento (attempt empieza en 1)
+func backoffFor(attempt int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
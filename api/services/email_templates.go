@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"bitacora-medica-backend/api/domains"
+)
+
+// Plantillas por evento y por idioma:
+//
+//	templates/email/layout.html          -> Estructura HTML común
+//	templates/email/{locale}/common.html -> Pie de página
+//	templates/email/{locale}/{event}.txt -> bloques "subject" y "text"
+//	templates/email/{locale}/{event}.html -> bloque "content"
+//
+//go:embed templates/email
+var emailTemplatesFS embed.FS
+
+const defaultLocale = "es"
+
+var supportedLocales = map[string]bool{"es": true, "en": true}
+
+// RenderedEmail es el resultado de aplicar una plantilla a los datos de un evento
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// renderEmail genera asunto, texto plano y HTML para un tipo de notificación (ej: "INCIDENT_ALERT")
+func renderEmail(notifType string, locale string, data interface{}) (RenderedEmail, error) {
+	if !supportedLocales[locale] {
+		locale = defaultLocale
+	}
+	name := strings.ToLower(notifType)
+	dir := "templates/email/" + locale
+
+	// 1. Asunto y cuerpo de texto plano (text/template: sin escapar HTML)
+	textTmpl, err := texttemplate.ParseFS(emailTemplatesFS, dir+"/"+name+".txt")
+	if err != nil {
+		return RenderedEmail{}, fmt.Errorf("load text template %s/%s: %w", locale, name, err)
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return RenderedEmail{}, err
+	}
+	if err := textTmpl.ExecuteTemplate(&text, "text", data); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	// 2. HTML (html/template: escapa los datos ingresados por usuarios)
+	htmlTmpl, err := htmltemplate.ParseFS(emailTemplatesFS,
+		"templates/email/layout.html",
+		dir+"/common.html",
+		dir+"/"+name+".html",
+	)
+	if err != nil {
+		return RenderedEmail{}, fmt.Errorf("load html template %s/%s: %w", locale, name, err)
+	}
+
+	var html bytes.Buffer
+	layoutData := map[string]interface{}{
+		"Locale":  locale,
+		"Subject": strings.TrimSpace(subject.String()),
+		"Data":    data,
+	}
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout", layoutData); err != nil {
+		return RenderedEmail{}, err
+	}
+
+	return RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    html.String(),
+	}, nil
+}
+
+// userLocale obtiene el idioma preferido desde ProfileData ("locale" o "language", ej: "en-US")
+func userLocale(user domains.User) string {
+	var profile map[string]interface{}
+	if len(user.ProfileData) == 0 || json.Unmarshal(user.ProfileData, &profile) != nil {
+		return defaultLocale
+	}
+
+	for _, key := range []string{"locale", "language"} {
+		if value, ok := profile[key].(string); ok && value != "" {
+			locale := strings.ToLower(strings.SplitN(strings.ReplaceAll(value, "_", "-"), "-", 2)[0])
+			if supportedLocales[locale] {
+				return locale
+			}
+		}
+	}
+	return defaultLocale
+}
+
+// UserDisplayName usa el nombre del perfil (ProfileData.full_name) o el email como respaldo
+func UserDisplayName(user domains.User) string {
+	var profile map[string]interface{}
+	if len(user.ProfileData) > 0 && json.Unmarshal(user.ProfileData, &profile) == nil {
+		if name, ok := profile["full_name"].(string); ok && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return user.Email
+}
+
+// PatientDisplayName arma "Nombre Apellido" desde Patient.PersonalInfo
+func PatientDisplayName(patient domains.Patient) string {
+	var info map[string]interface{}
+	if len(patient.PersonalInfo) > 0 && json.Unmarshal(patient.PersonalInfo, &info) == nil {
+		first, _ := info["first_name"].(string)
+		last, _ := info["last_name"].(string)
+		if name := strings.TrimSpace(first + " " + last); name != "" {
+			return name
+		}
+	}
+	return "Paciente " + patient.ID.String()
+}
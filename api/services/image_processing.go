@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Variantes generadas para cada foto de sesión
+const (
+	ImageVariantOriginal = "original"
+	ImageVariantWeb      = "web"
+	ImageVariantThumb    = "thumb"
+)
+
+const (
+	imageWebMaxSide   = 1600
+	imageThumbMaxSide = 320
+	imageJPEGQuality  = 85
+)
+
+// ProcessedImage es una variante lista para subir (sin metadatos)
+type ProcessedImage struct {
+	Variant     string
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// ImageVariantKey arma la clave de una variante a partir de la clave original:
+// "uuid_ts.jpg" -> "uuid_ts_thumb.jpg"
+func ImageVariantKey(key, variant string) string {
+	if variant == ImageVariantOriginal || variant == "" {
+		return key
+	}
+	ext := filepath.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + variant + ext
+}
+
+// OriginalImageKey es la operación inversa de ImageVariantKey (para validar acceso a una variante)
+func OriginalImageKey(key string) string {
+	ext := filepath.Ext(key)
+	base := strings.TrimSuffix(key, ext)
+	for _, variant := range []string{ImageVariantWeb, ImageVariantThumb} {
+		if strings.HasSuffix(base, "_"+variant) {
+			return strings.TrimSuffix(base, "_"+variant) + ext
+		}
+	}
+	return key
+}
+
+// processImage decodifica la foto, la endereza según EXIF y la re-codifica en tres tamaños.
+// Re-codificar descarta todos los metadatos (GPS, modelo de cámara, etc.): ninguna foto se guarda tal cual.
+// maxPixels protege contra "decompression bombs": una imagen de 10MB puede declarar dimensiones enormes.
+func processImage(data []byte, detected DetectedFile, maxPixels int) ([]ProcessedImage, error) {
+	// 1. Validar dimensiones antes de decodificar
+	cfg, err := decodeImageConfig(data, detected.ContentType)
+	if err != nil {
+		return nil, ErrUnsupportedFileType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrFileTooLarge
+	}
+
+	// 2. Decodificar
+	img, err := decodeImage(data, detected.ContentType)
+	if err != nil {
+		return nil, ErrUnsupportedFileType
+	}
+
+	// 3. Orientación (solo JPEG trae EXIF en la práctica). Se aplica a cada variante ya reducida:
+	// rotar la foto completa para luego achicarla duplicaría la memoria en web y thumb.
+	orientation := 1
+	if detected.ContentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// 4. Formato de salida: PNG se mantiene (transparencias), el resto pasa a JPEG
+	encode, contentType, ext := encodeJPEG, "image/jpeg", ".jpg"
+	if detected.ContentType == "image/png" {
+		encode, contentType, ext = encodePNG, "image/png", ".png"
+	}
+
+	// 5. Generar variantes
+	variants := []struct {
+		name    string
+		maxSide int
+	}{
+		{ImageVariantOriginal, 0},
+		{ImageVariantWeb, imageWebMaxSide},
+		{ImageVariantThumb, imageThumbMaxSide},
+	}
+
+	processed := make([]ProcessedImage, 0, len(variants))
+	for _, v := range variants {
+		out, err := encode(applyOrientation(resizeToFit(img, v.maxSide), orientation))
+		if err != nil {
+			return nil, err
+		}
+		processed = append(processed, ProcessedImage{Variant: v.name, Data: out, ContentType: contentType, Ext: ext})
+	}
+	return processed, nil
+}
+
+// Formatos que no podemos decodificar en Go puro
+var errImageNotDecodable = errors.New("image format not decodable")
+
+func decodeImageConfig(data []byte, contentType string) (image.Config, error) {
+	r := bytes.NewReader(data)
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.DecodeConfig(r)
+	case "image/png":
+		return png.DecodeConfig(r)
+	case "image/webp":
+		return webp.DecodeConfig(r)
+	}
+	return image.Config{}, errImageNotDecodable
+}
+
+func decodeImage(data []byte, contentType string) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(r)
+	case "image/png":
+		return png.Decode(r)
+	case "image/webp":
+		return webp.Decode(r)
+	}
+	return nil, errImageNotDecodable
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageJPEGQuality})
+	return buf.Bytes(), err
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	err := encoder.Encode(&buf, img)
+	return buf.Bytes(), err
+}
+
+// resizeToFit reduce la imagen para que su lado mayor sea maxSide (0 = sin cambio; nunca agranda)
+func resizeToFit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+
+	newW, newH := maxSide, maxSide
+	if w >= h {
+		newH = max(1, h*maxSide/w)
+	} else {
+		newW = max(1, w*maxSide/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, newW, newH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// --- Orientación EXIF ---
+
+// jpegOrientation lee el tag Orientation (0x0112) del segmento APP1/Exif. Retorna 1 si no existe.
+func jpegOrientation(data []byte) int {
+	// Recorrer los segmentos JPEG: FF D8, luego FF xx + largo (big endian)
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 { // Inicio de datos de imagen / fin: no hay más metadatos
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation interpreta el encabezado TIFF y busca el tag en el IFD0
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rota/refleja la imagen para que se vea derecha (valores EXIF 1-8)
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// 5-8 intercambian ancho y alto
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for dy := 0; dy < dstH; dy++ {
+		for dx := 0; dx < dstW; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Espejo horizontal
+				sx, sy = w-1-dx, dy
+			case 3: // 180°
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // Espejo vertical
+				sx, sy = dx, h-1-dy
+			case 5: // Transponer
+				sx, sy = dy, dx
+			case 6: // 90° horario
+				sx, sy = dy, h-1-dx
+			case 7: // Transversa
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // 90° antihorario
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
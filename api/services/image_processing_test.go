@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withOrientation agrega un segmento APP1/Exif con el tag Orientation justo después del SOI
+func withOrientation(jpegData []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // Encabezado big endian, IFD0 en el offset 8
+		0, 1, // Una entrada
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // Orientation (SHORT)
+		0, 0, 0, 0, // Sin más IFDs
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, 0xFF, 0xE1, byte(length>>8), byte(length))
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestProcessImageOrientsEveryVariant(t *testing.T) {
+	// Foto horizontal de 2000x1000 tomada con el teléfono girado (EXIF 6: rotar 90° a la derecha)
+	img := image.NewGray(image.Rect(0, 0, 2000, 1000))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := withOrientation(buf.Bytes(), 6)
+
+	variants, err := processImage(data, DetectedFile{ContentType: "image/jpeg", Ext: ".jpg"}, 16_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]image.Point{
+		ImageVariantOriginal: {1000, 2000},
+		ImageVariantWeb:      {800, 1600},
+		ImageVariantThumb:    {160, 320},
+	}
+	for _, variant := range variants {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (image.Point{cfg.Width, cfg.Height}); got != want[variant.Variant] {
+			t.Errorf("%s = %v, want %v", variant.Variant, got, want[variant.Variant])
+		}
+		if jpegOrientation(variant.Data) != 1 {
+			t.Errorf("%s keeps the EXIF orientation", variant.Variant)
+		}
+	}
+}
+
+func TestProcessImagePixelLimit(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	detected := DetectedFile{ContentType: "image/png", Ext: ".png"}
+
+	if _, err := processImage(buf.Bytes(), detected, 19_999); err != ErrFileTooLarge {
+		t.Errorf("over the limit: err = %v, want ErrFileTooLarge", err)
+	}
+	if _, err := processImage(buf.Bytes(), detected, 20_000); err != nil {
+		t.Errorf("at the limit: err = %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPatientIdentity = errors.New("invalid patient identity mode")
+	ErrPseudonymKeyMissing    = errors.New("pseudonymized exports are disabled: EXPORT_PSEUDONYM_KEY is not configured")
+)
+
+// Cómo se identifica al paciente en la exportación
+const (
+	PatientIdentityFull      = "full"      // ID y nombre reales
+	PatientIdentityPseudonym = "pseudonym" // Código estable por paciente (HMAC), sin datos personales
+	PatientIdentityNone      = "none"      // Sin identificador de paciente
+)
+
+// Solo el modo "full" incluye la descripción del incidente: es texto libre y suele nombrar al
+// paciente o a su familia, así que en los modos seudonimizado y anónimo sale vacía (igual que el
+// nombre y la foto). La categoría y la severidad bastan para los reportes agregados.
+
+// IncidentExportFilter rango (From inclusivo, To exclusivo) y pacientes visibles (nil = todos)
+type IncidentExportFilter struct {
+	From       time.Time
+	To         time.Time
+	PatientIDs *gorm.DB
+	Identity   string
+}
+
+// IncidentExportRow una fila de la exportación. Los incidentes registrados antes del seguimiento
+// de incidentes (solo Session.HasIncident) salen sin IncidentID, severidad ni estado.
+type IncidentExportRow struct {
+	IncidentID     string     `json:"incident_id"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	SessionID      string     `json:"session_id"`
+	Patient        string     `json:"patient"`
+	PatientName    string     `json:"patient_name,omitempty"`
+	Category       string     `json:"category"`
+	Severity       string     `json:"severity"`
+	Status         string     `json:"status"`
+	Description    string     `json:"description"`
+	HasPhoto       bool       `json:"has_photo"`
+	PhotoKey       string     `json:"photo_key,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ProfessionalID string     `json:"professional_id"`
+	Professional   string     `json:"professional"`
+}
+
+// IncidentExportColumns encabezado del CSV (mismo orden que IncidentExportRow.Values)
+var IncidentExportColumns = []string{
+	"incident_id", "occurred_at", "session_id", "patient", "patient_name", "category", "severity", "status",
+	"description", "has_photo", "photo_key", "resolved_at", "professional_id", "professional",
+}
+
+// Values devuelve la fila como texto para el CSV
+func (r IncidentExportRow) Values() []string {
+	resolvedAt := ""
+	if r.ResolvedAt != nil {
+		resolvedAt = r.ResolvedAt.UTC().Format(time.RFC3339)
+	}
+	hasPhoto := "false"
+	if r.HasPhoto {
+		hasPhoto = "true"
+	}
+	return []string{
+		r.IncidentID, r.OccurredAt.UTC().Format(time.RFC3339), r.SessionID, r.Patient, r.PatientName, r.Category, r.Severity, r.Status,
+		r.Description, hasPhoto, r.PhotoKey, resolvedAt, r.ProfessionalID, r.Professional,
+	}
+}
+
+// IncidentCount conteo por un criterio (categoría, severidad o profesional)
+type IncidentCount struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// IncidentExportSummary totales del periodo
+type IncidentExportSummary struct {
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	Total          int             `json:"total"`
+	ByCategory     []IncidentCount `json:"by_category"`
+	BySeverity     []IncidentCount `json:"by_severity"`
+	ByProfessional []IncidentCount `json:"by_professional"`
+}
+
+type IncidentExportService struct {
+	cfg *config.Config
+}
+
+func NewIncidentExportService(cfg *config.Config) *IncidentExportService {
+	return &IncidentExportService{cfg: cfg}
+}
+
+// Fila cruda de la consulta (incidentes + incidentes antiguos de sesiones)
+type incidentExportRecord struct {
+	IncidentID     *uuid.UUID
+	OccurredAt     time.Time
+	SessionID      *uuid.UUID
+	PatientID      uuid.UUID
+	PersonalInfo   datatypes.JSON
+	Category       string
+	Severity       *string
+	Status         *string
+	Description    string
+	Photo          string
+	ResolvedAt     *time.Time
+	ProfessionalID uuid.UUID
+	Email          string
+	ProfileData    datatypes.JSON
+}
+
+// Each recorre los incidentes del periodo en orden cronológico, sin cargarlos todos en memoria
+func (s *IncidentExportService) Each(filter IncidentExportFilter, fn func(IncidentExportRow) error) error {
+	switch filter.Identity {
+	case PatientIdentityFull, PatientIdentityNone:
+	case PatientIdentityPseudonym:
+		if s.cfg.ExportPseudonymKey == "" {
+			return ErrPseudonymKeyMissing
+		}
+	default:
+		return ErrInvalidPatientIdentity
+	}
+
+	db := database.GetDB()
+	rows, err := db.Raw("SELECT * FROM (?) AS export ORDER BY occurred_at", exportUnion(db, filter)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record incidentExportRecord
+		if err := db.ScanRows(rows, &record); err != nil {
+			return err
+		}
+		if err := fn(s.exportRow(record, filter.Identity)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// exportUnion une los incidentes del periodo con los incidentes antiguos de sesiones
+func exportUnion(db *gorm.DB, filter IncidentExportFilter) *gorm.DB {
+	incidents := db.Table("incidents AS i").
+		Select(`i.id AS incident_id, i.created_at AS occurred_at, i.session_id, i.patient_id, p.personal_info,
+			i.category, i.severity, i.status, i.description, i.photo, i.resolved_at,
+			i.reported_by_id AS professional_id, u.email, u.profile_data`).
+		Joins("JOIN patients p ON p.id = i.patient_id").
+		Joins("JOIN users u ON u.id = i.reported_by_id").
+		Where("i.created_at >= ? AND i.created_at < ?", filter.From, filter.To)
+
+	// Sesiones con incidente registradas antes de que existiera la entidad Incident
+	legacy := db.Table("sessions AS s").
+		Select(`NULL::uuid AS incident_id, s.created_at AS occurred_at, s.id AS session_id, s.patient_id, p.personal_info,
+			?::text AS category, NULL::text AS severity, NULL::text AS status, s.incident_details AS description,
+			COALESCE(s.incident_photo, '') AS photo, NULL::timestamptz AS resolved_at,
+			s.professional_id, u.email, u.profile_data`, domains.IncidentCategoryOther).
+		Joins("JOIN patients p ON p.id = s.patient_id").
+		Joins("JOIN users u ON u.id = s.professional_id").
+		Where("s.has_incident = ? AND s.deleted_at IS NULL", true).
+		Where("NOT EXISTS (SELECT 1 FROM incidents WHERE incidents.session_id = s.id)").
+		Where("s.created_at >= ? AND s.created_at < ?", filter.From, filter.To)
+
+	if filter.PatientIDs != nil {
+		incidents = incidents.Where("i.patient_id IN (?)", filter.PatientIDs)
+		legacy = legacy.Where("s.patient_id IN (?)", filter.PatientIDs)
+	}
+
+	return db.Raw("(?) UNION ALL (?)", incidents, legacy)
+}
+
+// Summary cuenta los incidentes del periodo por categoría, severidad y profesional
+func (s *IncidentExportService) Summary(filter IncidentExportFilter) (IncidentExportSummary, error) {
+	summary := IncidentExportSummary{From: filter.From, To: filter.To}
+	db := database.GetDB()
+
+	// 1. Agrupar en la base (sobre la misma unión que el export), sin traer las filas
+	group := func(column string) (map[string]int, error) {
+		var rows []struct {
+			Key   string
+			Count int
+		}
+		err := db.Raw("SELECT "+column+" AS key, COUNT(*) AS count FROM (?) AS export GROUP BY 1", exportUnion(db, filter)).
+			Scan(&rows).Error
+		counts := make(map[string]int, len(rows))
+		for _, row := range rows {
+			counts[row.Key] = row.Count
+		}
+		return counts, err
+	}
+
+	byCategory, err := group("category")
+	if err != nil {
+		return summary, err
+	}
+	bySeverity, err := group("COALESCE(severity, 'UNSPECIFIED')")
+	if err != nil {
+		return summary, err
+	}
+	byProfessional, err := group("CAST(professional_id AS text)")
+	if err != nil {
+		return summary, err
+	}
+
+	// 2. Nombres de los profesionales del periodo
+	ids := make([]string, 0, len(byProfessional))
+	for id := range byProfessional {
+		ids = append(ids, id)
+	}
+	professionalNames := make(map[string]string, len(ids))
+	if len(ids) > 0 {
+		var professionals []domains.User
+		if err := db.Select("id", "email", "profile_data").Where("id IN ?", ids).Find(&professionals).Error; err != nil {
+			return summary, err
+		}
+		for _, professional := range professionals {
+			professionalNames[professional.ID.String()] = UserDisplayName(professional)
+		}
+	}
+
+	for _, count := range byCategory {
+		summary.Total += count
+	}
+	summary.ByCategory = sortedCounts(byCategory, nil)
+	summary.BySeverity = sortedCounts(bySeverity, nil)
+	summary.ByProfessional = sortedCounts(byProfessional, professionalNames)
+	return summary, nil
+}
+
+func (s *IncidentExportService) exportRow(record incidentExportRecord, identity string) IncidentExportRow {
+	row := IncidentExportRow{
+		OccurredAt:     record.OccurredAt,
+		Category:       record.Category,
+		HasPhoto:       record.Photo != "",
+		ResolvedAt:     record.ResolvedAt,
+		ProfessionalID: record.ProfessionalID.String(),
+		Professional:   UserDisplayName(domains.User{Email: record.Email, ProfileData: record.ProfileData}),
+	}
+	if record.IncidentID != nil {
+		row.IncidentID = record.IncidentID.String()
+	}
+	if record.SessionID != nil {
+		row.SessionID = record.SessionID.String()
+	}
+	if record.Severity != nil {
+		row.Severity = *record.Severity
+	}
+	if record.Status != nil {
+		row.Status = *record.Status
+	}
+
+	// Identificación del paciente según el modo pedido
+	switch identity {
+	case PatientIdentityFull:
+		row.Patient = record.PatientID.String()
+		row.PatientName = PatientDisplayName(domains.Patient{ID: record.PatientID, PersonalInfo: record.PersonalInfo})
+		row.Description = record.Description
+		row.PhotoKey = record.Photo
+	case PatientIdentityPseudonym:
+		row.Patient = s.pseudonym(record.PatientID)
+	}
+	return row
+}
+
+// pseudonym genera un código estable por paciente que no permite recuperar su ID sin la clave
+func (s *IncidentExportService) pseudonym(patientID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.ExportPseudonymKey))
+	mac.Write(patientID[:])
+	return "P-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// sortedCounts ordena de mayor a menor (y por clave para empates)
+func sortedCounts(counts map[string]int, labels map[string]string) []IncidentCount {
+	result := make([]IncidentCount, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, IncidentCount{Key: key, Label: labels[key], Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+func TestIncidentExportRowIdentity(t *testing.T) {
+	exports := NewIncidentExportService(&config.Config{ExportPseudonymKey: "test-key"})
+	record := incidentExportRecord{
+		OccurredAt:     time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		PatientID:      uuid.New(),
+		PersonalInfo:   datatypes.JSON(`{"first_name":"Ana","last_name":"Pérez"}`),
+		Category:       "FALL",
+		Description:    "Ana Pérez se cayó en el baño; avisamos a su hija",
+		Photo:          "incident.jpg",
+		ProfessionalID: uuid.New(),
+		Email:          "pro@test.local",
+	}
+
+	full := exports.exportRow(record, PatientIdentityFull)
+	if full.Patient != record.PatientID.String() || full.PatientName == "" || full.Description != record.Description || full.PhotoKey != record.Photo {
+		t.Errorf("full row = %+v", full)
+	}
+
+	for _, identity := range []string{PatientIdentityPseudonym, PatientIdentityNone} {
+		row := exports.exportRow(record, identity)
+		if row.PatientName != "" || row.Description != "" || row.PhotoKey != "" {
+			t.Errorf("%s row leaks patient data: %+v", identity, row)
+		}
+		if !row.HasPhoto || row.Category != record.Category {
+			t.Errorf("%s row lost non-identifying fields: %+v", identity, row)
+		}
+	}
+
+	if pseudonym := exports.exportRow(record, PatientIdentityPseudonym).Patient; pseudonym == "" || pseudonym == record.PatientID.String() {
+		t.Errorf("pseudonym = %q", pseudonym)
+	}
+}
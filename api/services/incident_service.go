@@ -0,0 +1,334 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errores del flujo de incidentes
+var (
+	ErrIncidentNotFound       = errors.New("incident not found")
+	ErrIncidentResolved       = errors.New("incident is already resolved")
+	ErrInvalidAssignee        = errors.New("assignee has no access to the patient")
+	ErrIncidentActionNotFound = errors.New("incident action not found")
+	ErrIncidentActionDone     = errors.New("incident action is already completed")
+)
+
+// Tamaño del lote del escalamiento
+const incidentEscalationBatch = 100
+
+// Orden de severidad para listar (lo más grave primero)
+const incidentSeverityOrder = "CASE severity WHEN 'CRITICAL' THEN 0 WHEN 'HIGH' THEN 1 WHEN 'MEDIUM' THEN 2 ELSE 3 END"
+
+// IncidentFilter filtros del listado. PatientIDs es una subquery (pacientes visibles); nil = sin restricción.
+type IncidentFilter struct {
+	PatientID  *uuid.UUID
+	PatientIDs *gorm.DB
+	Statuses   []domains.IncidentStatus
+	Severity   string
+	AssigneeID *uuid.UUID
+}
+
+type IncidentService struct {
+	cfg *config.Config
+}
+
+func NewIncidentService(cfg *config.Config) *IncidentService {
+	return &IncidentService{cfg: cfg}
+}
+
+// Create registra el incidente con valores por defecto (OPEN, OTHER, MEDIUM) y su plazo según severidad
+func (s *IncidentService) Create(tx *gorm.DB, incident *domains.Incident) error {
+	if incident.Category == "" {
+		incident.Category = domains.IncidentCategoryOther
+	}
+	if incident.Severity == "" {
+		incident.Severity = domains.SeverityMedium
+	}
+	incident.Status = domains.IncidentOpen
+	incident.DueAt = time.Now().Add(s.deadline(incident.Severity))
+
+	return tx.Omit("ReportedBy", "Assignee", "Actions").Create(incident).Error
+}
+
+// CreateFromSession promueve el incidente reportado en una sesión (si aún no existe)
+func (s *IncidentService) CreateFromSession(tx *gorm.DB, session domains.Session, category, severity string) (*domains.Incident, error) {
+	var count int64
+	if err := tx.Model(&domains.Incident{}).Where("session_id = ?", session.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	sessionID := session.ID
+	incident := &domains.Incident{
+		PatientID:    session.PatientID,
+		SessionID:    &sessionID,
+		ReportedByID: session.ProfessionalID,
+		Category:     category,
+		Severity:     domains.IncidentSeverity(severity),
+		Description:  session.IncidentDetails,
+		Photo:        session.IncidentPhoto, // El archivo sigue asociado a la sesión
+	}
+	return incident, s.Create(tx, incident)
+}
+
+// Get carga el incidente con su reportante, responsable y acciones
+func (s *IncidentService) Get(id string) (domains.Incident, error) {
+	var incident domains.Incident
+	err := database.GetDB().
+		Preload("ReportedBy").
+		Preload("Assignee").
+		Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Actions.Author").
+		First(&incident, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return incident, ErrIncidentNotFound
+	}
+	return incident, err
+}
+
+// List devuelve incidentes (más graves y más atrasados primero)
+func (s *IncidentService) List(filter IncidentFilter, page, limit int) ([]domains.Incident, int64, error) {
+	query := database.GetDB().Model(&domains.Incident{})
+
+	if filter.PatientID != nil {
+		query = query.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.PatientIDs != nil {
+		query = query.Where("patient_id IN (?)", filter.PatientIDs)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.AssigneeID != nil {
+		query = query.Where("assignee_id = ?", *filter.AssigneeID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var incidents []domains.Incident
+	err := query.Preload("ReportedBy").Preload("Assignee").
+		Order(incidentSeverityOrder).Order("due_at ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&incidents).Error
+	return incidents, total, err
+}
+
+// Update aplica clasificación, estado y responsable sobre la fila bloqueada y retorna su estado previo.
+// Solo escribe las columnas que cambia el input: un resolve o un escalamiento guardado entretanto no se pisa.
+// Reabrir un incidente resuelto reinicia su plazo.
+func (s *IncidentService) Update(tx *gorm.DB, incident *domains.Incident, input domains.UpdateIncidentInput) (domains.Incident, error) {
+	now := time.Now()
+
+	// 1. Releer la fila bloqueada (lo leído antes de la transacción puede estar desactualizado)
+	var locked domains.Incident
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", incident.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return locked, ErrIncidentNotFound
+		}
+		return locked, err
+	}
+	locked.ReportedBy, locked.Actions = incident.ReportedBy, incident.Actions
+	if incident.Assignee != nil && locked.AssigneeID != nil && incident.Assignee.ID == *locked.AssigneeID {
+		locked.Assignee = incident.Assignee
+	}
+	before := locked
+	*incident = locked
+
+	// 2. Aplicar el input anotando las columnas que cambian
+	changes := map[string]interface{}{}
+	if input.Category != nil {
+		incident.Category = *input.Category
+		changes["category"] = incident.Category
+	}
+	if input.Severity != nil && domains.IncidentSeverity(*input.Severity) != incident.Severity {
+		// El plazo se recalcula desde la creación con la nueva severidad
+		incident.Severity = domains.IncidentSeverity(*input.Severity)
+		changes["severity"] = incident.Severity
+		if incident.Status != domains.IncidentResolved {
+			incident.DueAt = incident.CreatedAt.Add(s.deadline(incident.Severity))
+			changes["due_at"] = incident.DueAt
+		}
+	}
+	if input.AssigneeID != nil {
+		if err := s.Assign(tx, incident, *input.AssigneeID); err != nil {
+			return before, err
+		}
+		changes["assignee_id"] = incident.AssigneeID
+	}
+	if input.Status != nil {
+		status := domains.IncidentStatus(*input.Status)
+		if incident.Status == domains.IncidentResolved && status != domains.IncidentResolved {
+			incident.ResolvedAt = nil
+			incident.ResolvedByID = nil
+			incident.DueAt = now.Add(s.deadline(incident.Severity))
+			incident.EscalationLevel = 0
+			incident.LastEscalatedAt = nil
+			changes["resolved_at"] = nil
+			changes["resolved_by_id"] = nil
+			changes["due_at"] = incident.DueAt
+			changes["escalation_level"] = 0
+			changes["last_escalated_at"] = nil
+		}
+		incident.Status = status
+		changes["status"] = status
+	}
+
+	if len(changes) == 0 {
+		return before, nil
+	}
+	return before, tx.Model(&domains.Incident{}).Where("id = ?", incident.ID).Updates(changes).Error
+}
+
+// Assign valida que el responsable pueda ver al paciente ("" quita la asignación).
+// Solo modifica el struct: lo persiste Create o Update.
+func (s *IncidentService) Assign(tx *gorm.DB, incident *domains.Incident, rawID string) error {
+	if rawID == "" {
+		incident.AssigneeID = nil
+		incident.Assignee = nil
+		return nil
+	}
+
+	assigneeID, err := uuid.Parse(rawID)
+	if err != nil {
+		return ErrInvalidAssignee
+	}
+	var assignee domains.User
+	if err := tx.First(&assignee, "id = ?", assigneeID).Error; err != nil {
+		return ErrInvalidAssignee
+	}
+	if err := NewAccessService().CheckPatientAccess(assignee, incident.PatientID.String()); err != nil {
+		return ErrInvalidAssignee
+	}
+
+	incident.AssigneeID = &assignee.ID
+	incident.Assignee = &assignee
+	return nil
+}
+
+// AddAction agrega una acción de seguimiento
+func (s *IncidentService) AddAction(tx *gorm.DB, incident domains.Incident, author domains.User, description string, dueAt *time.Time) (domains.IncidentAction, error) {
+	if incident.Status == domains.IncidentResolved {
+		return domains.IncidentAction{}, ErrIncidentResolved
+	}
+
+	action := domains.IncidentAction{
+		IncidentID:  incident.ID,
+		AuthorID:    author.ID,
+		Description: description,
+		DueAt:       dueAt,
+	}
+	err := tx.Create(&action).Error
+	return action, err
+}
+
+// CompleteAction marca una acción como realizada (una sola vez)
+func (s *IncidentService) CompleteAction(tx *gorm.DB, incident domains.Incident, actionID string) (domains.IncidentAction, error) {
+	var action domains.IncidentAction
+	if err := tx.First(&action, "id = ? AND incident_id = ?", actionID, incident.ID).Error; err != nil {
+		return action, ErrIncidentActionNotFound
+	}
+
+	now := time.Now()
+	result := tx.Model(&domains.IncidentAction{}).
+		Where("id = ? AND completed_at IS NULL", action.ID).
+		Update("completed_at", now)
+	if result.Error != nil {
+		return action, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return action, ErrIncidentActionDone
+	}
+
+	action.CompletedAt = &now
+	return action, nil
+}
+
+// Resolve cierra el incidente con sus notas de resolución
+func (s *IncidentService) Resolve(tx *gorm.DB, incident *domains.Incident, resolver domains.User, notes string) error {
+	now := time.Now()
+
+	result := tx.Model(&domains.Incident{}).
+		Where("id = ? AND status <> ?", incident.ID, domains.IncidentResolved).
+		Updates(map[string]interface{}{
+			"status":           domains.IncidentResolved,
+			"resolution_notes": notes,
+			"resolved_at":      now,
+			"resolved_by_id":   resolver.ID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIncidentResolved
+	}
+
+	incident.Status = domains.IncidentResolved
+	incident.ResolutionNotes = notes
+	incident.ResolvedAt = &now
+	incident.ResolvedByID = &resolver.ID
+	return nil
+}
+
+// EscalateOverdue es llamado por el Scheduler: escala los incidentes abiertos con plazo vencido.
+// Cada incidente se vuelve a escalar cada IncidentEscalationRepeat mientras siga sin resolver.
+func (s *IncidentService) EscalateOverdue(now time.Time) {
+	db := database.GetDB()
+	notifier := NewNotificationService(s.cfg)
+	repeatBefore := now.Add(-s.cfg.IncidentEscalationRepeat)
+
+	var overdue []domains.Incident
+	err := db.Where("status <> ? AND due_at < ?", domains.IncidentResolved, now).
+		Where("last_escalated_at IS NULL OR last_escalated_at < ?", repeatBefore).
+		Order("due_at ASC").Limit(incidentEscalationBatch).
+		Find(&overdue).Error
+	if err != nil {
+		slog.Error("Failed to load overdue incidents", "error", err)
+		return
+	}
+
+	for _, incident := range overdue {
+		// Reclamar el escalamiento: si otra instancia ya lo hizo (o se resolvió entretanto), no se repite el aviso
+		result := db.Model(&domains.Incident{}).
+			Where("id = ? AND escalation_level = ? AND status <> ?", incident.ID, incident.EscalationLevel, domains.IncidentResolved).
+			Updates(map[string]interface{}{
+				"escalation_level":  incident.EscalationLevel + 1,
+				"last_escalated_at": now,
+			})
+		if result.Error != nil {
+			slog.Error("Failed to escalate incident", "incidentID", incident.ID, "error", result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		incident.EscalationLevel++
+		notifier.NotifyIncidentEscalation(incident, now)
+		slog.Warn("Incident escalated", "incidentID", incident.ID, "level", incident.EscalationLevel)
+	}
+}
+
+func (s *IncidentService) deadline(severity domains.IncidentSeverity) time.Duration {
+	if deadline, ok := s.cfg.IncidentDeadlines[string(severity)]; ok {
+		return deadline
+	}
+	return s.cfg.IncidentDeadlines[string(domains.SeverityMedium)]
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+
+	"github.com/google/uuid"
+)
+
+// MailMessage es un correo listo para enviar, independiente del transporte
+type MailMessage struct {
+	From     string
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string // Opcional: si viene, se envía multipart/alternative (texto + HTML)
+}
+
+// Mailer abstrae el transporte de correo (SMTP real, archivo local, memoria para tests)
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+// NewMailer elige la implementación según cfg.MailDriver
+func NewMailer(cfg *config.Config) Mailer {
+	switch cfg.MailDriver {
+	case "log":
+		return &LogMailer{}
+	case "file":
+		return &FileMailer{Dir: cfg.MailFileDir}
+	case "memory":
+		return NewMemoryMailer()
+	case "smtp", "":
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPEmail,
+			Password: cfg.SMTPPassword,
+			TLSMode:  cfg.SMTPTLSMode,
+		}
+	default:
+		slog.Warn("Unknown MAIL_DRIVER, falling back to log mailer", "driver", cfg.MailDriver)
+		return &LogMailer{}
+	}
+}
+
+// --- SMTP (STARTTLS o TLS implícito) ---
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	TLSMode  string // "starttls", "implicit" o "none"
+}
+
+const smtpDialTimeout = 15 * time.Second
+
+func (m *SMTPMailer) Send(ctx context.Context, msg MailMessage) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	tlsConfig := &tls.Config{ServerName: m.Host}
+
+	// 1. Conexión (TLS desde el primer byte en modo implícito, ej: puerto 465)
+	var conn net.Conn
+	if m.TLSMode == "implicit" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	// 2. STARTTLS (ej: Gmail puerto 587)
+	if m.TLSMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	// 3. Autenticación
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	// 4. Envío
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(buildMIMEMessage(msg)); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data close: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// --- LOG (Desarrollo: solo imprime) ---
+
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, msg MailMessage) error {
+	slog.Info("📧 [LOG MAILER] Email not sent (development)", "to", msg.To, "subject", msg.Subject, "body", msg.TextBody)
+	return nil
+}
+
+// --- FILE (Desarrollo: deja un .eml por correo, se abre con cualquier cliente de correo) ---
+
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg MailMessage) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	path := filepath.Join(m.Dir, fileName)
+
+	if err := os.WriteFile(path, buildMIMEMessage(msg), 0o644); err != nil {
+		return fmt.Errorf("write mail file: %w", err)
+	}
+
+	slog.Info("📧 [FILE MAILER] Email written to disk", "to", msg.To, "path", path)
+	return nil
+}
+
+// --- MEMORY (Tests: registra los correos para inspeccionarlos) ---
+
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []MailMessage
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages devuelve una copia de los correos registrados
+func (m *MemoryMailer) Messages() []MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MailMessage(nil), m.messages...)
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// buildMIMEMessage arma el correo RFC 5322 (asunto codificado para tildes/emojis).
+// Con HTMLBody genera multipart/alternative para que cada cliente elija la versión que soporta.
+func buildMIMEMessage(msg MailMessage) []byte {
+	var buf bytes.Buffer
+
+	headers := []string{
+		"From: " + msg.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + uuid.New().String() + "@bitacora-medica>",
+		"MIME-Version: 1.0",
+	}
+
+	if msg.HTMLBody == "" {
+		headers = append(headers,
+			"Content-Type: text/plain; charset=\"UTF-8\"",
+			"Content-Transfer-Encoding: quoted-printable",
+		)
+		buf.WriteString(strings.Join(headers, "\r\n"))
+		buf.WriteString("\r\n\r\n")
+		writeQuotedPrintable(&buf, msg.TextBody)
+		return buf.Bytes()
+	}
+
+	writer := multipart.NewWriter(&buf)
+	headers = append(headers, "Content-Type: multipart/alternative; boundary=\""+writer.Boundary()+"\"")
+	buf.WriteString(strings.Join(headers, "\r\n"))
+	buf.WriteString("\r\n\r\n")
+
+	// El orden importa: la última parte es la preferida (HTML)
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=\"UTF-8\"", msg.TextBody},
+		{"text/html; charset=\"UTF-8\"", msg.HTMLBody},
+	} {
+		partWriter, _ := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(partWriter, part.body)
+	}
+	writer.Close()
+
+	return buf.Bytes()
+}
+
+func writeQuotedPrintable(w io.Writer, body string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(body))
+	qp.Close()
+}
@@ -0,0 +1,583 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidReportRange = errors.New("start_date and end_date must be YYYY-MM-DD and start_date not after end_date")
+
+// Tendencia de un signo vital en el periodo (último valor vs primero)
+const (
+	VitalTrendUp     = "UP"
+	VitalTrendDown   = "DOWN"
+	VitalTrendStable = "STABLE"
+)
+
+// Variación relativa bajo la cual un signo vital se considera estable
+const vitalTrendTolerance = 0.05
+
+// Logo por defecto (se reemplaza con CLINIC_LOGO_PATH)
+//
+//go:embed assets/clinic_logo.png
+var defaultClinicLogo []byte
+
+// MasterReport es el resumen global del paciente en un periodo
+type MasterReport struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	DateRange   string              `json:"date_range"`
+	Patient     MasterReportPatient `json:"patient"`
+
+	// Métricas Cuantitativas (Calculadas desde Sesiones)
+	TotalSessions  int64 `json:"total_sessions"`
+	TotalIncidents int64 `json:"total_incidents"`
+
+	// Resumen por Área/Profesional (Agregado desde Reportes)
+	ProfessionalSummaries []ProfessionalSummary `json:"professional_summaries"`
+
+	// Atención entregada: sesiones en orden cronológico, actividad por profesional y evolución de signos vitales
+	Timeline      []MasterReportSession  `json:"timeline"`
+	Professionals []ProfessionalActivity `json:"professionals"`
+	VitalsTrends  []VitalTrend           `json:"vitals_trends"`
+}
+
+// MasterReportPatient encabezado del paciente (desde PersonalInfo)
+type MasterReportPatient struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	RUT       string    `json:"rut,omitempty"`
+	BirthDate string    `json:"birth_date,omitempty"`
+	Diagnosis string    `json:"diagnosis,omitempty"`
+}
+
+// MasterReportSession una sesión del periodo
+type MasterReportSession struct {
+	SessionID          uuid.UUID `json:"session_id"`
+	Date               time.Time `json:"date"`
+	ProfessionalID     uuid.UUID `json:"professional_id"`
+	ProfessionalName   string    `json:"professional_name"`
+	Specialty          string    `json:"specialty,omitempty"`
+	Achievements       string    `json:"achievements"`
+	PatientPerformance string    `json:"patient_performance"`
+	HasIncident        bool      `json:"has_incident"`
+	IncidentCategory   string    `json:"incident_category,omitempty"`
+	IncidentSeverity   string    `json:"incident_severity,omitempty"`
+	Signed             bool      `json:"signed"`
+}
+
+// ProfessionalActivity sesiones de un profesional en el periodo y su frecuencia de atención
+type ProfessionalActivity struct {
+	ProfessionalID   uuid.UUID `json:"professional_id"`
+	ProfessionalName string    `json:"professional_name"`
+	Specialty        string    `json:"specialty,omitempty"`
+	Sessions         int       `json:"sessions"`
+	Incidents        int       `json:"incidents"`
+	FirstSessionAt   time.Time `json:"first_session_at"`
+	LastSessionAt    time.Time `json:"last_session_at"`
+	SessionsPerWeek  float64   `json:"sessions_per_week"`
+}
+
+// VitalTrend evolución de un signo vital en el periodo
+type VitalTrend struct {
+	Metric string     `json:"metric"`
+	Unit   string     `json:"unit"`
+	Stats  VitalStats `json:"stats"`
+	First  float64    `json:"first"`
+	Last   float64    `json:"last"`
+	Change float64    `json:"change"`
+	Trend  string     `json:"trend"`
+}
+
+type ProfessionalSummary struct {
+	ProfessionalName string `json:"professional_name"`
+	Role             string `json:"role"`      // Rol del sistema (ej: PROFESSIONAL)
+	Specialty        string `json:"specialty"` // Ej: Fonoaudiólogo (desde ProfileData)
+	Summary          string `json:"summary"`
+	Objectives       string `json:"objectives"`
+}
+
+type MasterReportService struct {
+	cfg *config.Config
+}
+
+func NewMasterReportService(cfg *config.Config) *MasterReportService {
+	return &MasterReportService{cfg: cfg}
+}
+
+// Build arma el reporte maestro. El acceso al paciente lo valida quien llama.
+// El periodo incluye completos los días start_date y end_date (zona horaria de la clínica).
+func (s *MasterReportService) Build(req domains.MasterReportRequest) (MasterReport, error) {
+	db := database.GetDB()
+	report := MasterReport{
+		GeneratedAt: time.Now(),
+		DateRange:   req.StartDate + " to " + req.EndDate,
+	}
+
+	from, to, err := s.reportRange(req)
+	if err != nil {
+		return report, err
+	}
+
+	// 1. Encabezado del paciente
+	var patient domains.Patient
+	if err := db.First(&patient, "id = ?", req.PatientID).Error; err != nil {
+		return report, err
+	}
+	report.Patient = masterReportPatient(patient)
+
+	// 2. Obtener Reportes Individuales aprobados en el rango (borradores y pendientes no se publican)
+	var reports []domains.ProfessionalReport
+	if err := db.Preload("Author").
+		Where("patient_id = ? AND date_range_start >= ? AND date_range_end <= ?",
+			req.PatientID, req.StartDate, req.EndDate).
+		Where("status = ?", domains.ReportApproved).
+		Order("created_at ASC").
+		Find(&reports).Error; err != nil {
+		return report, err
+	}
+
+	// 3. Sesiones del periodo (Hard Data): línea de tiempo, totales y actividad por profesional
+	if report.Timeline, err = s.timeline(patient.ID, from, to); err != nil {
+		return report, err
+	}
+	report.TotalSessions = int64(len(report.Timeline))
+	for _, session := range report.Timeline {
+		if session.HasIncident {
+			report.TotalIncidents++
+		}
+	}
+	report.Professionals = professionalActivity(report.Timeline, from, to)
+
+	// 4. Evolución de signos vitales
+	if report.VitalsTrends, err = s.vitalsTrends(patient.ID, from, to); err != nil {
+		return report, err
+	}
+
+	// 5. Consolidar Información de cada experto
+	report.ProfessionalSummaries = make([]ProfessionalSummary, 0, len(reports))
+	for _, r := range reports {
+		report.ProfessionalSummaries = append(report.ProfessionalSummaries, ProfessionalSummary{
+			ProfessionalName: UserDisplayName(r.Author),
+			Role:             string(r.Author.Role),
+			Specialty:        userSpecialty(r.Author),
+			Summary:          r.Content,
+			Objectives:       r.ObjectivesAchieved,
+		})
+	}
+
+	return report, nil
+}
+
+// reportRange convierte las fechas del pedido en [from, to)
+func (s *MasterReportService) reportRange(req domains.MasterReportRequest) (time.Time, time.Time, error) {
+	location, err := time.LoadLocation(s.cfg.DigestTimezone)
+	if err != nil {
+		location = time.UTC
+	}
+	from, err := time.ParseInLocation("2006-01-02", req.StartDate, location)
+	if err != nil {
+		return from, from, ErrInvalidReportRange
+	}
+	end, err := time.ParseInLocation("2006-01-02", req.EndDate, location)
+	if err != nil || end.Before(from) {
+		return from, from, ErrInvalidReportRange
+	}
+	return from, end.AddDate(0, 0, 1), nil
+}
+
+// timeline sesiones vigentes del periodo, en orden cronológico, con la clasificación de su incidente
+func (s *MasterReportService) timeline(patientID uuid.UUID, from, to time.Time) ([]MasterReportSession, error) {
+	db := database.GetDB()
+
+	var sessions []domains.Session
+	if err := db.Preload("Creator").
+		Where("patient_id = ? AND created_at >= ? AND created_at < ?", patientID, from, to).
+		Order("created_at ASC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	sessionIDs := make([]uuid.UUID, 0, len(sessions))
+	for _, session := range sessions {
+		if session.HasIncident {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+	incidents := map[uuid.UUID]domains.Incident{}
+	if len(sessionIDs) > 0 {
+		var rows []domains.Incident
+		if err := db.Where("session_id IN ?", sessionIDs).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, incident := range rows {
+			incidents[*incident.SessionID] = incident
+		}
+	}
+
+	timeline := make([]MasterReportSession, 0, len(sessions))
+	for _, session := range sessions {
+		entry := MasterReportSession{
+			SessionID:          session.ID,
+			Date:               session.CreatedAt.In(from.Location()),
+			ProfessionalID:     session.ProfessionalID,
+			ProfessionalName:   UserDisplayName(session.Creator),
+			Specialty:          userSpecialty(session.Creator),
+			Achievements:       session.Achievements,
+			PatientPerformance: session.PatientPerformance,
+			HasIncident:        session.HasIncident,
+			Signed:             session.IsSigned(),
+		}
+		// Las sesiones anteriores al seguimiento de incidentes no tienen clasificación
+		if incident, ok := incidents[session.ID]; ok {
+			entry.IncidentCategory = incident.Category
+			entry.IncidentSeverity = string(incident.Severity)
+		}
+		timeline = append(timeline, entry)
+	}
+	return timeline, nil
+}
+
+// professionalActivity agrupa la línea de tiempo por profesional (más sesiones primero).
+// La frecuencia es sesiones por semana sobre el periodo completo del reporte.
+func professionalActivity(timeline []MasterReportSession, from, to time.Time) []ProfessionalActivity {
+	weeks := math.Max(to.Sub(from).Hours()/(24*7), 1)
+
+	activity := []ProfessionalActivity{}
+	index := map[uuid.UUID]int{}
+	for _, session := range timeline {
+		i, ok := index[session.ProfessionalID]
+		if !ok {
+			i = len(activity)
+			index[session.ProfessionalID] = i
+			activity = append(activity, ProfessionalActivity{
+				ProfessionalID:   session.ProfessionalID,
+				ProfessionalName: session.ProfessionalName,
+				Specialty:        session.Specialty,
+				FirstSessionAt:   session.Date,
+			})
+		}
+		activity[i].Sessions++
+		activity[i].LastSessionAt = session.Date
+		if session.HasIncident {
+			activity[i].Incidents++
+		}
+	}
+
+	for i := range activity {
+		activity[i].SessionsPerWeek = math.Round(float64(activity[i].Sessions)/weeks*100) / 100
+	}
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].Sessions > activity[j].Sessions
+	})
+	return activity
+}
+
+// vitalsTrends resumen de cada signo vital con mediciones en el periodo
+func (s *MasterReportService) vitalsTrends(patientID uuid.UUID, from, to time.Time) ([]VitalTrend, error) {
+	series, err := NewVitalsSeriesService().Series(VitalsQuery{
+		PatientID: patientID,
+		Metrics:   domains.VitalNames,
+		From:      from,
+		To:        to,
+		Bucket:    VitalsBucketNone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trends := []VitalTrend{}
+	for _, metric := range domains.VitalNames {
+		serie := series[metric]
+		if serie == nil || len(serie.Points) == 0 {
+			continue
+		}
+		first := serie.Points[0].Value
+		last := serie.Points[len(serie.Points)-1].Value
+		trend := VitalTrend{
+			Metric: metric,
+			Unit:   serie.Unit,
+			Stats:  serie.Summary,
+			First:  first,
+			Last:   last,
+			Change: math.Round((last-first)*100) / 100,
+			Trend:  VitalTrendStable,
+		}
+		if math.Abs(last-first) > math.Abs(first)*vitalTrendTolerance {
+			trend.Trend = VitalTrendUp
+			if last < first {
+				trend.Trend = VitalTrendDown
+			}
+		}
+		trends = append(trends, trend)
+	}
+	return trends, nil
+}
+
+// RenderPDF escribe el reporte como PDF (A4, logo de la clínica y "Página X de Y" al pie)
+func (s *MasterReportService) RenderPDF(report MasterReport, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+
+	// Las fuentes base son cp1252: se traducen tildes y ñ desde UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	logo := s.registerLogo(pdf)
+
+	// 1. Encabezado y pie de cada página
+	pdf.SetHeaderFunc(func() {
+		if logo != "" {
+			pdf.ImageOptions(logo, 18, 10, 14, 0, false, fpdf.ImageOptions{ReadDpi: true}, 0, "")
+		}
+		pdf.SetXY(35, 12)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 6, tr(s.cfg.ClinicName), "", 2, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(0, 5, tr("Reporte maestro del paciente"), "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Line(18, 27, 192, 27)
+		pdf.SetY(32)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-14)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(0, 5, tr("Generado el "+report.GeneratedAt.Format("02-01-2006 15:04")), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("Página %d de {nb}", pdf.PageNo())), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	pdf.AddPage()
+
+	// 2. Datos del paciente y periodo
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.MultiCell(0, 8, tr(report.Patient.Name), "", "L", false)
+	pdf.Ln(1)
+	field := func(label, value string) {
+		if value == "" {
+			return
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(40, 6, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 6, tr(value), "", "L", false)
+	}
+	field("RUT:", report.Patient.RUT)
+	field("Fecha de nacimiento:", report.Patient.BirthDate)
+	field("Diagnóstico:", report.Patient.Diagnosis)
+	field("Periodo:", strings.Replace(report.DateRange, " to ", " al ", 1))
+	pdf.Ln(4)
+
+	// 3. Totales del periodo
+	pdf.SetFillColor(234, 243, 245)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(87, 8, tr("Sesiones registradas"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(87, 8, tr("Incidentes"), "1", 1, "C", true, 0, "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(87, 10, fmt.Sprint(report.TotalSessions), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(87, 10, fmt.Sprint(report.TotalIncidents), "1", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	// Tabla simple: encabezado sombreado y una fila por elemento
+	table := func(title string, widths []float64, headers []string, rows [][]string) {
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 8, tr(title), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 9)
+		for i, header := range headers {
+			pdf.CellFormat(widths[i], 7, tr(header), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, row := range rows {
+			for i, value := range row {
+				align := "C"
+				if i == 0 {
+					align = "L"
+				}
+				pdf.CellFormat(widths[i], 6, tr(value), "1", 0, align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(6)
+	}
+
+	// 4. Actividad por profesional
+	if len(report.Professionals) > 0 {
+		rows := make([][]string, 0, len(report.Professionals))
+		for _, activity := range report.Professionals {
+			name := activity.ProfessionalName
+			if activity.Specialty != "" {
+				name += " (" + activity.Specialty + ")"
+			}
+			rows = append(rows, []string{
+				name, fmt.Sprint(activity.Sessions), fmt.Sprintf("%.2f", activity.SessionsPerWeek),
+				activity.LastSessionAt.Format("02-01-2006"), fmt.Sprint(activity.Incidents),
+			})
+		}
+		table("Atención por profesional", []float64{74, 22, 28, 28, 22},
+			[]string{"Profesional", "Sesiones", "Por semana", "Última", "Incidentes"}, rows)
+	}
+
+	// 5. Evolución de signos vitales
+	if len(report.VitalsTrends) > 0 {
+		rows := make([][]string, 0, len(report.VitalsTrends))
+		for _, trend := range report.VitalsTrends {
+			rows = append(rows, []string{
+				vitalLabels[trend.Metric] + " (" + trend.Unit + ")",
+				formatVital(trend.First), formatVital(trend.Last),
+				formatVital(trend.Stats.Min) + " - " + formatVital(trend.Stats.Max),
+				fmt.Sprint(trend.Stats.Count), trendLabels[trend.Trend],
+			})
+		}
+		table("Evolución de signos vitales", []float64{52, 20, 20, 34, 24, 24},
+			[]string{"Signo vital", "Inicial", "Final", "Rango", "Mediciones", "Tendencia"}, rows)
+	}
+
+	// 6. Un apartado por profesional
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(0, 8, tr("Resumen por profesional"), "", 1, "L", false, 0, "")
+	if len(report.ProfessionalSummaries) == 0 {
+		pdf.SetFont("Helvetica", "I", 10)
+		pdf.MultiCell(0, 6, tr("No hay reportes individuales en este periodo."), "", "L", false)
+	}
+	for _, summary := range report.ProfessionalSummaries {
+		title := summary.ProfessionalName
+		if summary.Specialty != "" {
+			title += " - " + summary.Specialty
+		}
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 7, tr(title), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, tr(summary.Summary), "", "L", false)
+		if summary.Objectives != "" {
+			pdf.Ln(1)
+			pdf.SetFont("Helvetica", "B", 10)
+			pdf.CellFormat(0, 6, tr("Objetivos logrados"), "", 1, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 10)
+			pdf.MultiCell(0, 5, tr(summary.Objectives), "", "L", false)
+		}
+		pdf.Ln(2)
+	}
+
+	// 7. Línea de tiempo de sesiones
+	if len(report.Timeline) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 8, tr("Línea de tiempo de sesiones"), "", 1, "L", false, 0, "")
+	}
+	for _, session := range report.Timeline {
+		title := session.Date.Format("02-01-2006 15:04") + "  " + session.ProfessionalName
+		if session.Specialty != "" {
+			title += " (" + session.Specialty + ")"
+		}
+		pdf.Ln(1)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(0, 6, tr(title), "B", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		if session.Achievements != "" {
+			pdf.MultiCell(0, 5, tr("Logros: "+session.Achievements), "", "L", false)
+		}
+		if session.PatientPerformance != "" {
+			pdf.MultiCell(0, 5, tr("Desempeño: "+session.PatientPerformance), "", "L", false)
+		}
+		if session.HasIncident {
+			incident := "Incidente registrado"
+			if session.IncidentCategory != "" {
+				incident += " (" + session.IncidentCategory + ", " + session.IncidentSeverity + ")"
+			}
+			pdf.SetTextColor(170, 30, 30)
+			pdf.MultiCell(0, 5, tr(incident), "", "L", false)
+			pdf.SetTextColor(0, 0, 0)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// registerLogo carga el logo configurado (o el incluido). Devuelve "" si no se pudo cargar.
+func (s *MasterReportService) registerLogo(pdf *fpdf.Fpdf) string {
+	data, imageType := defaultClinicLogo, "PNG"
+	if s.cfg.ClinicLogoPath != "" {
+		custom, err := os.ReadFile(s.cfg.ClinicLogoPath)
+		if err != nil {
+			slog.Warn("Failed to read clinic logo, using default", "path", s.cfg.ClinicLogoPath, "error", err)
+		} else {
+			data = custom
+			imageType = strings.TrimPrefix(strings.ToUpper(filepath.Ext(s.cfg.ClinicLogoPath)), ".")
+		}
+	}
+
+	pdf.RegisterImageOptionsReader("clinic-logo", fpdf.ImageOptions{ImageType: imageType, ReadDpi: true}, bytes.NewReader(data))
+	if pdf.Ok() {
+		return "clinic-logo"
+	}
+	slog.Warn("Invalid clinic logo, rendering without it", "error", pdf.Error())
+	pdf.ClearError()
+	return ""
+}
+
+// Nombres de los signos vitales y tendencias en el PDF
+var vitalLabels = map[string]string{
+	domains.VitalHeartRate:       "Frecuencia cardíaca",
+	domains.VitalSystolicBP:      "Presión sistólica",
+	domains.VitalDiastolicBP:     "Presión diastólica",
+	domains.VitalSpO2:            "Saturación O2",
+	domains.VitalTemperature:     "Temperatura",
+	domains.VitalRespiratoryRate: "Frecuencia respiratoria",
+	domains.VitalPainScale:       "Escala de dolor",
+	domains.VitalWeight:          "Peso",
+}
+
+var trendLabels = map[string]string{
+	VitalTrendUp:     "Al alza",
+	VitalTrendDown:   "A la baja",
+	VitalTrendStable: "Estable",
+}
+
+func formatVital(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.1f", value), "0"), ".")
+}
+
+func masterReportPatient(patient domains.Patient) MasterReportPatient {
+	header := MasterReportPatient{ID: patient.ID, Name: PatientDisplayName(patient)}
+
+	var info map[string]interface{}
+	if len(patient.PersonalInfo) > 0 && json.Unmarshal(patient.PersonalInfo, &info) == nil {
+		header.RUT, _ = info["rut"].(string)
+		header.BirthDate, _ = info["birth_date"].(string)
+		header.Diagnosis, _ = info["diagnosis"].(string)
+	}
+	return header
+}
+
+// userSpecialty lee "specialty" desde ProfileData
+func userSpecialty(user domains.User) string {
+	var profile map[string]interface{}
+	if len(user.ProfileData) > 0 && json.Unmarshal(user.ProfileData, &profile) == nil {
+		if specialty, ok := profile["specialty"].(string); ok {
+			return strings.TrimSpace(specialty)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log/slog"
+	"sync"
+
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+)
+
+// NotificationBroker distribuye las notificaciones recién creadas a los clientes conectados (SSE).
+// La implementación actual es en memoria (una sola instancia del backend). Para varias instancias
+// basta con otra implementación que publique vía Postgres NOTIFY y escuche con LISTEN,
+// reenviando a los suscriptores locales; los handlers no cambian.
+type NotificationBroker interface {
+	// Publish entrega la notificación a todas las conexiones abiertas de notif.UserID
+	Publish(notif domains.Notification)
+	// Subscribe abre un canal para el usuario. La función retornada cierra la suscripción.
+	Subscribe(userID uuid.UUID) (<-chan domains.Notification, func())
+}
+
+// Capacidad del buffer por conexión. Si un cliente lento lo llena, se descartan eventos
+// (la notificación sigue persistida en BD y aparece en la bandeja).
+const subscriberBufferSize = 16
+
+type InMemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan domains.Notification]struct{}
+}
+
+func NewInMemoryBroker() *InMemoryBroker {
+	return &InMemoryBroker{
+		subscribers: make(map[uuid.UUID]map[chan domains.Notification]struct{}),
+	}
+}
+
+func (b *InMemoryBroker) Publish(notif domains.Notification) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[notif.UserID] {
+		select {
+		case ch <- notif:
+		default:
+			slog.Warn("Notification stream buffer full, dropping event", "userID", notif.UserID)
+		}
+	}
+}
+
+func (b *InMemoryBroker) Subscribe(userID uuid.UUID) (<-chan domains.Notification, func()) {
+	ch := make(chan domains.Notification, subscriberBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan domains.Notification]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Broker compartido por todo el proceso (igual que database.DB)
+var notificationBroker NotificationBroker = NewInMemoryBroker()
+
+func GetNotificationBroker() NotificationBroker {
+	return notificationBroker
+}
+
+// SetNotificationBroker permite reemplazar la implementación (ej: Postgres LISTEN/NOTIFY)
+func SetNotificationBroker(b NotificationBroker) {
+	notificationBroker = b
+}
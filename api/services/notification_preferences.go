@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationEventTypes lista los eventos configurables por el usuario
+var NotificationEventTypes = []string{
+	domains.NotifNewUser,
+	domains.NotifAccountStatus,
+	domains.NotifIncidentAlert,
+	domains.NotifCollabInvite,
+	domains.NotifInviteResponse,
+	domains.NotifVitalsAlert,
+	domains.NotifIncidentEscalation,
+	domains.NotifReportReview,
+}
+
+// Eventos que siempre se envían por email: sin ellos el usuario no se entera
+// de que su cuenta fue aprobada/rechazada y no puede entrar a cambiar nada.
+var mandatoryEmailEvents = map[string]bool{
+	domains.NotifAccountStatus: true,
+}
+
+const defaultChannel = domains.ChannelEmail
+
+var ErrInvalidPreference = errors.New("invalid notification preference")
+
+// resolveChannel obtiene el canal efectivo de un usuario para un tipo de evento
+func resolveChannel(tx *gorm.DB, userID uuid.UUID, eventType string) domains.NotificationChannel {
+	if mandatoryEmailEvents[eventType] {
+		return domains.ChannelEmail
+	}
+
+	var pref domains.NotificationPreference
+	if err := tx.Where("user_id = ? AND event_type = ?", userID, eventType).First(&pref).Error; err != nil {
+		return defaultChannel
+	}
+	return pref.Channel
+}
+
+// GetPreferences devuelve el canal efectivo para cada evento (incluye los defaults)
+func GetPreferences(userID uuid.UUID) (map[string]domains.NotificationChannel, error) {
+	var stored []domains.NotificationPreference
+	if err := database.GetDB().Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]domains.NotificationChannel, len(NotificationEventTypes))
+	for _, eventType := range NotificationEventTypes {
+		result[eventType] = defaultChannel
+	}
+	for _, pref := range stored {
+		result[pref.EventType] = pref.Channel
+	}
+	for eventType := range mandatoryEmailEvents {
+		result[eventType] = domains.ChannelEmail
+	}
+	return result, nil
+}
+
+// UpdatePreferences hace upsert de las preferencias indicadas
+func UpdatePreferences(userID uuid.UUID, inputs []domains.PreferenceInput) error {
+	valid := make(map[string]bool, len(NotificationEventTypes))
+	for _, eventType := range NotificationEventTypes {
+		valid[eventType] = true
+	}
+
+	prefs := make([]domains.NotificationPreference, 0, len(inputs))
+	for _, in := range inputs {
+		if !valid[in.EventType] {
+			return fmt.Errorf("%w: unknown event type %s", ErrInvalidPreference, in.EventType)
+		}
+		if mandatoryEmailEvents[in.EventType] && domains.NotificationChannel(in.Channel) != domains.ChannelEmail {
+			return fmt.Errorf("%w: %s is always delivered by email", ErrInvalidPreference, in.EventType)
+		}
+		prefs = append(prefs, domains.NotificationPreference{
+			UserID:    userID,
+			EventType: in.EventType,
+			Channel:   domains.NotificationChannel(in.Channel),
+		})
+	}
+
+	if len(prefs) == 0 {
+		return nil
+	}
+
+	return database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel", "updated_at"}),
+	}).Create(&prefs).Error
+}
+
+// SetDigestFrequency guarda la frecuencia del resumen (DAILY/WEEKLY) en ProfileData
+func SetDigestFrequency(user domains.User, frequency domains.DigestFrequency) error {
+	profile := make(map[string]interface{})
+	if len(user.ProfileData) > 0 {
+		if err := json.Unmarshal(user.ProfileData, &profile); err != nil {
+			profile = make(map[string]interface{})
+		}
+	}
+	profile["digest_frequency"] = string(frequency)
+
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return database.GetDB().Model(&user).Update("profile_data", datatypes.JSON(profileJSON)).Error
+}
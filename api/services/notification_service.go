@@ -3,14 +3,15 @@ package services
 import (
 	"fmt"
 	"log/slog"
-	"net/smtp"
-	"strings"
+	"sync"
+	"time"
 
 	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
 	"bitacora-medica-backend/api/domains"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type NotificationService struct {
@@ -21,64 +22,117 @@ func NewNotificationService(cfg *config.Config) *NotificationService {
 	return &NotificationService{cfg: cfg}
 }
 
-// --- CORE: ENVÍO REAL Y PERSISTENCIA ---
+// --- CORE: PERSISTENCIA Y COLA DE ENVÍO ---
 
-func (s *NotificationService) createAndNotify(userID uuid.UUID, notifType string, subject string, body string, relatedID *uuid.UUID) {
+// Notificaciones que se están creando en segundo plano
+var pendingNotifications sync.WaitGroup
+
+// WaitForNotifications espera a que terminen las notificaciones en curso (tests y apagado ordenado)
+func WaitForNotifications() {
+	pendingNotifications.Wait()
+}
+
+// createAndNotify crea la notificación en segundo plano: la request (o el login, que avisa a
+// todos los ADMIN de un usuario nuevo) no espera el render ni la transacción de cada destinatario.
+func (s *NotificationService) createAndNotify(userID uuid.UUID, notifType string, data map[string]interface{}, relatedID *uuid.UUID) {
+	pendingNotifications.Add(1)
+	go func() {
+		defer pendingNotifications.Done()
+		s.notify(userID, notifType, data, relatedID)
+	}()
+}
+
+// notify respeta la preferencia del destinatario para el evento (IN_APP, EMAIL,
+// DIGEST u OFF), renderiza la plantilla en su idioma, guarda la notificación in-app y,
+// si corresponde, encola el email en la misma transacción.
+// El envío real lo hace EmailOutboxWorker con reintentos, así un fallo SMTP o un reinicio
+// del servidor no pierden correos ya guardados.
+func (s *NotificationService) notify(userID uuid.UUID, notifType string, data map[string]interface{}, relatedID *uuid.UUID) {
 	db := database.GetDB()
+	var notif domains.Notification
 
-	// 1. Guardar en Base de Datos (Requerimiento )
-	notif := domains.Notification{
-		UserID:    userID,
-		Type:      notifType,
-		Message:   subject + ": " + body, // Resumen para la UI
-		RelatedID: relatedID,
-		IsRead:    false,
+	channel := resolveChannel(db, userID, notifType)
+	if channel == domains.ChannelOff {
+		return
 	}
 
-	// Usamos goroutine para que el guardado y envío no bloqueen al usuario
-	go func() {
-		// A. Guardar en DB
-		if err := db.Create(&notif).Error; err != nil {
-			slog.Error("Failed to save notification DB", "error", err)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// A. Obtener destinatario (email + idioma desde ProfileData)
+		var user domains.User
+		if err := tx.Select("id", "email", "profile_data").First(&user, "id = ?", userID).Error; err != nil {
+			return fmt.Errorf("recipient not found: %w", err)
 		}
 
-		// B. Obtener Email del destinatario
-		var user domains.User
-		if err := db.Select("email").First(&user, "id = ?", userID).Error; err != nil {
-			slog.Error("User email not found for notification", "userID", userID)
-			return
+		// B. Renderizar plantilla (texto + HTML)
+		rendered, err := renderEmail(notifType, userLocale(user), data)
+		if err != nil {
+			return fmt.Errorf("render email: %w", err)
 		}
 
-		// C. Enviar Email Real
-		s.sendRealEmail(user.Email, subject, body)
-	}()
+		// C. Guardar notificación para la UI
+		notif = domains.Notification{
+			UserID:    userID,
+			Type:      notifType,
+			Message:   rendered.Subject + ": " + rendered.Text, // Resumen para la UI
+			RelatedID: relatedID,
+			IsRead:    false,
+		}
+		if err := tx.Create(&notif).Error; err != nil {
+			return fmt.Errorf("save notification: %w", err)
+		}
+
+		// D. Encolar Email inmediato (lo envía el worker).
+		// Con DIGEST la notificación queda sin leer y la recoge el resumen periódico.
+		if channel != domains.ChannelEmail {
+			return nil
+		}
+		return EnqueueEmail(tx, &userID, user.Email, rendered)
+	})
+	if err != nil {
+		slog.Error("Failed to create notification", "userID", userID, "type", notifType, "error", err)
+		return
+	}
+
+	// Empujar en tiempo real a las pestañas abiertas (SSE)
+	GetNotificationBroker().Publish(notif)
 }
 
-func (s *NotificationService) sendRealEmail(to string, subject string, body string) {
-	// Configuración de autenticación SMTP
-	auth := smtp.PlainAuth("", s.cfg.SMTPEmail, s.cfg.SMTPPassword, s.cfg.SMTPHost)
+// careTeam devuelve el paciente y su equipo: creador + colaboradores ACEPTADOS (sin duplicados)
+func (s *NotificationService) careTeam(patientID uuid.UUID) (domains.Patient, map[string]domains.User) {
+	db := database.GetDB()
 
-	// Cabeceras estándar de email
-	headers := []string{
-		"To: " + to,
-		"From: Bitácora Médica <" + s.cfg.SMTPEmail + ">",
-		"Subject: " + subject,
-		"MIME-Version: 1.0",
-		"Content-Type: text/plain; charset=\"UTF-8\"",
-		"\r\n",
-	}
+	// Obtener Paciente (para el nombre y el creador)
+	var patient domains.Patient
+	db.First(&patient, "id = ?", patientID)
 
-	msg := []byte(strings.Join(headers, "") + body)
+	// 1. Buscar colaboradores ACEPTADOS
+	var collaborators []domains.User
+	db.Table("users").
+		Joins("JOIN collaborations ON collaborations.professional_id = users.id").
+		Where("collaborations.patient_id = ? AND collaborations.status = ?", patientID, domains.CollabAccepted).
+		Find(&collaborators)
+
+	// 2. Buscar al Creador
+	var creator domains.User
+	db.First(&creator, "id = ?", patient.CreatorID)
 
-	// Envío
-	addr := fmt.Sprintf("%s:%s", s.cfg.SMTPHost, s.cfg.SMTPPort)
-	err := smtp.SendMail(addr, auth, s.cfg.SMTPEmail, []string{to}, msg)
+	recipients := append(collaborators, creator)
 
-	if err != nil {
-		slog.Error("❌ FAILED to send email", "to", to, "error", err)
-	} else {
-		slog.Info("✅ Email sent successfully", "to", to, "subject", subject)
+	// Usamos un mapa para evitar duplicados si el creador también está en collaborations (raro pero posible)
+	uniqueUsers := make(map[string]domains.User)
+	for _, u := range recipients {
+		uniqueUsers[u.ID.String()] = u
 	}
+	return patient, uniqueUsers
+}
+
+// patientName obtiene el nombre real del paciente desde PersonalInfo
+func (s *NotificationService) patientName(patientID uuid.UUID) string {
+	var patient domains.Patient
+	if err := database.GetDB().Select("id", "personal_info").First(&patient, "id = ?", patientID).Error; err != nil {
+		return "Paciente " + patientID.String()
+	}
+	return PatientDisplayName(patient)
 }
 
 // --- MÉTODOS DE NEGOCIO (Los 5 Eventos del PDF) ---
@@ -88,79 +142,143 @@ func (s *NotificationService) NotifyNewUser(newUserID uuid.UUID, userEmail strin
 	var admins []domains.User
 	database.GetDB().Where("role = ?", domains.RoleAdmin).Find(&admins)
 
-	subject := "Nuevo Usuario Registrado"
-	body := fmt.Sprintf("El usuario %s se ha registrado y espera verificación. Por favor ingrese al panel administrativo.", userEmail)
+	data := map[string]interface{}{"UserEmail": userEmail}
 
 	for _, admin := range admins {
 		// Notificar a cada admin
-		s.createAndNotify(admin.ID, "NEW_USER", subject, body, &newUserID)
+		s.createAndNotify(admin.ID, domains.NotifNewUser, data, &newUserID)
 	}
 }
 
 // 2. AccountStatus[cite: 105]: Aprobación o Rechazo
 func (s *NotificationService) NotifyAccountStatus(userID uuid.UUID, status domains.UserStatus, reason string) {
-	subject := "Actualización de Estado de Cuenta"
-	body := fmt.Sprintf("Su cuenta ha sido: %s.", status)
-
-	if status == domains.StatusRejected {
-		body += fmt.Sprintf("\n\nMotivo del rechazo: %s", reason)
-	} else {
-		body += "\n\nYa puede acceder a la plataforma y gestionar sus pacientes."
+	data := map[string]interface{}{
+		"Status":   status,
+		"Approved": status != domains.StatusRejected,
+		"Reason":   reason,
 	}
 
-	s.createAndNotify(userID, "ACCOUNT_STATUS", subject, body, nil)
+	s.createAndNotify(userID, domains.NotifAccountStatus, data, nil)
 }
 
 // 3. IncidentAlert[cite: 106]: A todo el equipo
 func (s *NotificationService) NotifyIncident(patientID uuid.UUID, incidentDetails string) {
-	db := database.GetDB()
+	patient, team := s.careTeam(patientID)
 
-	// Obtener Paciente (para el nombre)
-	var patient domains.Patient
-	db.First(&patient, "id = ?", patientID)
-	// Como 'PersonalInfo' es JSON, aquí asumimos un string simple para el ejemplo,
-	// en producción parsearías el JSON para sacar "Juan Perez".
-	patientName := "Paciente ID " + patientID.String()
+	data := map[string]interface{}{
+		"PatientName": PatientDisplayName(patient),
+		"Details":     incidentDetails,
+	}
 
-	// 1. Buscar colaboradores ACEPTADOS
-	var collaborators []domains.User
-	db.Table("users").
-		Joins("JOIN collaborations ON collaborations.professional_id = users.id").
-		Where("collaborations.patient_id = ? AND collaborations.status = ?", patientID, domains.CollabAccepted).
-		Find(&collaborators)
+	for _, professional := range team {
+		s.createAndNotify(professional.ID, domains.NotifIncidentAlert, data, &patientID)
+	}
+}
 
-	// 2. Buscar al Creador
-	var creator domains.User
-	db.First(&creator, "id = ?", patient.CreatorID)
+// 4. CollabInvite[cite: 107]: Invitación recibida
+func (s *NotificationService) NotifyCollabInvite(invitedUserID uuid.UUID, patientID uuid.UUID) {
+	data := map[string]interface{}{"PatientName": s.patientName(patientID)}
 
-	recipients := append(collaborators, creator)
+	s.createAndNotify(invitedUserID, domains.NotifCollabInvite, data, &patientID)
+}
 
-	// Usamos un mapa para evitar duplicados si el creador también está en collaborations (raro pero posible)
-	uniqueUsers := make(map[string]domains.User)
-	for _, u := range recipients {
-		uniqueUsers[u.ID.String()] = u
+// 5. InviteResponse[cite: 108]: Aviso al creador
+func (s *NotificationService) NotifyInviteResponse(creatorID uuid.UUID, patientID uuid.UUID, responderEmail string, status domains.CollabStatus) {
+	data := map[string]interface{}{
+		"ResponderEmail": responderEmail,
+		"Status":         status,
+		"Accepted":       status == domains.CollabAccepted,
+		"PatientName":    s.patientName(patientID),
 	}
 
-	subject := "⚠️ ALERTA DE INCIDENTE: " + patientName
-	body := fmt.Sprintf("Se ha reportado un incidente para el paciente %s.\n\nDetalle: %s\n\nPor favor revise la bitácora para más detalles y evidencia.", patientName, incidentDetails)
+	s.createAndNotify(creatorID, domains.NotifInviteResponse, data, &patientID)
+}
+
+// 6. VitalsAlert: signos vitales fuera de rango, a todo el equipo
+func (s *NotificationService) NotifyAbnormalVitals(patientID uuid.UUID, alerts []VitalAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	patient, team := s.careTeam(patientID)
 
-	for _, professional := range uniqueUsers {
-		s.createAndNotify(professional.ID, "INCIDENT_ALERT", subject, body, &patientID)
+	data := map[string]interface{}{
+		"PatientName": PatientDisplayName(patient),
+		"Alerts":      alerts,
+	}
+
+	for _, professional := range team {
+		s.createAndNotify(professional.ID, domains.NotifVitalsAlert, data, &patientID)
 	}
 }
 
-// 4. CollabInvite[cite: 107]: Invitación recibida
-func (s *NotificationService) NotifyCollabInvite(invitedUserID uuid.UUID, patientID uuid.UUID) {
-	subject := "Invitación a Colaborar"
-	body := "Has sido invitado a colaborar en el expediente clínico de un paciente. Ingresa a la app para aceptar o rechazar."
+// 7. IncidentEscalation: incidente sin resolver pasado su plazo.
+// Avisa al responsable y al creador del paciente; desde el segundo escalamiento también a los ADMINs.
+func (s *NotificationService) NotifyIncidentEscalation(incident domains.Incident, now time.Time) {
+	db := database.GetDB()
+
+	var patient domains.Patient
+	db.Select("id", "creator_id", "personal_info").First(&patient, "id = ?", incident.PatientID)
+
+	recipients := map[uuid.UUID]bool{patient.CreatorID: true}
+	if incident.AssigneeID != nil {
+		recipients[*incident.AssigneeID] = true
+	}
+	if incident.EscalationLevel > 1 {
+		var adminIDs []uuid.UUID
+		db.Model(&domains.User{}).Where("role = ?", domains.RoleAdmin).Pluck("id", &adminIDs)
+		for _, id := range adminIDs {
+			recipients[id] = true
+		}
+	}
 
-	s.createAndNotify(invitedUserID, "COLLAB_INVITE", subject, body, &patientID)
+	data := map[string]interface{}{
+		"PatientName":  PatientDisplayName(patient),
+		"Description":  incident.Description,
+		"Category":     incident.Category,
+		"Severity":     string(incident.Severity),
+		"Status":       string(incident.Status),
+		"Level":        incident.EscalationLevel,
+		"HoursOverdue": int(now.Sub(incident.DueAt).Hours()),
+	}
+
+	for userID := range recipients {
+		if userID == uuid.Nil {
+			continue
+		}
+		s.createAndNotify(userID, domains.NotifIncidentEscalation, data, &incident.ID)
+	}
 }
 
-// 5. InviteResponse[cite: 108]: Aviso al creador
-func (s *NotificationService) NotifyInviteResponse(creatorID uuid.UUID, responderEmail string, status domains.CollabStatus) {
-	subject := fmt.Sprintf("Invitación %s", status)
-	body := fmt.Sprintf("El profesional %s ha respondido a tu invitación: %s.", responderEmail, status)
+// 8. ReportReview: reporte individual enviado (aviso al creador del paciente, que lo revisa)
+// o revisado (aviso al autor: aprobado o devuelto con observaciones)
+func (s *NotificationService) NotifyReportReview(report domains.ProfessionalReport, author domains.User) {
+	data := map[string]interface{}{
+		"PatientName": s.patientName(report.PatientID),
+		"AuthorName":  UserDisplayName(author),
+		"Period":      report.DateRangeStart.Format("02-01-2006") + " - " + report.DateRangeEnd.Format("02-01-2006"),
+		"Status":      string(report.Status),
+		"Submitted":   report.Status == domains.ReportSubmitted,
+		"Approved":    report.Status == domains.ReportApproved,
+		"Notes":       report.ReviewNotes,
+	}
+
+	recipient := report.AuthorID
+	if report.Status == domains.ReportSubmitted {
+		var patient domains.Patient
+		if err := database.GetDB().Select("id", "creator_id").First(&patient, "id = ?", report.PatientID).Error; err != nil {
+			return
+		}
+		recipient = patient.CreatorID
+	}
+	// Los reportes del propio creador los revisa un ADMIN: se avisa a los administradores
+	if report.Status == domains.ReportSubmitted && recipient == report.AuthorID {
+		var admins []domains.User
+		database.GetDB().Where("role = ?", domains.RoleAdmin).Find(&admins)
+		for _, admin := range admins {
+			s.createAndNotify(admin.ID, domains.NotifReportReview, data, &report.ID)
+		}
+		return
+	}
 
-	s.createAndNotify(creatorID, "INVITE_RESPONSE", subject, body, nil)
+	s.createAndNotify(recipient, domains.NotifReportReview, data, &report.ID)
 }
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"log/slog"
+
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Tamaño de lote del backfill (evita cargar todas las sesiones en memoria)
+const backfillBatchSize = 200
+
+// ObjectKeyBackfillResult filas convertidas por BackfillObjectKeys
+type ObjectKeyBackfillResult struct {
+	Patients int
+	Sessions int
+}
+
+// BackfillObjectKeys convierte las URLs públicas guardadas antes de los buckets privados en claves
+// de objeto (Patient.ConsentPDFUrl, Session.Photos y Session.IncidentPhoto), con la misma
+// normalización que aplican los handlers (ObjectKeyFromReference). Tarea única e idempotente:
+// se ejecuta con `go run . backfill-object-keys`. Incluye las filas con soft delete.
+func BackfillObjectKeys(db *gorm.DB) (ObjectKeyBackfillResult, error) {
+	var result ObjectKeyBackfillResult
+
+	// 1. Consentimiento vigente de cada paciente
+	var patients []domains.Patient
+	err := db.Unscoped().Select("id", "consent_pdf_url").
+		Where("consent_pdf_url LIKE ?", "%://%").
+		FindInBatches(&patients, backfillBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, patient := range patients {
+				key := ObjectKeyFromReference(BucketPatientsConsent, patient.ConsentPDFUrl)
+				if key == patient.ConsentPDFUrl {
+					slog.Warn("Consent URL does not point to the consent bucket, left as is", "patientID", patient.ID)
+					continue
+				}
+				if err := db.Unscoped().Model(&domains.Patient{}).Where("id = ?", patient.ID).
+					UpdateColumn("consent_pdf_url", key).Error; err != nil {
+					return err
+				}
+				result.Patients++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return result, err
+	}
+
+	// 2. Fotos de las sesiones (evidencia e incidente)
+	var sessions []domains.Session
+	err = db.Unscoped().Select("id", "photos", "incident_photo").
+		Where("incident_photo LIKE ? OR EXISTS (SELECT 1 FROM unnest(photos) AS photo WHERE photo LIKE ?)", "%://%", "%://%").
+		FindInBatches(&sessions, backfillBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, session := range sessions {
+				photos := pq.StringArray(ObjectKeysFromReferences(BucketSessionEvidence, session.Photos))
+				incidentPhoto := ObjectKeyFromReference(BucketSessionEvidence, session.IncidentPhoto)
+
+				if err := db.Unscoped().Model(&domains.Session{}).Where("id = ?", session.ID).
+					UpdateColumns(map[string]interface{}{"photos": photos, "incident_photo": incidentPhoto}).Error; err != nil {
+					return err
+				}
+				result.Sessions++
+			}
+			return nil
+		}).Error
+	return result, err
+}
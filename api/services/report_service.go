@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errores del ciclo de vida de los reportes individuales
+var (
+	ErrReportNotFound     = errors.New("report not found")
+	ErrInvalidReportDates = errors.New("start_date and end_date must be YYYY-MM-DD and start_date not after end_date")
+	ErrReportOverlap      = errors.New("author already has a report for this patient overlapping the period")
+	ErrReportNotDraft     = errors.New("only draft reports can be edited or submitted")
+	ErrReportNotSubmitted = errors.New("only submitted reports can be reviewed")
+	ErrReportApproved     = errors.New("approved reports can no longer be deleted")
+	ErrReportNotAuthor    = errors.New("only the author can modify the report")
+	ErrReviewNotAllowed   = errors.New("only the patient creator (or an admin, for the creator's own reports) can review reports")
+	ErrSelfReview         = errors.New("authors cannot review their own reports")
+	ErrReportEmptyContent = errors.New("report content cannot be empty")
+)
+
+// ReportFilter filtros del listado. PatientIDs es una subquery (pacientes visibles); nil = sin restricción.
+// Los borradores solo los ve su autor (Viewer).
+type ReportFilter struct {
+	Viewer     uuid.UUID
+	PatientID  *uuid.UUID
+	PatientIDs *gorm.DB
+	AuthorID   *uuid.UUID
+	Status     string
+}
+
+type ReportService struct{}
+
+func NewReportService() *ReportService {
+	return &ReportService{}
+}
+
+// ParseRange valida el periodo del reporte (fechas YYYY-MM-DD, inicio no posterior al fin)
+func (s *ReportService) ParseRange(rawStart, rawEnd string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", rawStart)
+	if err != nil {
+		return start, start, ErrInvalidReportDates
+	}
+	end, err := time.Parse("2006-01-02", rawEnd)
+	if err != nil || end.Before(start) {
+		return start, start, ErrInvalidReportDates
+	}
+	return start, end, nil
+}
+
+// Create registra el reporte como borrador (o ya enviado con submit)
+func (s *ReportService) Create(tx *gorm.DB, report *domains.ProfessionalReport, submit bool) error {
+	if err := s.checkOverlap(tx, *report); err != nil {
+		return err
+	}
+
+	report.Status = domains.ReportDraft
+	if submit {
+		now := time.Now()
+		report.Status = domains.ReportSubmitted
+		report.SubmittedAt = &now
+	}
+	return overlapError(tx.Omit("Author").Create(report).Error)
+}
+
+// Get carga el reporte con su autor. Un borrador ajeno se informa como inexistente.
+func (s *ReportService) Get(id string, viewer uuid.UUID) (domains.ProfessionalReport, error) {
+	var report domains.ProfessionalReport
+	err := database.GetDB().Preload("Author").First(&report, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return report, ErrReportNotFound
+	}
+	if err == nil && report.Status == domains.ReportDraft && report.AuthorID != viewer {
+		return report, ErrReportNotFound
+	}
+	return report, err
+}
+
+// List devuelve los reportes visibles (más recientes primero)
+func (s *ReportService) List(filter ReportFilter, page, limit int) ([]domains.ProfessionalReport, int64, error) {
+	query := database.GetDB().Model(&domains.ProfessionalReport{}).
+		Where("status <> ? OR author_id = ?", domains.ReportDraft, filter.Viewer)
+
+	if filter.PatientID != nil {
+		query = query.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.PatientIDs != nil {
+		query = query.Where("patient_id IN (?)", filter.PatientIDs)
+	}
+	if filter.AuthorID != nil {
+		query = query.Where("author_id = ?", *filter.AuthorID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []domains.ProfessionalReport
+	err := query.Preload("Author").
+		Order("date_range_start DESC").Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&reports).Error
+	return reports, total, err
+}
+
+// Update edita un borrador del autor
+func (s *ReportService) Update(tx *gorm.DB, report *domains.ProfessionalReport, editor domains.User, input domains.UpdateReportInput) error {
+	if report.AuthorID != editor.ID {
+		return ErrReportNotAuthor
+	}
+	if err := s.lock(tx, report.ID, domains.ReportDraft); err != nil {
+		return err
+	}
+
+	rawStart := report.DateRangeStart.Format("2006-01-02")
+	rawEnd := report.DateRangeEnd.Format("2006-01-02")
+	if input.DateRangeStart != nil {
+		rawStart = *input.DateRangeStart
+	}
+	if input.DateRangeEnd != nil {
+		rawEnd = *input.DateRangeEnd
+	}
+	start, end, err := s.ParseRange(rawStart, rawEnd)
+	if err != nil {
+		return err
+	}
+	report.DateRangeStart = start
+	report.DateRangeEnd = end
+
+	if input.Content != nil {
+		if strings.TrimSpace(*input.Content) == "" {
+			return ErrReportEmptyContent
+		}
+		report.Content = *input.Content
+	}
+	if input.ObjectivesAchieved != nil {
+		report.ObjectivesAchieved = *input.ObjectivesAchieved
+	}
+
+	if err := s.checkOverlap(tx, *report); err != nil {
+		return err
+	}
+	return overlapError(tx.Omit("Author").Save(report).Error)
+}
+
+// Delete elimina (soft delete) un borrador o un reporte enviado; los aprobados se conservan
+func (s *ReportService) Delete(tx *gorm.DB, report domains.ProfessionalReport, editor domains.User) error {
+	if report.AuthorID != editor.ID && editor.Role != domains.RoleAdmin {
+		return ErrReportNotAuthor
+	}
+
+	result := tx.Where("id = ? AND status <> ?", report.ID, domains.ReportApproved).Delete(&domains.ProfessionalReport{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportApproved
+	}
+	return nil
+}
+
+// Submit envía el borrador a revisión del creador del paciente
+func (s *ReportService) Submit(tx *gorm.DB, report *domains.ProfessionalReport, editor domains.User) error {
+	if report.AuthorID != editor.ID {
+		return ErrReportNotAuthor
+	}
+
+	now := time.Now()
+	result := tx.Model(&domains.ProfessionalReport{}).
+		Where("id = ? AND status = ?", report.ID, domains.ReportDraft).
+		Updates(map[string]interface{}{"status": domains.ReportSubmitted, "submitted_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportNotDraft
+	}
+
+	report.Status = domains.ReportSubmitted
+	report.SubmittedAt = &now
+	return nil
+}
+
+// Approve el revisor aprueba el reporte enviado: desde ahí entra al reporte maestro
+func (s *ReportService) Approve(tx *gorm.DB, report *domains.ProfessionalReport, reviewer domains.User) error {
+	return s.review(tx, report, reviewer, domains.ReportApproved, "")
+}
+
+// Return el revisor devuelve el reporte a borrador con observaciones
+func (s *ReportService) Return(tx *gorm.DB, report *domains.ProfessionalReport, reviewer domains.User, notes string) error {
+	return s.review(tx, report, reviewer, domains.ReportDraft, notes)
+}
+
+// review aplica la revisión. Nadie revisa su propio reporte: el creador del paciente revisa los
+// reportes de su equipo y los reportes del propio creador los revisa un ADMIN.
+func (s *ReportService) review(tx *gorm.DB, report *domains.ProfessionalReport, reviewer domains.User, status domains.ReportStatus, notes string) error {
+	if report.AuthorID == reviewer.ID {
+		return ErrSelfReview
+	}
+
+	var patient domains.Patient
+	if err := tx.Select("id", "creator_id").First(&patient, "id = ?", report.PatientID).Error; err != nil {
+		return err
+	}
+	creatorReviews := patient.CreatorID == reviewer.ID
+	adminReviews := patient.CreatorID == report.AuthorID && reviewer.Role == domains.RoleAdmin
+	if !creatorReviews && !adminReviews {
+		return ErrReviewNotAllowed
+	}
+
+	now := time.Now()
+	result := tx.Model(&domains.ProfessionalReport{}).
+		Where("id = ? AND status = ?", report.ID, domains.ReportSubmitted).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"reviewed_at":    now,
+			"reviewed_by_id": reviewer.ID,
+			"review_notes":   notes,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportNotSubmitted
+	}
+
+	report.Status = status
+	report.ReviewedAt = &now
+	report.ReviewedByID = &reviewer.ID
+	report.ReviewNotes = notes
+	return nil
+}
+
+// lock bloquea la fila y verifica su estado (evita editar un reporte que se envía en paralelo)
+func (s *ReportService) lock(tx *gorm.DB, id uuid.UUID, status domains.ReportStatus) error {
+	var current domains.ProfessionalReport
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrReportNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current.Status != status {
+		return ErrReportNotDraft
+	}
+	return nil
+}
+
+// checkOverlap un autor no puede tener dos reportes del mismo paciente con periodos que se crucen.
+// Es el aviso temprano: dos creaciones en paralelo las detiene la restricción EXCLUDE de la base
+// (migración 000015), que overlapError traduce al mismo error.
+func (s *ReportService) checkOverlap(tx *gorm.DB, report domains.ProfessionalReport) error {
+	query := tx.Model(&domains.ProfessionalReport{}).
+		Where("patient_id = ? AND author_id = ?", report.PatientID, report.AuthorID).
+		Where("date_range_start <= ? AND date_range_end >= ?", report.DateRangeEnd, report.DateRangeStart)
+	if report.ID != uuid.Nil {
+		query = query.Where("id <> ?", report.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrReportOverlap
+	}
+	return nil
+}
+
+// overlapError traduce la violación de la restricción de exclusión (SQLSTATE 23P01) a ErrReportOverlap
+func overlapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" {
+		return ErrReportOverlap
+	}
+	return err
+}
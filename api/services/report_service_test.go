@@ -0,0 +1,24 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestOverlapError(t *testing.T) {
+	exclusion := &pgconn.PgError{Code: "23P01", ConstraintName: "professional_reports_no_overlap"}
+	if err := overlapError(fmt.Errorf("insert: %w", exclusion)); err != ErrReportOverlap {
+		t.Errorf("overlapError(23P01) = %v, want ErrReportOverlap", err)
+	}
+
+	unique := &pgconn.PgError{Code: "23505"}
+	if err := overlapError(unique); !errors.Is(err, unique) {
+		t.Errorf("overlapError(23505) = %v, want the original error", err)
+	}
+	if err := overlapError(nil); err != nil {
+		t.Errorf("overlapError(nil) = %v", err)
+	}
+}
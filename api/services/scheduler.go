@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Scheduler ejecuta tareas periódicas dentro del proceso (sin cron externo)
+type Scheduler struct {
+	jobs []scheduledJob
+}
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context)
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registra una tarea que corre al iniciar y luego cada "interval"
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context)) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: run})
+}
+
+// Start lanza cada tarea en su propia goroutine hasta que ctx se cancela
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job scheduledJob) {
+	slog.Info("Scheduled job registered", "job", job.name, "interval", job.interval)
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runSafely(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSafely evita que un panic en una tarea tumbe el servidor completo
+func (s *Scheduler) runSafely(ctx context.Context, job scheduledJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Scheduled job panicked", "job", job.name, "panic", r)
+		}
+	}()
+	job.run(ctx)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRevisionNotFound = errors.New("session revision not found")
+
+// Valor de "to" en el diff que representa el estado actual de la sesión
+const RevisionCurrent = "current"
+
+type SessionRevisionService struct{}
+
+func NewSessionRevisionService() *SessionRevisionService {
+	return &SessionRevisionService{}
+}
+
+// Record guarda el estado previo de la sesión como nueva versión.
+// Debe llamarse dentro de la transacción del cambio (antes de guardar o borrar).
+func (s *SessionRevisionService) Record(tx *gorm.DB, prior domains.Session, editor domains.User, reason, changeType string) (domains.SessionRevision, error) {
+	// 1. Bloquear la sesión para que dos ediciones simultáneas no tomen el mismo número de versión
+	var locked domains.Session
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", prior.ID).Error; err != nil {
+		return domains.SessionRevision{}, err
+	}
+
+	var last int
+	if err := tx.Model(&domains.SessionRevision{}).Where("session_id = ?", prior.ID).
+		Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+		return domains.SessionRevision{}, err
+	}
+
+	// 2. Snapshot de los campos clínicos (mismo criterio que la auditoría)
+	snapshot, err := json.Marshal(auditFields(prior))
+	if err != nil {
+		return domains.SessionRevision{}, err
+	}
+
+	revision := domains.SessionRevision{
+		SessionID:  prior.ID,
+		Version:    last + 1,
+		Snapshot:   datatypes.JSON(snapshot),
+		ChangeType: changeType,
+		EditedByID: editor.ID,
+		Reason:     reason,
+	}
+	err = tx.Create(&revision).Error
+	return revision, err
+}
+
+// List devuelve las revisiones de una sesión, de la más antigua a la más nueva
+func (s *SessionRevisionService) List(sessionID uuid.UUID) ([]domains.SessionRevision, error) {
+	var revisions []domains.SessionRevision
+	err := database.GetDB().Preload("EditedBy").
+		Where("session_id = ?", sessionID).
+		Order("version ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// Diff compara dos versiones. "to" puede ser RevisionCurrent para comparar contra la sesión actual.
+func (s *SessionRevisionService) Diff(session domains.Session, from int, to string) (map[string]map[string]interface{}, error) {
+	fromFields, err := s.snapshot(session.ID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var toFields map[string]interface{}
+	if to == RevisionCurrent {
+		toFields = auditFields(session)
+	} else {
+		version, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, ErrRevisionNotFound
+		}
+		if toFields, err = s.snapshot(session.ID, version); err != nil {
+			return nil, err
+		}
+	}
+
+	return AuditDiff(fromFields, toFields), nil
+}
+
+func (s *SessionRevisionService) snapshot(sessionID uuid.UUID, version int) (map[string]interface{}, error) {
+	var revision domains.SessionRevision
+	err := database.GetDB().Where("session_id = ? AND version = ?", sessionID, version).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(revision.Snapshot, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errores de firma clínica
+var (
+	ErrSessionSigned        = errors.New("session is signed")
+	ErrSessionNotSigned     = errors.New("session is not signed")
+	ErrSessionCosigned      = errors.New("session is already co-signed")
+	ErrSignNotAllowed       = errors.New("only the session author can sign")
+	ErrCosignNotAllowed     = errors.New("only the patient creator can co-sign")
+	ErrCosignBySignerDenied = errors.New("the signer cannot co-sign their own session")
+)
+
+type SessionSigningService struct{}
+
+func NewSessionSigningService() *SessionSigningService {
+	return &SessionSigningService{}
+}
+
+// LockEditable bloquea la fila de la sesión dentro de la transacción, la relee y falla si ya está firmada.
+// Las ediciones y eliminaciones deben aplicar sus cambios (y tomar el "antes" de la revisión y la
+// auditoría) sobre la copia retornada: así una firma o edición concurrente no queda pisada por un
+// Save con la versión leída antes de la transacción.
+func (s *SessionSigningService) LockEditable(tx *gorm.DB, sessionID uuid.UUID) (domains.Session, error) {
+	var locked domains.Session
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", sessionID).Error; err != nil {
+		return locked, err
+	}
+	if locked.IsSigned() {
+		return locked, ErrSessionSigned
+	}
+	return locked, nil
+}
+
+// Sign cierra la nota: solo el autor de la sesión puede firmarla y solo una vez
+func (s *SessionSigningService) Sign(tx *gorm.DB, session *domains.Session, signer domains.User, now time.Time) error {
+	if session.ProfessionalID != signer.ID {
+		return ErrSignNotAllowed
+	}
+
+	// Transición condicional: si otra petición firmó primero, no se toca nada
+	result := tx.Model(&domains.Session{}).
+		Where("id = ? AND signed_at IS NULL", session.ID).
+		Updates(map[string]interface{}{"signed_at": now, "signed_by_id": signer.ID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionSigned
+	}
+
+	session.SignedAt = &now
+	session.SignedByID = &signer.ID
+	return nil
+}
+
+// Cosign registra la co-firma del creador del paciente sobre una sesión ya firmada
+func (s *SessionSigningService) Cosign(tx *gorm.DB, session *domains.Session, cosigner domains.User, now time.Time) error {
+	if !session.IsSigned() {
+		return ErrSessionNotSigned
+	}
+
+	var patient domains.Patient
+	if err := tx.Select("id", "creator_id").First(&patient, "id = ?", session.PatientID).Error; err != nil {
+		return err
+	}
+	if patient.CreatorID != cosigner.ID {
+		return ErrCosignNotAllowed
+	}
+	if session.SignedByID != nil && *session.SignedByID == cosigner.ID {
+		return ErrCosignBySignerDenied
+	}
+
+	result := tx.Model(&domains.Session{}).
+		Where("id = ? AND signed_at IS NOT NULL AND cosigned_at IS NULL", session.ID).
+		Updates(map[string]interface{}{"cosigned_at": now, "cosigned_by_id": cosigner.ID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionCosigned
+	}
+
+	session.CosignedAt = &now
+	session.CosignedByID = &cosigner.ID
+	return nil
+}
+
+// AddAddendum agrega una nota posterior a una sesión firmada
+func (s *SessionSigningService) AddAddendum(tx *gorm.DB, session domains.Session, author domains.User, content string) (domains.SessionAddendum, error) {
+	if !session.IsSigned() {
+		return domains.SessionAddendum{}, ErrSessionNotSigned
+	}
+
+	addendum := domains.SessionAddendum{
+		SessionID: session.ID,
+		AuthorID:  author.ID,
+		Content:   content,
+	}
+	err := tx.Create(&addendum).Error
+	return addendum, err
+}
+
+// Addenda devuelve las adendas de una sesión en orden cronológico
+func (s *SessionSigningService) Addenda(sessionID uuid.UUID) ([]domains.SessionAddendum, error) {
+	var addenda []domains.SessionAddendum
+	err := database.GetDB().Preload("Author").
+		Where("session_id = ?", sessionID).
+		Order("created_at ASC").
+		Find(&addenda).Error
+	return addenda, err
+}
@@ -2,135 +2,316 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"mime/multipart"
-	"net/http"
-	"path/filepath"
+	"net/url"
+	"strings"
 	"time"
 
 	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Buckets usados por la aplicación (PRIVADOS: datos de salud)
+const (
+	BucketPatientsConsent = "patients-consent"
+	BucketSessionEvidence = "session-evidence"
+)
+
+var ErrUnknownBucket = errors.New("unknown bucket")
+
 type StorageService struct {
 	Config *config.Config
+	store  BlobStore
 }
 
 func NewStorageService(cfg *config.Config) *StorageService {
-	return &StorageService{Config: cfg}
+	return &StorageService{Config: cfg, store: GetBlobStore(cfg)}
 }
 
-// UploadConsentPDF sube el archivo a Supabase y retorna la URL pública
-func (s *StorageService) UploadConsentPDF(file *multipart.FileHeader) (string, error) {
-	// 1. Validar extensión
-	ext := filepath.Ext(file.Filename)
-	if ext != ".pdf" {
-		return "", fmt.Errorf("only PDF files are allowed")
+// Tipos aceptados por bucket (se validan por magic bytes, no por extensión)
+var (
+	allowedConsentTypes = map[string]bool{"application/pdf": true}
+	allowedImageTypes   = map[string]bool{
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/webp": true,
+		"image/heic": true, // Se reconoce solo para rechazarlo con un mensaje claro (ver UploadImage)
 	}
+	allowedVideoTypes = map[string]bool{"video/mp4": true, "video/quicktime": true}
+)
 
-	// 2. Abrir el archivo
-	src, err := file.Open()
-	if err != nil {
-		return "", err
-	}
-	defer src.Close()
+// UploadedFile describe un archivo guardado: la clave (la que se guarda en la sesión/paciente),
+// su hash para integridad/deduplicación y, en fotos, las claves de cada variante (original, web, thumb)
+type UploadedFile struct {
+	Key         string
+	ContentType string
+	Size        int64  // Tamaño recibido (antes de procesar)
+	SHA256      string // Hash (hex) del contenido recibido
+	Variants    map[string]string
+}
 
-	// Leer contenido
-	fileBytes, err := io.ReadAll(src)
+// UploadConsentPDF transmite el PDF al bucket privado sin cargarlo completo en memoria:
+// valida la firma, el tamaño (hasta limit bytes) y la ausencia de JavaScript mientras se sube.
+func (s *StorageService) UploadConsentPDF(ctx context.Context, r io.Reader, limit int64) (UploadedFile, error) {
+	// 1. Validar firma real de PDF (primeros bytes)
+	body, detected, err := sniffReader(r, allowedConsentTypes)
 	if err != nil {
-		return "", err
+		return UploadedFile{}, err
 	}
 
-	// 3. Generar nombre único: {uuid}_{timestamp}.pdf
-	fileName := fmt.Sprintf("%s_%d.pdf", uuid.New().String(), time.Now().Unix())
+	// 2. Generar nombre único: {uuid}_{timestamp}.pdf
+	fileName := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), detected.Ext)
+
+	// 3. Subir al bucket de consentimientos calculando hash, tamaño e inspeccionando el contenido
+	counter := newUploadReader(body, limit)
+	scanner := newPDFScanner()
+	putErr := s.store.Put(ctx, BucketPatientsConsent, fileName, io.TeeReader(counter, scanner), -1, detected.ContentType)
 
-	// 4. Subir a Supabase Storage vía REST API
-	// Endpoint: POST /storage/v1/object/{bucket}/{path}
-	bucketName := "patients-consent"
-	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.Config.SupabaseURL, bucketName, fileName)
+	// 4. Si se superó el límite, hay JavaScript o no se pudo inspeccionar, el objeto no debe quedar en el bucket
+	validationErr := counter.err
+	if validationErr == nil && putErr == nil {
+		validationErr = scanner.Err()
+	}
+	if validationErr != nil {
+		if putErr == nil {
+			s.store.Delete(context.Background(), BucketPatientsConsent, fileName)
+		}
+		return UploadedFile{}, validationErr
+	}
+	if putErr != nil {
+		return UploadedFile{}, putErr
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(fileBytes))
+	// 5. Solo devolvemos la clave: el acceso es siempre vía URL firmada
+	return UploadedFile{Key: fileName, ContentType: detected.ContentType, Size: counter.n, SHA256: counter.Sum()}, nil
+}
+
+// UploadImage sube evidencia visual de sesiones: sin EXIF, enderezada y en varios tamaños.
+// Las fotos se decodifican en memoria para procesarlas (acotado por UploadMaxImageBytes).
+func (s *StorageService) UploadImage(ctx context.Context, r io.Reader) (UploadedFile, error) {
+	// 1. Validar firma (JPEG, PNG, WebP) y tamaño
+	body, detected, err := sniffReader(r, allowedImageTypes)
 	if err != nil {
-		return "", err
+		return UploadedFile{}, err
+	}
+	// HEIC no se puede decodificar en Go puro: guardarlo tal cual conservaría su EXIF (GPS)
+	if detected.ContentType == "image/heic" {
+		return UploadedFile{}, ErrHEICNotSupported
 	}
 
-	// Headers requeridos por Supabase
-	req.Header.Set("Authorization", "Bearer "+s.Config.SupabaseKey)
-	req.Header.Set("Content-Type", "application/pdf")
+	counter := newUploadReader(body, s.Config.UploadMaxImageBytes)
+	fileBytes, err := io.ReadAll(counter)
+	if err != nil {
+		return UploadedFile{}, err
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// 2. Procesar: quitar metadatos (GPS), orientar y generar tamaños
+	variants, err := processImage(fileBytes, detected, s.Config.UploadMaxImagePixels)
 	if err != nil {
-		slog.Error("Failed to request Supabase Storage", "error", err)
-		return "", err
+		return UploadedFile{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		slog.Error("Supabase Storage Error", "status", resp.StatusCode, "body", string(body))
-		return "", fmt.Errorf("failed to upload file, status: %d", resp.StatusCode)
+	// 3. Generar nombre único (la extensión sale del formato final)
+	baseName := fmt.Sprintf("%s_%d", uuid.New().String(), time.Now().Unix())
+	stored := UploadedFile{
+		Key:         baseName + variants[0].Ext,
+		ContentType: variants[0].ContentType,
+		Size:        counter.n,
+		SHA256:      counter.Sum(),
+		Variants:    make(map[string]string, len(variants)),
 	}
 
-	// 5. Construir URL pública (Asumiendo bucket público)
-	// Formato: {supabaseUrl}/storage/v1/object/public/{bucket}/{fileName}
-	publicURL := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", s.Config.SupabaseURL, bucketName, fileName)
+	// 4. Subir cada variante a bucket "session-evidence"
+	for _, v := range variants {
+		key := ImageVariantKey(stored.Key, v.Variant)
+		if err := s.store.Put(ctx, BucketSessionEvidence, key, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType); err != nil {
+			return UploadedFile{}, err
+		}
+		stored.Variants[v.Variant] = key
+	}
 
-	return publicURL, nil
+	return stored, nil
 }
 
-// Agrega este método a la struct StorageService
-func (s *StorageService) UploadImage(file *multipart.FileHeader) (string, error) {
-	// 1. Validar extensión (Solo imágenes)
-	ext := filepath.Ext(file.Filename)
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-		return "", fmt.Errorf("only JPG/PNG images are allowed")
+// UploadVideo transmite un video de evidencia (MP4/MOV, hasta limit bytes) al bucket sin cargarlo
+// en memoria. No se re-codifica: se anulan sus cajas de metadatos (GPS) mientras se sube.
+func (s *StorageService) UploadVideo(ctx context.Context, r io.Reader, limit int64) (UploadedFile, error) {
+	// 1. Validar firma (ftyp de MP4 o QuickTime)
+	body, detected, err := sniffReader(r, allowedVideoTypes)
+	if err != nil {
+		return UploadedFile{}, err
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		return "", err
+	// 2. Generar nombre único: {uuid}_{timestamp}.mp4
+	fileName := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), detected.Ext)
+
+	// 3. Subir calculando hash y tamaño de lo recibido; los metadatos se anulan en el camino
+	counter := newUploadReader(body, limit)
+	stripped, writer := io.Pipe()
+	stripDone := make(chan error, 1)
+	go func() {
+		err := stripMP4Metadata(writer, counter)
+		writer.CloseWithError(err)
+		stripDone <- err
+	}()
+	putErr := s.store.Put(ctx, BucketSessionEvidence, fileName, stripped, -1, detected.ContentType)
+	stripped.Close() // Si Put terminó antes de leer todo, libera la goroutine
+	stripErr := <-stripDone
+
+	// 4. Si se superó el límite o el archivo está mal formado, el objeto no debe quedar en el bucket
+	// (ErrClosedPipe solo indica que Put falló primero: se informa el error de Put)
+	validationErr := counter.err
+	if validationErr == nil && !errors.Is(stripErr, io.ErrClosedPipe) {
+		validationErr = stripErr
+	}
+	if validationErr != nil {
+		if putErr == nil {
+			s.store.Delete(context.Background(), BucketSessionEvidence, fileName)
+		}
+		return UploadedFile{}, validationErr
+	}
+	if putErr != nil {
+		return UploadedFile{}, putErr
 	}
-	defer src.Close()
 
-	fileBytes, err := io.ReadAll(src)
-	if err != nil {
-		return "", err
+	return UploadedFile{Key: fileName, ContentType: detected.ContentType, Size: counter.n, SHA256: counter.Sum()}, nil
+}
+
+// DeleteUploaded elimina un archivo recién subido y sus variantes (ej: duplicado detectado)
+func (s *StorageService) DeleteUploaded(ctx context.Context, bucket string, file UploadedFile) {
+	keys := []string{file.Key}
+	for _, key := range file.Variants {
+		if key != file.Key {
+			keys = append(keys, key)
+		}
 	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, bucket, key); err != nil && !errors.Is(err, ErrObjectNotFound) {
+			slog.Error("Failed to delete uploaded object", "bucket", bucket, "key", key, "error", err)
+		}
+	}
+}
+
+// SignedURL genera un link temporal (cfg.SignedURLTTL) para descargar un objeto
+func (s *StorageService) SignedURL(ctx context.Context, bucket, key string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.Config.SignedURLTTL)
+	signed, err := s.store.SignedURL(ctx, bucket, key, s.Config.SignedURLTTL)
+	return signed, expiresAt, err
+}
 
-	// 2. Generar nombre único
-	fileName := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
+// Open abre el objeto para transmitirlo a través de la API
+func (s *StorageService) Open(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+	return s.store.Get(ctx, bucket, key)
+}
 
-	// 3. Subir a bucket "session-evidence"
-	bucketName := "session-evidence"
-	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.Config.SupabaseURL, bucketName, fileName)
+// ResolvePatientForObject busca a qué paciente pertenece un archivo, para validar acceso:
+// - patients-consent: ConsentDocument (cualquier versión) o Patient.ConsentPDFUrl
+// - session-evidence: Session.Photos o Session.IncidentPhoto
+// Acepta filas antiguas que guardaban la URL pública completa.
+func (s *StorageService) ResolvePatientForObject(bucket, key string) (uuid.UUID, error) {
+	db := database.GetDB()
+	legacySuffix := legacyURLPattern(bucket, key)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(fileBytes))
-	if err != nil {
-		return "", err
+	switch bucket {
+	case BucketPatientsConsent:
+		var doc domains.ConsentDocument
+		err := db.Select("patient_id").Where("object_key = ?", key).First(&doc).Error
+		if err == nil {
+			return doc.PatientID, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, err
+		}
+
+		var patient domains.Patient
+		if err := db.Select("id").
+			Where(`consent_pdf_url = ? OR consent_pdf_url LIKE ? ESCAPE '\'`, key, legacySuffix).
+			First(&patient).Error; err != nil {
+			return uuid.Nil, notFoundAsObjectError(err)
+		}
+		return patient.ID, nil
+
+	case BucketSessionEvidence:
+		// Las variantes (web, thumb) pertenecen al mismo paciente que la foto original
+		key = OriginalImageKey(key)
+		legacySuffix = legacyURLPattern(bucket, key)
+
+		var session domains.Session
+		err := db.Select("id", "patient_id").
+			Where(`incident_photo = ? OR incident_photo LIKE ? ESCAPE '\' OR EXISTS (SELECT 1 FROM unnest(photos) AS photo WHERE photo = ? OR photo LIKE ? ESCAPE '\')`,
+				key, legacySuffix, key, legacySuffix).
+			First(&session).Error
+		if err == nil {
+			return session.PatientID, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, err
+		}
+
+		// Foto de un incidente reportado fuera de una sesión
+		var incident domains.Incident
+		if err := db.Select("id", "patient_id").Where("photo = ?", key).First(&incident).Error; err != nil {
+			return uuid.Nil, notFoundAsObjectError(err)
+		}
+		return incident.PatientID, nil
+
+	default:
+		return uuid.Nil, ErrUnknownBucket
 	}
+}
 
-	req.Header.Set("Authorization", "Bearer "+s.Config.SupabaseKey)
-	// Detectar content-type correcto
-	contentType := http.DetectContentType(fileBytes)
-	req.Header.Set("Content-Type", contentType)
+// legacyURLPattern patrón LIKE para URLs antiguas que terminan en /{bucket}/{key}.
+// La clave viene del cliente: se escapan los comodines para que "%" o "_" no coincidan con otros archivos.
+func legacyURLPattern(bucket, key string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%/" + escaper.Replace(bucket+"/"+key)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func notFoundAsObjectError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrObjectNotFound
+	}
+	return err
+}
+
+// ObjectKeyFromReference normaliza lo que envía el frontend a una clave de objeto.
+// Acepta la clave directa o URLs antiguas (públicas, firmadas de Supabase o del driver local).
+func ObjectKeyFromReference(bucket, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || !strings.Contains(ref, "://") {
+		return ref
+	}
+
+	parsed, err := url.Parse(ref)
 	if err != nil {
-		slog.Error("Supabase request failed", "error", err)
-		return "", err
+		return ref
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("upload failed with status: %d", resp.StatusCode)
+	marker := "/" + bucket + "/"
+	if idx := strings.Index(parsed.Path, marker); idx >= 0 {
+		key, err := url.PathUnescape(parsed.Path[idx+len(marker):])
+		if err == nil {
+			return key
+		}
 	}
+	return ref
+}
 
-	// 4. Retornar URL Pública
-	publicURL := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", s.Config.SupabaseURL, bucketName, fileName)
-	return publicURL, nil
+// ObjectKeysFromReferences aplica ObjectKeyFromReference a una lista (ej: Session.Photos)
+func ObjectKeysFromReferences(bucket string, refs []string) []string {
+	keys := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if key := ObjectKeyFromReference(bucket, ref); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
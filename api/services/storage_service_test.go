@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestLegacyURLPatternEscapesWildcards(t *testing.T) {
+	tests := map[string]string{
+		"abc_1.jpg":   `%/session-evidence/abc\_1.jpg`,
+		"%.jpg":       `%/session-evidence/\%.jpg`,
+		`a\b.jpg`:     `%/session-evidence/a\\b.jpg`,
+		"plain-1.jpg": `%/session-evidence/plain-1.jpg`,
+	}
+	for key, want := range tests {
+		if got := legacyURLPattern(BucketSessionEvidence, key); got != want {
+			t.Errorf("legacyURLPattern(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestObjectKeyFromReference(t *testing.T) {
+	tests := map[string]string{
+		"abc.jpg": "abc.jpg",
+		"https://x.supabase.co/storage/v1/object/public/session-evidence/abc.jpg":         "abc.jpg",
+		"https://x.supabase.co/storage/v1/object/sign/session-evidence/a%20b.jpg?token=t": "a b.jpg",
+		"https://example.com/other-bucket/abc.jpg":                                        "https://example.com/other-bucket/abc.jpg",
+	}
+	for ref, want := range tests {
+		if got := ObjectKeyFromReference(BucketSessionEvidence, ref); got != want {
+			t.Errorf("ObjectKeyFromReference(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
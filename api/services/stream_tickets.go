@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamTicketTTL vida de un ticket: el cliente lo pide justo antes de abrir el EventSource
+const StreamTicketTTL = 30 * time.Second
+
+// StreamTicketStore emite tickets de un solo uso para abrir el stream SSE de notificaciones.
+// EventSource no permite cabeceras, así que el ticket viaja en la URL en lugar del JWT:
+// si termina en un log de acceso ya está consumido o vencido.
+// En memoria (una sola instancia del backend, igual que el NotificationBroker).
+type StreamTicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]streamTicket
+}
+
+type streamTicket struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+func NewStreamTicketStore() *StreamTicketStore {
+	return &StreamTicketStore{tickets: make(map[string]streamTicket)}
+}
+
+// Issue genera un ticket aleatorio para el usuario
+func (s *StreamTicketStore) Issue(userID uuid.UUID) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(raw)
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Limpieza perezosa de los tickets que nunca se usaron
+	for key, t := range s.tickets {
+		if now.After(t.expiresAt) {
+			delete(s.tickets, key)
+		}
+	}
+	s.tickets[ticket] = streamTicket{userID: userID, expiresAt: now.Add(StreamTicketTTL)}
+	return ticket, nil
+}
+
+// Redeem consume el ticket: solo funciona una vez y antes de vencer
+func (s *StreamTicketStore) Redeem(ticket string) (uuid.UUID, bool) {
+	if ticket == "" {
+		return uuid.Nil, false
+	}
+
+	s.mu.Lock()
+	t, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(t.expiresAt) {
+		return uuid.Nil, false
+	}
+	return t.userID, true
+}
+
+// Store compartido por todo el proceso (igual que el broker de notificaciones)
+var streamTickets = NewStreamTicketStore()
+
+func GetStreamTickets() *StreamTicketStore {
+	return streamTickets
+}
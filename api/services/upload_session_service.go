@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrUploadSessionClosed   = errors.New("upload session is not active")
+	ErrUploadOffsetMismatch  = errors.New("chunk offset does not match received size")
+	ErrUploadIncomplete      = errors.New("upload is incomplete")
+)
+
+// UploadSessionService maneja las subidas reanudables por partes:
+// Start -> AppendChunk (n veces, en orden) -> Complete. Si se corta, el cliente consulta
+// ReceivedSize y continúa desde ese offset.
+type UploadSessionService struct {
+	Config  *config.Config
+	store   BlobStore
+	storage *StorageService
+}
+
+func NewUploadSessionService(cfg *config.Config) *UploadSessionService {
+	return &UploadSessionService{Config: cfg, store: GetBlobStore(cfg), storage: NewStorageService(cfg)}
+}
+
+// BucketForUploadKind indica dónde se guarda cada tipo de subida
+func BucketForUploadKind(kind string) string {
+	if kind == domains.UploadKindConsent {
+		return BucketPatientsConsent
+	}
+	return BucketSessionEvidence
+}
+
+// maxSize: las fotos se decodifican en memoria y mantienen su límite; PDFs y videos se transmiten
+// parte por parte al almacenamiento, así que admiten archivos mucho más grandes
+func (s *UploadSessionService) maxSize(kind string) int64 {
+	if kind == domains.UploadKindImage {
+		return s.Config.UploadMaxImageBytes
+	}
+	return s.Config.UploadMaxResumableBytes
+}
+
+// Start abre una subida por partes para un archivo de "size" bytes
+func (s *UploadSessionService) Start(owner domains.User, kind string, size int64) (domains.UploadSession, error) {
+	if size > s.maxSize(kind) {
+		return domains.UploadSession{}, ErrFileTooLarge
+	}
+
+	session := domains.UploadSession{
+		OwnerID:   owner.ID,
+		Kind:      kind,
+		TotalSize: size,
+		Status:    domains.UploadSessionActive,
+		ExpiresAt: time.Now().Add(s.Config.UploadSessionTTL),
+	}
+	err := database.GetDB().Create(&session).Error
+	return session, err
+}
+
+// Get retorna la subida (solo su dueño puede verla)
+func (s *UploadSessionService) Get(owner domains.User, id string) (domains.UploadSession, error) {
+	var session domains.UploadSession
+	if err := database.GetDB().Where("id = ? AND owner_id = ?", id, owner.ID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, ErrUploadSessionNotFound
+		}
+		return session, err
+	}
+	return session, nil
+}
+
+// AppendChunk guarda la parte que empieza en "offset" (debe ser igual a ReceivedSize).
+// La parte se transmite directo al almacenamiento como objeto temporal.
+func (s *UploadSessionService) AppendChunk(ctx context.Context, owner domains.User, id string, offset int64, r io.Reader) (domains.UploadSession, error) {
+	// 1. Validar estado y offset
+	session, err := s.Get(owner, id)
+	if err != nil {
+		return session, err
+	}
+	if session.Status != domains.UploadSessionActive || time.Now().After(session.ExpiresAt) {
+		return session, ErrUploadSessionClosed
+	}
+	if offset != session.ReceivedSize {
+		return session, ErrUploadOffsetMismatch
+	}
+
+	// 2. Subir la parte (nunca más de lo que falta ni del máximo por parte)
+	limit := min(s.Config.UploadChunkMaxBytes, session.TotalSize-session.ReceivedSize)
+	bucket := BucketForUploadKind(session.Kind)
+	chunkKey := fmt.Sprintf("uploads/%s/%012d-%s", session.ID, offset, uuid.New().String())
+
+	counter := newUploadReader(r, limit)
+	putErr := s.store.Put(ctx, bucket, chunkKey, counter, -1, "application/octet-stream")
+	if counter.err != nil || putErr != nil {
+		if putErr == nil {
+			s.store.Delete(context.Background(), bucket, chunkKey)
+		}
+		if counter.err != nil {
+			return session, counter.err
+		}
+		return session, putErr
+	}
+	if counter.n == 0 {
+		s.store.Delete(context.Background(), bucket, chunkKey)
+		return session, nil
+	}
+
+	// 3. Registrar la parte solo si nadie más avanzó el offset mientras subíamos
+	result := database.GetDB().Model(&domains.UploadSession{}).
+		Where("id = ? AND received_size = ? AND status = ?", session.ID, offset, domains.UploadSessionActive).
+		Updates(map[string]interface{}{
+			"received_size": gorm.Expr("received_size + ?", counter.n),
+			"chunk_keys":    gorm.Expr("array_append(chunk_keys, ?)", chunkKey),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		s.store.Delete(context.Background(), bucket, chunkKey)
+		if result.Error != nil {
+			return session, result.Error
+		}
+		current, _ := s.Get(owner, id)
+		return current, ErrUploadOffsetMismatch
+	}
+
+	return s.Get(owner, id)
+}
+
+// Complete concatena las partes y las procesa como una subida normal (validación, hash, variantes)
+func (s *UploadSessionService) Complete(ctx context.Context, owner domains.User, id string) (UploadedFile, error) {
+	// 1. Validar
+	session, err := s.Get(owner, id)
+	if err != nil {
+		return UploadedFile{}, err
+	}
+	if session.Status != domains.UploadSessionActive {
+		return UploadedFile{}, ErrUploadSessionClosed
+	}
+	if session.ReceivedSize != session.TotalSize {
+		return UploadedFile{}, ErrUploadIncomplete
+	}
+
+	// 2. Reclamar la subida (evita completar dos veces en paralelo). Queda PROCESSING con su propio
+	// plazo: si el proceso se cae a mitad de camino, SweepExpired la expira y elimina las partes.
+	claimed := s.update(session.ID, domains.UploadSessionActive, map[string]interface{}{
+		"status":     domains.UploadSessionProcessing,
+		"expires_at": time.Now().Add(s.Config.UploadProcessingTimeout),
+	})
+	if !claimed {
+		return UploadedFile{}, ErrUploadSessionClosed
+	}
+
+	// 3. Procesar el archivo armado (streaming: las partes se leen una tras otra)
+	bucket := BucketForUploadKind(session.Kind)
+	reader := &chunkReader{ctx: ctx, store: s.store, bucket: bucket, keys: session.ChunkKeys}
+	defer reader.Close()
+
+	var file UploadedFile
+	switch session.Kind {
+	case domains.UploadKindConsent:
+		file, err = s.storage.UploadConsentPDF(ctx, reader, s.Config.UploadMaxResumableBytes)
+	case domains.UploadKindVideo:
+		file, err = s.storage.UploadVideo(ctx, reader, s.Config.UploadMaxResumableBytes)
+	default:
+		file, err = s.storage.UploadImage(ctx, reader)
+	}
+
+	if err != nil {
+		// Contenido inválido: no tiene sentido reintentar. Error de infraestructura: se puede volver a completar.
+		if errors.Is(err, ErrUnsupportedFileType) || errors.Is(err, ErrUnsafePDF) || errors.Is(err, ErrFileTooLarge) {
+			s.transition(session.ID, domains.UploadSessionProcessing, domains.UploadSessionAborted)
+			s.deleteChunks(session)
+		} else {
+			s.update(session.ID, domains.UploadSessionProcessing, map[string]interface{}{
+				"status":     domains.UploadSessionActive,
+				"expires_at": session.ExpiresAt,
+			})
+		}
+		return UploadedFile{}, err
+	}
+
+	// 4. Cerrar la subida. Si el plazo de proceso venció y el sweeper ya eliminó las partes, el
+	// archivo armado no se entrega.
+	completed := s.update(session.ID, domains.UploadSessionProcessing, map[string]interface{}{
+		"status":     domains.UploadSessionCompleted,
+		"result_key": file.Key,
+	})
+	if !completed {
+		s.store.Delete(context.Background(), bucket, file.Key)
+		for _, key := range file.Variants {
+			s.store.Delete(context.Background(), bucket, key)
+		}
+		return UploadedFile{}, ErrUploadSessionClosed
+	}
+
+	// 5. Limpiar partes temporales
+	s.deleteChunks(session)
+	return file, nil
+}
+
+// Abort cancela la subida y elimina las partes recibidas
+func (s *UploadSessionService) Abort(owner domains.User, id string) error {
+	session, err := s.Get(owner, id)
+	if err != nil {
+		return err
+	}
+	if !s.transition(session.ID, domains.UploadSessionActive, domains.UploadSessionAborted) {
+		return ErrUploadSessionClosed
+	}
+	s.deleteChunks(session)
+	return nil
+}
+
+// SweepExpired marca como EXPIRED las subidas abandonadas (y las que quedaron a medio procesar
+// porque el proceso se cayó) y elimina sus partes
+func (s *UploadSessionService) SweepExpired(now time.Time) (int, error) {
+	var sessions []domains.UploadSession
+	if err := database.GetDB().
+		Where("status IN ? AND expires_at < ?", []domains.UploadSessionStatus{domains.UploadSessionActive, domains.UploadSessionProcessing}, now).
+		Limit(attachmentSweepBatchSize).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, session := range sessions {
+		if s.transition(session.ID, session.Status, domains.UploadSessionExpired) {
+			s.deleteChunks(session)
+			expired++
+		}
+	}
+	if expired > 0 {
+		slog.Info("Expired upload sessions cleaned", "count", expired)
+	}
+	return expired, nil
+}
+
+// transition cambia el estado solo si sigue en "from" (retorna false si otro proceso se adelantó)
+func (s *UploadSessionService) transition(id uuid.UUID, from, to domains.UploadSessionStatus) bool {
+	return s.update(id, from, map[string]interface{}{"status": to})
+}
+
+// update aplica los cambios solo si la subida sigue en "from"
+func (s *UploadSessionService) update(id uuid.UUID, from domains.UploadSessionStatus, changes map[string]interface{}) bool {
+	result := database.GetDB().Model(&domains.UploadSession{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(changes)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+func (s *UploadSessionService) deleteChunks(session domains.UploadSession) {
+	bucket := BucketForUploadKind(session.Kind)
+	for _, key := range session.ChunkKeys {
+		if err := s.store.Delete(context.Background(), bucket, key); err != nil && !errors.Is(err, ErrObjectNotFound) {
+			slog.Error("Failed to delete upload chunk", "key", key, "error", err)
+		}
+	}
+}
+
+// chunkReader lee las partes en orden como si fueran un único archivo
+type chunkReader struct {
+	ctx     context.Context
+	store   BlobStore
+	bucket  string
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			body, _, err := r.store.Get(r.ctx, r.bucket, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = body
+			r.keys = r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
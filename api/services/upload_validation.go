@@ -0,0 +1,668 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+
+	"golang.org/x/image/tiff/lzw"
+)
+
+// Errores de validación de archivos subidos (los handlers los traducen a 413/415/422)
+var (
+	ErrFileTooLarge        = errors.New("file too large")
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	ErrHEICNotSupported    = fmt.Errorf("%w: HEIC photos must be converted to JPEG before uploading", ErrUnsupportedFileType)
+	ErrUnsafePDF           = errors.New("pdf contains embedded javascript")
+	ErrUninspectablePDF    = fmt.Errorf("%w: object stream filters cannot be inspected", ErrUnsafePDF)
+)
+
+// DetectedFile es el tipo real del archivo según sus primeros bytes (no su extensión)
+type DetectedFile struct {
+	ContentType string
+	Ext         string
+}
+
+// Bytes necesarios para reconocer cualquiera de las firmas soportadas
+const sniffLength = 16
+
+var (
+	pdfMagic  = []byte("%PDF-")
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+)
+
+// Marcas ISO-BMFF ("ftyp") de HEIC/HEIF (fotos de iPhone)
+var heicBrands = map[string]bool{
+	"heic": true, "heix": true, "hevc": true, "hevx": true,
+	"heim": true, "heis": true, "mif1": true, "msf1": true,
+}
+
+// Marcas "ftyp" de videos MP4 (QuickTime usa "qt  ")
+var mp4Brands = map[string]bool{
+	"isom": true, "iso2": true, "iso4": true, "iso5": true, "iso6": true,
+	"mp41": true, "mp42": true, "avc1": true, "M4V ": true,
+}
+
+// sniffFile identifica el tipo por firma (magic bytes)
+func sniffFile(header []byte) (DetectedFile, bool) {
+	switch {
+	case bytes.HasPrefix(header, pdfMagic):
+		return DetectedFile{ContentType: "application/pdf", Ext: ".pdf"}, true
+	case bytes.HasPrefix(header, jpegMagic):
+		return DetectedFile{ContentType: "image/jpeg", Ext: ".jpg"}, true
+	case bytes.HasPrefix(header, pngMagic):
+		return DetectedFile{ContentType: "image/png", Ext: ".png"}, true
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return DetectedFile{ContentType: "image/webp", Ext: ".webp"}, true
+	case len(header) >= 12 && string(header[4:8]) == "ftyp" && heicBrands[string(header[8:12])]:
+		return DetectedFile{ContentType: "image/heic", Ext: ".heic"}, true
+	case len(header) >= 12 && string(header[4:8]) == "ftyp" && string(header[8:12]) == "qt  ":
+		return DetectedFile{ContentType: "video/quicktime", Ext: ".mov"}, true
+	case len(header) >= 12 && string(header[4:8]) == "ftyp" && mp4Brands[string(header[8:12])]:
+		return DetectedFile{ContentType: "video/mp4", Ext: ".mp4"}, true
+	}
+	return DetectedFile{}, false
+}
+
+// sniffReader identifica el tipo real leyendo solo los primeros bytes, sin consumirlos
+func sniffReader(r io.Reader, allowed map[string]bool) (io.Reader, DetectedFile, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, DetectedFile{}, err
+	}
+
+	detected, ok := sniffFile(header)
+	if !ok || !allowed[detected.ContentType] {
+		return nil, DetectedFile{}, ErrUnsupportedFileType
+	}
+	return buffered, detected, nil
+}
+
+// --- Lectura con límite y hash ---
+
+// uploadReader cuenta los bytes leídos, calcula el SHA-256 y corta con ErrFileTooLarge
+// al superar el límite. Permite transmitir el archivo al almacenamiento sin cargarlo en memoria.
+type uploadReader struct {
+	r     io.Reader
+	hash  hash.Hash
+	n     int64
+	limit int64
+	err   error // Primer error de validación (se revisa después de Put)
+}
+
+func newUploadReader(r io.Reader, limit int64) *uploadReader {
+	return &uploadReader{r: r, hash: sha256.New(), limit: limit}
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	if u.n > u.limit {
+		u.err = ErrFileTooLarge
+		return 0, u.err
+	}
+	u.hash.Write(p[:n])
+	return n, err
+}
+
+// Sum retorna el SHA-256 (hex) de lo leído hasta ahora
+func (u *uploadReader) Sum() string {
+	return hex.EncodeToString(u.hash.Sum(nil))
+}
+
+// --- Detección de JavaScript en PDF ---
+
+// Límite de bytes decodificados por stream al inspeccionar (evita "zip bombs")
+const maxInflatedStreamSize = 8 << 20
+
+// Filtros encadenados que se aceptan en un object stream (/Filter [/AHx /Fl])
+const maxPDFStreamFilters = 4
+
+// Límite de memoria del escáner: object streams comprimidos
+const maxPDFObjStmBuffer = 16 << 20
+
+// Palabras clave más largas que reconoce el escáner ("endstream")
+const maxPDFKeywordLength = 9
+
+// pdfScanner busca los nombres /JavaScript o /JS en los diccionarios del PDF y dentro de los
+// object streams (/Type /ObjStm), donde también pueden esconderse. Los object streams se decodifican
+// aplicando su cadena de /Filter; si usan un filtro que no sabemos decodificar (o un /Predictor) no se
+// pueden inspeccionar y el PDF se rechaza con ErrUninspectablePDF. Es un io.Writer
+// para inspeccionar el PDF mientras se transmite al almacenamiento. El contenido binario de los streams comunes (imágenes, fuentes)
+// no se inspecciona para evitar falsos positivos. Los nombres se decodifican (#xx) para
+// detectar ofuscaciones como /J#61vaScript.
+// Fuera de los streams se reconocen strings literales y comentarios: un "stream" dentro de un
+// string como (upstream) no abre un stream; solo cuenta la palabra clave tras el ">>" del diccionario.
+type pdfScanner struct {
+	found         bool
+	uninspectable bool // Un object stream usa filtros que no se pueden decodificar
+	names         nameScanner
+
+	// Léxico fuera de los streams
+	stringDepth int  // > 0 dentro de un string literal (los paréntesis se anidan)
+	escape      bool // El byte anterior del string fue "\"
+	inComment   bool
+	keyword     []byte // Palabra clave en curso (secuencia de caracteres regulares)
+	keywordLong bool   // La palabra en curso superó maxPDFKeywordLength
+	afterDict   bool   // Lo último fuera de espacios fue el ">>" que cierra un diccionario
+	keywordDict bool   // La palabra en curso empezó justo después de ">>"
+	pendingGT   bool   // Se vio un ">" (puede ser el primero de ">>")
+	pendingCR   bool   // "stream" seguido de "\r": falta el "\n"
+	objectStrm  bool   // El diccionario del objeto actual declara /Type /ObjStm
+
+	// Filtros del objeto actual
+	filters     []string    // Cadena de /Filter ("" si no es un nombre, p. ej. 6 0 R)
+	filterState filterState // Dónde estamos al leer el valor de /Filter
+	predictor   bool        // Declara /Predictor en sus /DecodeParms
+
+	// Contenido del stream actual
+	inStream   bool
+	recent     [9]byte // Últimos bytes del stream, para reconocer "endstream"
+	streamObjs bool    // El stream actual es un object stream: se descomprime e inspecciona
+	body       []byte
+}
+
+// filterState sigue la lectura del valor de /Filter: un nombre o un arreglo de nombres
+type filterState int
+
+const (
+	filterNone filterState = iota
+	filterValue
+	filterArray
+)
+
+func newPDFScanner() *pdfScanner {
+	return &pdfScanner{}
+}
+
+func (s *pdfScanner) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if s.found || s.uninspectable {
+			break
+		}
+		s.scanByte(b)
+	}
+	return len(p), nil
+}
+
+// Found indica si se detectó JavaScript en lo recibido hasta ahora
+func (s *pdfScanner) Found() bool {
+	if name, ok := s.names.finish(); ok {
+		s.onName(name)
+	}
+	return s.found
+}
+
+// Err retorna ErrUnsafePDF si se detectó JavaScript o ErrUninspectablePDF si un object stream
+// no se pudo decodificar para inspeccionarlo
+func (s *pdfScanner) Err() error {
+	switch {
+	case s.Found():
+		return ErrUnsafePDF
+	case s.uninspectable:
+		return ErrUninspectablePDF
+	}
+	return nil
+}
+
+func (s *pdfScanner) scanByte(b byte) {
+	switch {
+	case s.inStream:
+		s.scanStreamByte(b)
+	case s.pendingCR:
+		// "stream" debe terminar en "\r\n" o "\n"
+		s.pendingCR = false
+		if b == '\n' {
+			s.beginStream()
+			return
+		}
+		s.scanTextByte(b)
+	default:
+		s.scanTextByte(b)
+	}
+}
+
+// scanTextByte procesa un byte fuera de los streams: strings, comentarios, nombres y palabras clave
+func (s *pdfScanner) scanTextByte(b byte) {
+	// 1. Strings literales: su contenido no son nombres ni palabras clave
+	if s.stringDepth > 0 {
+		switch {
+		case s.escape:
+			s.escape = false
+		case b == '\\':
+			s.escape = true
+		case b == '(':
+			s.stringDepth++
+		case b == ')':
+			s.stringDepth--
+		}
+		return
+	}
+
+	// 2. Comentarios hasta el fin de línea
+	if s.inComment {
+		if b == '\r' || b == '\n' {
+			s.inComment = false
+		}
+		return
+	}
+
+	// 3. Nombres (/Tipo), decodificados al terminar
+	if name, ok := s.names.feed(b); ok {
+		s.onName(name)
+		if s.found {
+			return
+		}
+	}
+
+	// 4. Palabras clave: secuencias de caracteres regulares que no son nombres
+	if !isPDFDelimiter(b) {
+		if s.names.inName {
+			s.afterDict = false
+			return
+		}
+		if len(s.keyword) == 0 && !s.keywordLong {
+			s.keywordDict = s.afterDict
+			if s.filterState == filterValue {
+				// /Filter con una referencia indirecta: no sabemos qué filtro es
+				s.addFilter("")
+				s.filterState = filterNone
+			}
+		}
+		if len(s.keyword) < maxPDFKeywordLength {
+			s.keyword = append(s.keyword, b)
+		} else {
+			s.keywordLong = true
+		}
+		s.afterDict = false
+		s.pendingGT = false
+		return
+	}
+	if s.endKeyword(b) {
+		return
+	}
+
+	// 5. Delimitadores
+	switch b {
+	case '[':
+		if s.filterState == filterValue {
+			s.filterState = filterArray
+		}
+	case ']':
+		if s.filterState == filterArray {
+			s.filterState = filterNone
+		}
+	case '(':
+		s.stringDepth = 1
+	case '%':
+		s.inComment = true
+	case '>':
+		if s.pendingGT {
+			s.afterDict = true
+			s.pendingGT = false
+			return
+		}
+		s.pendingGT = true
+		s.afterDict = false
+		return
+	}
+	s.pendingGT = false
+	if !isPDFWhitespace(b) {
+		s.afterDict = false
+	}
+}
+
+// endKeyword cierra la palabra clave en curso. Retorna true si con ella empieza un stream.
+func (s *pdfScanner) endKeyword(delimiter byte) bool {
+	keyword := string(s.keyword)
+	long, afterDict := s.keywordLong, s.keywordDict
+	s.keyword = s.keyword[:0]
+	s.keywordLong = false
+	if long || keyword == "" {
+		return false
+	}
+
+	switch keyword {
+	case "obj", "endobj":
+		s.resetObject()
+	case "stream":
+		// Solo es el inicio de un stream si sigue al diccionario y termina la línea
+		if !afterDict {
+			return false
+		}
+		switch delimiter {
+		case '\n':
+			s.beginStream()
+			return true
+		case '\r':
+			s.pendingCR = true
+			return true
+		}
+	}
+	return false
+}
+
+// onName revisa un nombre completo (ya decodificado)
+func (s *pdfScanner) onName(name string) {
+	if isPDFJavaScriptName(name) {
+		s.found = true
+		return
+	}
+
+	// Valor de /Filter: un nombre suelto o cada nombre del arreglo
+	switch s.filterState {
+	case filterValue:
+		s.addFilter(name)
+		s.filterState = filterNone
+		return
+	case filterArray:
+		s.addFilter(name)
+		return
+	}
+
+	switch name {
+	case "ObjStm":
+		s.objectStrm = true
+	case "Filter":
+		s.filters = s.filters[:0]
+		s.filterState = filterValue
+	case "Predictor":
+		s.predictor = true
+	}
+}
+
+// addFilter guarda un filtro de la cadena (más allá del máximo solo importa que se excedió)
+func (s *pdfScanner) addFilter(name string) {
+	if len(s.filters) <= maxPDFStreamFilters {
+		s.filters = append(s.filters, name)
+	}
+}
+
+// resetObject olvida lo leído del diccionario del objeto actual
+func (s *pdfScanner) resetObject() {
+	s.objectStrm = false
+	s.filters = s.filters[:0]
+	s.filterState = filterNone
+	s.predictor = false
+}
+
+func (s *pdfScanner) beginStream() {
+	s.filterState = filterNone
+	s.inStream = true
+	s.streamObjs = s.objectStrm
+	s.recent = [9]byte{}
+	s.body = s.body[:0]
+}
+
+func (s *pdfScanner) scanStreamByte(b byte) {
+	copy(s.recent[:], s.recent[1:])
+	s.recent[len(s.recent)-1] = b
+
+	if s.streamObjs && len(s.body) < maxPDFObjStmBuffer {
+		s.body = append(s.body, b)
+	}
+
+	if !bytes.HasSuffix(s.recent[:], []byte("endstream")) {
+		return
+	}
+
+	// Fin del stream: inspeccionar el object stream decodificado (o tal cual si no tiene filtro)
+	if s.streamObjs {
+		body := bytes.TrimSuffix(s.body, []byte("endstream"))
+		decoded, err := decodePDFStream(body, s.filters)
+		if err != nil || s.predictor || len(s.body) >= maxPDFObjStmBuffer {
+			s.uninspectable = true
+		}
+		body = decoded
+		var names nameScanner
+		for _, c := range body {
+			if name, ok := names.feed(c); ok && isPDFJavaScriptName(name) {
+				s.found = true
+				break
+			}
+		}
+		if name, ok := names.finish(); ok && isPDFJavaScriptName(name) {
+			s.found = true
+		}
+	}
+
+	s.inStream = false
+	s.streamObjs = false
+	s.resetObject()
+	s.body = s.body[:0]
+}
+
+func isPDFJavaScriptName(name string) bool {
+	return name == "JavaScript" || name == "JS"
+}
+
+// nameScanner reconoce nombres PDF (/Nombre) byte a byte
+type nameScanner struct {
+	inName   bool
+	name     []byte
+	overflow bool
+}
+
+// Los nombres relevantes son cortos: no hace falta guardar nombres largos completos
+const maxPDFNameLength = 64
+
+// feed procesa un byte; al terminar un nombre retorna su forma decodificada
+func (n *nameScanner) feed(b byte) (string, bool) {
+	if !n.inName {
+		if b == '/' {
+			n.start()
+		}
+		return "", false
+	}
+
+	if isPDFDelimiter(b) {
+		name, ok := n.finish()
+		if b == '/' {
+			n.start()
+		}
+		return name, ok
+	}
+
+	if len(n.name) < maxPDFNameLength {
+		n.name = append(n.name, b)
+	} else {
+		n.overflow = true
+	}
+	return "", false
+}
+
+// finish cierra el nombre en curso (si hay) y retorna su forma decodificada
+func (n *nameScanner) finish() (string, bool) {
+	if !n.inName {
+		return "", false
+	}
+	n.inName = false
+	if n.overflow {
+		return "", false
+	}
+	return decodePDFName(n.name), true
+}
+
+func (n *nameScanner) start() {
+	n.inName = true
+	n.overflow = false
+	n.name = n.name[:0]
+}
+
+var (
+	errUnsupportedPDFFilter = errors.New("unsupported pdf stream filter")
+	errPDFStreamTooLarge    = errors.New("decoded pdf stream too large")
+)
+
+// decodePDFStream aplica la cadena de filtros de un stream en orden. Un filtro desconocido, un
+// error al decodificar o un resultado mayor a maxInflatedStreamSize impiden inspeccionarlo.
+func decodePDFStream(body []byte, filters []string) ([]byte, error) {
+	if len(filters) > maxPDFStreamFilters {
+		return nil, errUnsupportedPDFFilter
+	}
+
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			body, err = inflate(body)
+		case "ASCIIHexDecode", "AHx":
+			body, err = decodeASCIIHex(body)
+		case "ASCII85Decode", "A85":
+			body, err = decodeASCII85(body)
+		case "LZWDecode", "LZW":
+			// PDF usa por defecto EarlyChange 1, igual que el LZW de TIFF
+			reader := lzw.NewReader(bytes.NewReader(body), lzw.MSB, 8)
+			body, err = readLimited(reader)
+			reader.Close()
+		case "RunLengthDecode", "RL":
+			body, err = decodeRunLength(body)
+		default:
+			return nil, errUnsupportedPDFFilter
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func inflate(compressed []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readLimited(reader)
+}
+
+// readLimited lee todo el stream decodificado sin superar maxInflatedStreamSize
+func readLimited(r io.Reader) ([]byte, error) {
+	decoded, err := io.ReadAll(io.LimitReader(r, maxInflatedStreamSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxInflatedStreamSize {
+		return nil, errPDFStreamTooLarge
+	}
+	return decoded, nil
+}
+
+// decodeASCIIHex decodifica /ASCIIHexDecode: pares hex hasta ">", ignorando espacios
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	digits := make([]byte, 0, len(data))
+	for _, b := range data {
+		if b == '>' {
+			break
+		}
+		if isPDFWhitespace(b) {
+			continue
+		}
+		digits = append(digits, b)
+	}
+	// Un dígito final sin pareja vale como si lo siguiera un 0
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	decoded := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(decoded, digits); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// decodeASCII85 decodifica /ASCII85Decode: hasta "~>", ignorando espacios y el "<~" opcional
+func decodeASCII85(data []byte) ([]byte, error) {
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	encoded := make([]byte, 0, len(data))
+	for _, b := range data {
+		if !isPDFWhitespace(b) {
+			encoded = append(encoded, b)
+		}
+	}
+	encoded = bytes.TrimPrefix(encoded, []byte("<~"))
+
+	return readLimited(ascii85.NewDecoder(bytes.NewReader(encoded)))
+}
+
+// decodeRunLength decodifica /RunLengthDecode: un byte de largo seguido de literales o de un byte a repetir
+func decodeRunLength(data []byte) ([]byte, error) {
+	var decoded []byte
+	for i := 0; i < len(data); {
+		length := int(data[i])
+		i++
+		switch {
+		case length == 128:
+			return decoded, nil
+		case length < 128:
+			if i+length+1 > len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			decoded = append(decoded, data[i:i+length+1]...)
+			i += length + 1
+		default:
+			if i >= len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			decoded = append(decoded, bytes.Repeat([]byte{data[i]}, 257-length)...)
+			i++
+		}
+		if len(decoded) > maxInflatedStreamSize {
+			return nil, errPDFStreamTooLarge
+		}
+	}
+	return decoded, nil
+}
+
+func isPDFWhitespace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// decodePDFName resuelve los escapes #xx de un nombre PDF
+func decodePDFName(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+
+	var out []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if value, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(value))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, raw[i])
+	}
+	return string(out)
+}
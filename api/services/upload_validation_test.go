@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bytes"
+	"compress/lzw"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func deflate(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestPDFScannerDetectsJavaScript(t *testing.T) {
+	objStm := func(filter, content string) string {
+		return "%PDF-1.7\n5 0 obj\n<< /Type /ObjStm /N 1 /First 4" + filter + " >>\nstream\n" + content + "\nendstream\nendobj\n"
+	}
+
+	tests := []struct {
+		name   string
+		pdf    string
+		unsafe bool
+	}{
+		{
+			name: "plain document",
+			pdf:  "%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF",
+		},
+		{
+			name:   "JavaScript action",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /S /JavaScript /JS (app.alert(1)) >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "JS key only",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /JS 4 0 R >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "hex-escaped JavaScript name",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /S /J#61va#53cript >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "hex-escaped JS name",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /#4A#53 4 0 R >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "name at end of file",
+			pdf:    "%PDF-1.7\n<< /JS",
+			unsafe: true,
+		},
+		{
+			name:   "stream keyword inside a string does not hide the action",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /T (upstream) /S /JavaScript /JS (app.alert(1)) >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "bare stream string does not hide the action",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /T (stream)\n/S /JavaScript >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "escaped and nested parentheses in strings",
+			pdf:    "%PDF-1.7\n1 0 obj\n<< /T (a \\) (b) stream) /JS 4 0 R >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "stream keyword not after a dictionary",
+			pdf:    "%PDF-1.7\n1 0 obj\n[ 1 2 ] stream\n<< /JS 4 0 R >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "stream keyword in a comment",
+			pdf:    "%PDF-1.7\n% >> stream\n1 0 obj\n<< /JS 4 0 R >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name: "names inside strings and comments are text",
+			pdf:  "%PDF-1.7\n% /JavaScript\n1 0 obj\n<< /Title (see /JavaScript docs) >>\nendobj\n",
+		},
+		{
+			name: "content stream is not inspected",
+			pdf:  "%PDF-1.7\n4 0 obj\n<< /Length 24 >>\nstream\nBT (/JavaScript) Tj ET /JS\nendstream\nendobj\n",
+		},
+		{
+			name: "stream with CRLF end of line",
+			pdf:  "%PDF-1.7\n4 0 obj\n<< /Length 3 >>\r\nstream\r\n/JS\r\nendstream\nendobj\n",
+		},
+		{
+			name:   "action after a content stream",
+			pdf:    "%PDF-1.7\n4 0 obj\n<< /Length 3 >>\nstream\nabc\nendstream\nendobj\n5 0 obj\n<< /S /JavaScript >>\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "compressed object stream",
+			pdf:    objStm(" /Filter /FlateDecode", deflate(t, "7 0 << /S /JavaScript /JS (x) >>")),
+			unsafe: true,
+		},
+		{
+			name:   "compressed object stream with escaped names",
+			pdf:    objStm(" /Filter /FlateDecode", deflate(t, "7 0 << /S /J#61vaScript >>")),
+			unsafe: true,
+		},
+		{
+			name:   "object stream with escaped type",
+			pdf:    "%PDF-1.7\n5 0 obj\n<< /Type /Obj#53tm /N 1 /First 4 /Filter /FlateDecode >>\nstream\n" + deflate(t, "7 0 << /JS 4 0 R >>") + "\nendstream\nendobj\n",
+			unsafe: true,
+		},
+		{
+			name:   "uncompressed object stream",
+			pdf:    objStm("", "7 0 << /JS 4 0 R >>"),
+			unsafe: true,
+		},
+		{
+			name: "safe compressed object stream",
+			pdf:  objStm(" /Filter /FlateDecode", deflate(t, "7 0 << /Type /Page /Parent 2 0 R >>")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Completo y byte a byte (el PDF llega en partes mientras se sube)
+			whole := newPDFScanner()
+			whole.Write([]byte(tt.pdf))
+			if got := whole.Err() != nil; got != tt.unsafe {
+				t.Errorf("unsafe = %v, want %v", got, tt.unsafe)
+			}
+
+			chunked := newPDFScanner()
+			for i := 0; i < len(tt.pdf); i++ {
+				chunked.Write([]byte{tt.pdf[i]})
+			}
+			if got := chunked.Err() != nil; got != tt.unsafe {
+				t.Errorf("unsafe byte by byte = %v, want %v", got, tt.unsafe)
+			}
+		})
+	}
+}
+
+func TestPDFScannerObjectStreamFilters(t *testing.T) {
+	const unsafe = "7 0 << /S /JavaScript /JS (x) >>"
+	const safe = "7 0 << /Type /Page /Parent 2 0 R >>"
+
+	asciiHex := func(data string) string {
+		return strings.ToUpper(hex.EncodeToString([]byte(data))) + ">"
+	}
+	ascii85Encode := func(data string) string {
+		encoded := make([]byte, ascii85.MaxEncodedLen(len(data)))
+		return "<~" + string(encoded[:ascii85.Encode(encoded, []byte(data))]) + "~>"
+	}
+	lzwEncode := func(data string) string {
+		var buf bytes.Buffer
+		w := lzw.NewWriter(&buf, lzw.MSB, 8)
+		w.Write([]byte(data))
+		w.Close()
+		return buf.String()
+	}
+	// Un literal de n bytes se codifica con el largo n-1 y se termina con 128
+	runLength := func(data string) string {
+		return string([]byte{byte(len(data) - 1)}) + data + "\x80"
+	}
+	objStm := func(dict, content string) string {
+		return "%PDF-1.7\n5 0 obj\n<< /Type /ObjStm /N 1 /First 4 " + dict + " >>\nstream\n" + content + "\nendstream\nendobj\n"
+	}
+
+	tests := []struct {
+		name string
+		pdf  string
+		want error
+	}{
+		{"ASCIIHexDecode", objStm("/Filter /ASCIIHexDecode", asciiHex(unsafe)), ErrUnsafePDF},
+		{"ASCIIHexDecode abbreviation", objStm("/Filter /AHx", asciiHex(unsafe)), ErrUnsafePDF},
+		{"safe ASCIIHexDecode", objStm("/Filter /ASCIIHexDecode", asciiHex(safe)), nil},
+		{"ASCII85Decode", objStm("/Filter /ASCII85Decode", ascii85Encode(unsafe)), ErrUnsafePDF},
+		{"safe ASCII85Decode", objStm("/Filter /A85", ascii85Encode(safe)), nil},
+		{"LZWDecode", objStm("/Filter /LZWDecode", lzwEncode(unsafe)), ErrUnsafePDF},
+		{"safe LZWDecode", objStm("/Filter /LZW", lzwEncode(safe)), nil},
+		{"RunLengthDecode", objStm("/Filter /RunLengthDecode", runLength(unsafe)), ErrUnsafePDF},
+		{"safe RunLengthDecode", objStm("/Filter /RL", runLength(safe)), nil},
+		{"filter chain", objStm("/Filter [/ASCIIHexDecode /FlateDecode]", asciiHex(deflate(t, unsafe))), ErrUnsafePDF},
+		{"filter chain without spaces", objStm("/Filter[/A85/Fl]", ascii85Encode(deflate(t, unsafe))), ErrUnsafePDF},
+		{"safe filter chain", objStm("/Filter [/AHx /Fl]", asciiHex(deflate(t, safe))), nil},
+		{"filter before the type", "%PDF-1.7\n5 0 obj\n<< /Filter /AHx /Type /ObjStm >>\nstream\n" + asciiHex(unsafe) + "\nendstream\nendobj\n", ErrUnsafePDF},
+		{"unsupported filter", objStm("/Filter /DCTDecode", safe), ErrUninspectablePDF},
+		{"unsupported filter in a chain", objStm("/Filter [/FlateDecode /JBIG2Decode]", deflate(t, safe)), ErrUninspectablePDF},
+		{"indirect filter", objStm("/Filter 6 0 R", deflate(t, safe)), ErrUninspectablePDF},
+		{"predictor", objStm("/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4 >>", deflate(t, safe)), ErrUninspectablePDF},
+		{"undecodable data", objStm("/Filter /FlateDecode", "not zlib"), ErrUninspectablePDF},
+		{"filters of other streams are ignored", "%PDF-1.7\n4 0 obj\n<< /Filter /DCTDecode /Length 3 >>\nstream\nabc\nendstream\nendobj\n" + objStm("/Filter /AHx", asciiHex(safe)), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whole := newPDFScanner()
+			whole.Write([]byte(tt.pdf))
+			if err := whole.Err(); err != tt.want {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+
+			chunked := newPDFScanner()
+			for i := 0; i < len(tt.pdf); i++ {
+				chunked.Write([]byte{tt.pdf[i]})
+			}
+			if err := chunked.Err(); err != tt.want {
+				t.Errorf("Err() byte by byte = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePDFName(t *testing.T) {
+	tests := map[string]string{
+		"JavaScript":   "JavaScript",
+		"J#61vaScript": "JavaScript",
+		"#4A#53":       "JS",
+		"A#zz":         "A#zz",
+		"A#4":          "A#4",
+	}
+	for raw, want := range tests {
+		if got := decodePDFName([]byte(raw)); got != want {
+			t.Errorf("decodePDFName(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
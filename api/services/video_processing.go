@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Los videos (MP4/MOV) no se re-codifican: se copian tal cual salvo las cajas de metadatos
+// ("udta", "meta", "uuid"), donde los teléfonos guardan la ubicación GPS y el modelo del equipo.
+// Esas cajas se convierten en "free" del mismo tamaño con el contenido en cero, así los offsets
+// del archivo (tablas stco/co64) siguen siendo válidos y no hace falta reescribir el índice.
+
+// La caja "moov" (índice del video) se procesa en memoria; todo lo demás se transmite
+const videoMaxMoovSize = 64 << 20
+
+var (
+	mp4MetadataBoxes  = map[string]bool{"udta": true, "meta": true, "uuid": true}
+	mp4ContainerBoxes = map[string]bool{"trak": true, "mdia": true, "minf": true}
+)
+
+// stripMP4Metadata copia el video de src a dst anulando sus cajas de metadatos
+func stripMP4Metadata(dst io.Writer, src io.Reader) error {
+	for {
+		// 1. Leer el encabezado de la caja de primer nivel
+		header := make([]byte, 8, 16)
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return truncatedVideo(err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+		if size == 1 {
+			header = header[:16]
+			if _, err := io.ReadFull(src, header[8:16]); err != nil {
+				return truncatedVideo(err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+
+		// size 0: la caja llega hasta el final del archivo
+		payload := int64(-1)
+		if size != 0 {
+			payload = size - int64(len(header))
+			if payload < 0 {
+				return ErrUnsupportedFileType
+			}
+		}
+
+		// 2. Copiar la caja (anulada si es de metadatos)
+		var err error
+		switch {
+		case boxType == "moov":
+			err = copyMoovBox(dst, src, header, payload)
+		case mp4MetadataBoxes[boxType]:
+			copy(header[4:8], "free")
+			if _, err = dst.Write(header); err == nil {
+				err = copyBoxPayload(zeroWriter{dst}, src, payload)
+			}
+		default:
+			if _, err = dst.Write(header); err == nil {
+				err = copyBoxPayload(dst, src, payload)
+			}
+		}
+		if err != nil || payload < 0 {
+			return err
+		}
+	}
+}
+
+// copyMoovBox lee el índice completo, anula los metadatos de la película y de cada pista y lo escribe
+func copyMoovBox(dst io.Writer, src io.Reader, header []byte, payload int64) error {
+	if payload < 0 || payload > videoMaxMoovSize {
+		return ErrFileTooLarge
+	}
+	data := make([]byte, payload)
+	if _, err := io.ReadFull(src, data); err != nil {
+		return truncatedVideo(err)
+	}
+	if err := blankMP4Metadata(data); err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	_, err := dst.Write(data)
+	return err
+}
+
+// blankMP4Metadata recorre las cajas hijas (en el lugar) entrando a las pistas
+func blankMP4Metadata(data []byte) error {
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return ErrUnsupportedFileType
+		}
+		size := int64(binary.BigEndian.Uint32(data[0:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			size = int64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return ErrUnsupportedFileType
+			}
+			size = int64(binary.BigEndian.Uint64(data[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || size > int64(len(data)) {
+			return ErrUnsupportedFileType
+		}
+
+		box := data[:size]
+		boxType := string(box[4:8])
+		switch {
+		case mp4MetadataBoxes[boxType]:
+			copy(box[4:8], "free")
+			clear(box[headerLen:])
+		case mp4ContainerBoxes[boxType]:
+			if err := blankMP4Metadata(box[headerLen:]); err != nil {
+				return err
+			}
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// copyBoxPayload copia n bytes (o hasta el final si n < 0)
+func copyBoxPayload(dst io.Writer, src io.Reader, n int64) error {
+	if n < 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	_, err := io.CopyN(dst, src, n)
+	return truncatedVideo(err)
+}
+
+// truncatedVideo: un archivo que termina a mitad de una caja no es un video válido
+func truncatedVideo(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrUnsupportedFileType
+	}
+	return err
+}
+
+// zeroWriter escribe ceros en lugar de lo recibido (mismo largo)
+type zeroWriter struct {
+	w io.Writer
+}
+
+func (z zeroWriter) Write(p []byte) (int, error) {
+	clear(p)
+	return z.w.Write(p)
+}
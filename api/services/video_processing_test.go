@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"bitacora-medica-backend/api/config"
+)
+
+func mp4Box(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(box, boxType...), payload...)
+}
+
+func mp4LargeBox(boxType string, payload []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, 1)
+	box = append(box, boxType...)
+	box = binary.BigEndian.AppendUint64(box, uint64(16+len(payload)))
+	return append(box, payload...)
+}
+
+// sampleMP4 arma un video mínimo con ubicación GPS en la película, en una pista y en una caja uuid (XMP)
+func sampleMP4() []byte {
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41")),
+		mp4Box("moov",
+			mp4Box("mvhd", make([]byte, 20)),
+			mp4Box("udta", mp4Box("\xa9xyz", []byte("+33.4489-070.6693/"))),
+			mp4Box("trak",
+				mp4Box("tkhd", make([]byte, 20)),
+				mp4Box("mdia", mp4Box("minf", mp4Box("stbl", []byte("sample-table")), mp4Box("meta", []byte("iPhone 15 Pro")))),
+			),
+		),
+		mp4Box("uuid", []byte("xmp:GPSLatitude=33,26.93S")),
+		mp4LargeBox("mdat", []byte("frame-data")),
+	}, nil)
+}
+
+func TestStripMP4Metadata(t *testing.T) {
+	original := sampleMP4()
+
+	var out bytes.Buffer
+	if err := stripMP4Metadata(&out, bytes.NewReader(original)); err != nil {
+		t.Fatal(err)
+	}
+	stripped := out.Bytes()
+
+	// Mismo largo: los offsets hacia mdat no cambian
+	if len(stripped) != len(original) {
+		t.Fatalf("len = %d, want %d", len(stripped), len(original))
+	}
+	for _, secret := range []string{"+33.4489", "iPhone", "GPSLatitude", "udta", "meta", "uuid"} {
+		if bytes.Contains(stripped, []byte(secret)) {
+			t.Errorf("stripped video still contains %q", secret)
+		}
+	}
+	for _, kept := range []string{"ftyp", "mvhd", "tkhd", "sample-table", "frame-data"} {
+		if !bytes.Contains(stripped, []byte(kept)) {
+			t.Errorf("stripped video lost %q", kept)
+		}
+	}
+	if got := bytes.Count(stripped, []byte("free")); got != 3 {
+		t.Errorf("got %d free boxes, want 3", got)
+	}
+}
+
+func TestStripMP4MetadataRejectsMalformedFiles(t *testing.T) {
+	video := sampleMP4()
+	corruptMoov := bytes.Clone(video)
+	moov := bytes.Index(corruptMoov, []byte("moov")) + 4
+	binary.BigEndian.PutUint32(corruptMoov[moov:], 0xFFFF) // Tamaño de mvhd mayor que moov
+
+	tests := map[string][]byte{
+		"truncated box":      video[:len(video)-3],
+		"truncated header":   append(bytes.Clone(video), 0, 0, 0),
+		"child out of range": corruptMoov,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := stripMP4Metadata(&out, bytes.NewReader(data)); !errors.Is(err, ErrUnsupportedFileType) {
+				t.Errorf("err = %v, want ErrUnsupportedFileType", err)
+			}
+		})
+	}
+}
+
+func TestUploadVideo(t *testing.T) {
+	store := NewMemoryBlobStore()
+	storage := &StorageService{Config: &config.Config{}, store: store}
+	video := sampleMP4()
+
+	file, err := storage.UploadVideo(context.Background(), bytes.NewReader(video), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.ContentType != "video/mp4" || file.Size != int64(len(video)) {
+		t.Errorf("file = %+v", file)
+	}
+	body, _, err := store.Get(context.Background(), BucketSessionEvidence, file.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	saved.ReadFrom(body)
+	if bytes.Contains(saved.Bytes(), []byte("+33.4489")) {
+		t.Error("stored video still contains the GPS location")
+	}
+
+	// Sobre el límite o mal formado: no queda nada en el bucket
+	if _, err := storage.UploadVideo(context.Background(), bytes.NewReader(video), 64); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("over limit: err = %v, want ErrFileTooLarge", err)
+	}
+	if _, err := storage.UploadVideo(context.Background(), bytes.NewReader(video[:len(video)-3]), 1<<20); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("truncated: err = %v, want ErrUnsupportedFileType", err)
+	}
+	if len(store.objects) != 1 {
+		t.Errorf("bucket has %d objects, want only the first upload", len(store.objects))
+	}
+}
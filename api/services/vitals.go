@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+
+	"gorm.io/datatypes"
+)
+
+var ErrInvalidVitals = errors.New("invalid vitals")
+
+// Dirección de un valor fuera de rango
+const (
+	VitalHigh = "HIGH"
+	VitalLow  = "LOW"
+)
+
+// Claves alternativas aceptadas en el input (clientes antiguos guardaban nombres libres)
+var vitalAliases = map[string]string{
+	"hr":                  domains.VitalHeartRate,
+	"pulse":               domains.VitalHeartRate,
+	"heartrate":           domains.VitalHeartRate,
+	"systolic":            domains.VitalSystolicBP,
+	"diastolic":           domains.VitalDiastolicBP,
+	"oxygen_saturation":   domains.VitalSpO2,
+	"saturation":          domains.VitalSpO2,
+	"temp":                domains.VitalTemperature,
+	"rr":                  domains.VitalRespiratoryRate,
+	"respiration":         domains.VitalRespiratoryRate,
+	"pain":                domains.VitalPainScale,
+	"eva":                 domains.VitalPainScale,
+	"blood_pressure":      vitalBloodPressure,
+	"bp":                  vitalBloodPressure,
+	"presion_arterial":    vitalBloodPressure,
+	"frecuencia_cardiaca": domains.VitalHeartRate,
+	"temperatura":         domains.VitalTemperature,
+	"peso":                domains.VitalWeight,
+}
+
+// Clave de entrada combinada "120/80" (se separa en sistólica/diastólica)
+const vitalBloodPressure = "blood_pressure"
+
+// Rangos físicamente posibles (en unidad canónica): fuera de ellos el dato es un error de tipeo
+var vitalLimits = map[string]config.VitalRange{
+	domains.VitalHeartRate:       {Min: 20, Max: 300},
+	domains.VitalSystolicBP:      {Min: 40, Max: 300},
+	domains.VitalDiastolicBP:     {Min: 20, Max: 200},
+	domains.VitalSpO2:            {Min: 50, Max: 100},
+	domains.VitalTemperature:     {Min: 25, Max: 45},
+	domains.VitalRespiratoryRate: {Min: 4, Max: 80},
+	domains.VitalPainScale:       {Min: 0, Max: 10},
+	domains.VitalWeight:          {Min: 0.3, Max: 500},
+}
+
+// VitalAlert describe un signo vital fuera del rango configurado
+type VitalAlert struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Unit      string  `json:"unit"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Direction string  `json:"direction"`
+}
+
+// NormalizeVitals valida el input libre de la sesión y lo convierte al modelo tipado.
+// Cada signo acepta un número (unidad canónica), un texto numérico o {"value": 98.6, "unit": "F"}.
+// Las claves no reconocidas se conservan tal cual en Extra.
+func NormalizeVitals(input map[string]interface{}) (domains.Vitals, error) {
+	var vitals domains.Vitals
+
+	for key, raw := range input {
+		name := strings.ToLower(strings.TrimSpace(key))
+		if alias, ok := vitalAliases[name]; ok {
+			name = alias
+		}
+
+		if name == vitalBloodPressure {
+			if err := setBloodPressure(&vitals, raw); err != nil {
+				return vitals, err
+			}
+			continue
+		}
+
+		if _, known := domains.VitalUnits[name]; !known {
+			if vitals.Extra == nil {
+				vitals.Extra = make(map[string]interface{})
+			}
+			vitals.Extra[key] = raw
+			continue
+		}
+
+		if raw == nil {
+			continue
+		}
+		value, err := parseVitalValue(name, raw)
+		if err != nil {
+			return vitals, err
+		}
+		vitals.Set(name, &value)
+	}
+
+	return vitals, validateVitals(vitals)
+}
+
+// ParseSessionVitals lee el JSONB guardado en Session.Vitals (vacío si no hay datos)
+func ParseSessionVitals(raw datatypes.JSON) domains.Vitals {
+	var vitals domains.Vitals
+	if len(raw) == 0 || string(raw) == "null" {
+		return vitals
+	}
+	if err := json.Unmarshal(raw, &vitals); err != nil {
+		return domains.Vitals{}
+	}
+	return vitals
+}
+
+// AbnormalVitals devuelve los signos fuera de los umbrales configurados
+func AbnormalVitals(vitals domains.Vitals, thresholds map[string]config.VitalRange) []VitalAlert {
+	var alerts []VitalAlert
+	for _, name := range domains.VitalNames {
+		value := vitals.Get(name)
+		limits, ok := thresholds[name]
+		if value == nil || !ok {
+			continue
+		}
+
+		direction := ""
+		switch {
+		case *value < limits.Min:
+			direction = VitalLow
+		case *value > limits.Max:
+			direction = VitalHigh
+		default:
+			continue
+		}
+		alerts = append(alerts, VitalAlert{
+			Name: name, Value: *value, Unit: domains.VitalUnits[name],
+			Min: limits.Min, Max: limits.Max, Direction: direction,
+		})
+	}
+	return alerts
+}
+
+// NewVitalAlerts devuelve solo las alertas que no existían antes (en una edición, no se vuelve a
+// alertar por un valor anormal que ya estaba registrado y no cambió)
+func NewVitalAlerts(before, after domains.Vitals, thresholds map[string]config.VitalRange) []VitalAlert {
+	var alerts []VitalAlert
+	for _, alert := range AbnormalVitals(after, thresholds) {
+		if previous := before.Get(alert.Name); previous != nil && *previous == alert.Value {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+func parseVitalValue(name string, raw interface{}) (float64, error) {
+	var value float64
+	unit := ""
+
+	switch v := raw.(type) {
+	case float64:
+		value = v
+	case string:
+		number, suffix := splitNumberUnit(v)
+		parsed, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s must be a number", ErrInvalidVitals, name)
+		}
+		value, unit = parsed, suffix
+	case map[string]interface{}:
+		number, ok := v["value"].(float64)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s.value must be a number", ErrInvalidVitals, name)
+		}
+		value = number
+		unit, _ = v["unit"].(string)
+	default:
+		return 0, fmt.Errorf("%w: %s must be a number", ErrInvalidVitals, name)
+	}
+
+	value, err := toCanonicalUnit(name, value, unit)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(value*100) / 100, nil
+}
+
+// toCanonicalUnit convierte desde la unidad indicada (vacía = canónica)
+func toCanonicalUnit(name string, value float64, unit string) (float64, error) {
+	unit = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(unit), "°")))
+	if unit == "" || unit == strings.ToLower(domains.VitalUnits[name]) {
+		return value, nil
+	}
+
+	switch name {
+	case domains.VitalTemperature:
+		switch unit {
+		case "c", "celsius":
+			return value, nil
+		case "f", "fahrenheit":
+			return (value - 32) * 5 / 9, nil
+		}
+	case domains.VitalWeight:
+		switch unit {
+		case "kg", "kgs":
+			return value, nil
+		case "g":
+			return value / 1000, nil
+		case "lb", "lbs":
+			return value * 0.45359237, nil
+		}
+	case domains.VitalHeartRate:
+		if unit == "lpm" || unit == "/min" {
+			return value, nil
+		}
+	case domains.VitalRespiratoryRate:
+		if unit == "/min" || unit == "bpm" {
+			return value, nil
+		}
+	case domains.VitalSystolicBP, domains.VitalDiastolicBP:
+		if unit == "mmhg" {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unsupported unit %q for %s", ErrInvalidVitals, unit, name)
+}
+
+// setBloodPressure acepta "120/80" o {"systolic": 120, "diastolic": 80}
+func setBloodPressure(vitals *domains.Vitals, raw interface{}) error {
+	switch v := raw.(type) {
+	case string:
+		number, unit := splitNumberUnit(strings.ReplaceAll(v, " ", ""))
+		parts := strings.Split(number, "/")
+		if len(parts) != 2 {
+			return fmt.Errorf("%w: blood_pressure must look like 120/80", ErrInvalidVitals)
+		}
+		systolic, err := parseVitalValue(domains.VitalSystolicBP, parts[0]+unit)
+		if err != nil {
+			return err
+		}
+		diastolic, err := parseVitalValue(domains.VitalDiastolicBP, parts[1]+unit)
+		if err != nil {
+			return err
+		}
+		vitals.SystolicBP, vitals.DiastolicBP = &systolic, &diastolic
+	case map[string]interface{}:
+		for key, name := range map[string]string{"systolic": domains.VitalSystolicBP, "diastolic": domains.VitalDiastolicBP} {
+			if raw, ok := v[key]; ok && raw != nil {
+				value, err := parseVitalValue(name, raw)
+				if err != nil {
+					return err
+				}
+				vitals.Set(name, &value)
+			}
+		}
+	case nil:
+	default:
+		return fmt.Errorf("%w: blood_pressure must look like 120/80", ErrInvalidVitals)
+	}
+	return nil
+}
+
+// splitNumberUnit separa "98.6 F" en ("98.6", "F")
+func splitNumberUnit(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '.' && r != '-' && r != '/' && r != ','
+	})
+	if end == -1 {
+		return strings.ReplaceAll(text, ",", "."), ""
+	}
+	return strings.ReplaceAll(strings.TrimSpace(text[:end]), ",", "."), strings.TrimSpace(text[end:])
+}
+
+// validateVitals revisa rangos posibles y coherencia entre signos
+func validateVitals(vitals domains.Vitals) error {
+	for _, name := range domains.VitalNames {
+		value := vitals.Get(name)
+		limits, ok := vitalLimits[name]
+		if value == nil || !ok {
+			continue
+		}
+		if *value < limits.Min || *value > limits.Max {
+			return fmt.Errorf("%w: %s must be between %g and %g %s", ErrInvalidVitals, name, limits.Min, limits.Max, domains.VitalUnits[name])
+		}
+	}
+
+	if vitals.PainScale != nil && *vitals.PainScale != math.Trunc(*vitals.PainScale) {
+		return fmt.Errorf("%w: pain_scale must be a whole number", ErrInvalidVitals)
+	}
+	if vitals.SystolicBP != nil && vitals.DiastolicBP != nil && *vitals.SystolicBP <= *vitals.DiastolicBP {
+		return fmt.Errorf("%w: systolic_bp must be greater than diastolic_bp", ErrInvalidVitals)
+	}
+	return nil
+}
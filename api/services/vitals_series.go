@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidVitalsBucket = errors.New("invalid vitals bucket")
+
+// Agrupación de la serie: sin agrupar (un punto por sesión), por día o por semana
+const (
+	VitalsBucketNone = "none"
+	VitalsBucketDay  = "day"
+	VitalsBucketWeek = "week"
+)
+
+// VitalsQuery parámetros de la serie de un paciente. From es inclusivo y To exclusivo.
+type VitalsQuery struct {
+	PatientID uuid.UUID
+	Metrics   []string
+	From      time.Time
+	To        time.Time
+	Bucket    string
+	Location  *time.Location // Zona horaria para cortar días/semanas
+}
+
+// VitalStats resumen de un conjunto de mediciones
+type VitalStats struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Count int64   `json:"count"`
+}
+
+// VitalPoint una medición (sin agrupar) o un grupo (día/semana).
+// En los grupos, Value es el promedio y Stats trae el detalle.
+type VitalPoint struct {
+	At        time.Time   `json:"at"`
+	Value     float64     `json:"value"`
+	SessionID *uuid.UUID  `json:"session_id,omitempty"`
+	Stats     *VitalStats `json:"stats,omitempty"`
+}
+
+// VitalSeries serie temporal de un signo vital
+type VitalSeries struct {
+	Unit    string       `json:"unit"`
+	Summary VitalStats   `json:"summary"`
+	Points  []VitalPoint `json:"points"`
+}
+
+type VitalsSeriesService struct{}
+
+func NewVitalsSeriesService() *VitalsSeriesService {
+	return &VitalsSeriesService{}
+}
+
+// Filtro común: sesiones vigentes del paciente en el rango, con el signo guardado como número.
+// Los valores se leen directo del JSONB (vitals->>'clave'), sin cargar las sesiones.
+const vitalsSeriesFrom = `
+	FROM sessions s
+	CROSS JOIN unnest(?::text[]) AS m(metric)
+	WHERE s.patient_id = ? AND s.deleted_at IS NULL
+	  AND s.created_at >= ? AND s.created_at < ?
+	  AND jsonb_typeof(s.vitals -> m.metric) = 'number'`
+
+// Series devuelve una serie por signo vital solicitado (incluye los que no tienen datos)
+func (s *VitalsSeriesService) Series(query VitalsQuery) (map[string]*VitalSeries, error) {
+	switch query.Bucket {
+	case VitalsBucketNone, "", VitalsBucketDay, VitalsBucketWeek:
+	default:
+		return nil, ErrInvalidVitalsBucket
+	}
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+
+	series := make(map[string]*VitalSeries, len(query.Metrics))
+	for _, metric := range query.Metrics {
+		series[metric] = &VitalSeries{Unit: domains.VitalUnits[metric], Points: []VitalPoint{}}
+	}
+	args := []interface{}{pq.Array(query.Metrics), query.PatientID, query.From, query.To}
+
+	// 1. Resumen del rango completo
+	var summaries []struct {
+		Metric string
+		VitalStats
+	}
+	err := database.GetDB().Raw(`
+		SELECT m.metric AS metric,
+		       MIN((s.vitals ->> m.metric)::float8) AS min,
+		       MAX((s.vitals ->> m.metric)::float8) AS max,
+		       AVG((s.vitals ->> m.metric)::float8) AS avg,
+		       COUNT(*) AS count`+vitalsSeriesFrom+`
+		GROUP BY m.metric`, args...).Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range summaries {
+		series[row.Metric].Summary = row.VitalStats
+	}
+
+	// 2. Puntos
+	if query.Bucket == VitalsBucketDay || query.Bucket == VitalsBucketWeek {
+		err = s.bucketPoints(series, args, query.Bucket, query.Location)
+	} else {
+		err = s.rawPoints(series, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// rawPoints un punto por sesión, en orden cronológico
+func (s *VitalsSeriesService) rawPoints(series map[string]*VitalSeries, args []interface{}) error {
+	var rows []struct {
+		Metric    string
+		SessionID uuid.UUID
+		At        time.Time
+		Value     float64
+	}
+	err := database.GetDB().Raw(`
+		SELECT m.metric AS metric, s.id AS session_id, s.created_at AS at,
+		       (s.vitals ->> m.metric)::float8 AS value`+vitalsSeriesFrom+`
+		ORDER BY m.metric, s.created_at`, args...).Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		sessionID := row.SessionID
+		series[row.Metric].Points = append(series[row.Metric].Points, VitalPoint{At: row.At, Value: row.Value, SessionID: &sessionID})
+	}
+	return nil
+}
+
+// bucketPoints agrupa por día o semana (lunes) en la zona horaria indicada
+func (s *VitalsSeriesService) bucketPoints(series map[string]*VitalSeries, args []interface{}, bucket string, location *time.Location) error {
+	var rows []struct {
+		Metric string
+		Bucket time.Time // Inicio del grupo, hora local sin zona
+		VitalStats
+	}
+	err := database.GetDB().Raw(`
+		SELECT m.metric AS metric,
+		       date_trunc(?, s.created_at AT TIME ZONE ?) AS bucket,
+		       MIN((s.vitals ->> m.metric)::float8) AS min,
+		       MAX((s.vitals ->> m.metric)::float8) AS max,
+		       AVG((s.vitals ->> m.metric)::float8) AS avg,
+		       COUNT(*) AS count`+vitalsSeriesFrom+`
+		GROUP BY m.metric, bucket
+		ORDER BY m.metric, bucket`, append([]interface{}{bucket, location.String()}, args...)...).Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		stats := row.VitalStats
+		// El timestamp sin zona viene como UTC: se reinterpreta en la zona pedida
+		start := time.Date(row.Bucket.Year(), row.Bucket.Month(), row.Bucket.Day(), 0, 0, 0, 0, location)
+		series[row.Metric].Points = append(series[row.Metric].Points, VitalPoint{At: start, Value: stats.Avg, Stats: &stats})
+	}
+	return nil
+}
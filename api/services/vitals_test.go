@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+)
+
+func TestNormalizeVitals(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		want    map[string]float64
+		extra   []string
+		invalid bool
+	}{
+		{
+			name:  "canonical numbers and aliases",
+			input: map[string]interface{}{"hr": 72.0, "SpO2": "97", "pain": 3.0},
+			want:  map[string]float64{domains.VitalHeartRate: 72, domains.VitalSpO2: 97, domains.VitalPainScale: 3},
+		},
+		{
+			name:  "fahrenheit to celsius",
+			input: map[string]interface{}{"temperature": map[string]interface{}{"value": 98.6, "unit": "F"}},
+			want:  map[string]float64{domains.VitalTemperature: 37},
+		},
+		{
+			name:  "fahrenheit text with degree sign",
+			input: map[string]interface{}{"temp": "100.4 °F"},
+			want:  map[string]float64{domains.VitalTemperature: 38},
+		},
+		{
+			name:  "pounds to kilograms",
+			input: map[string]interface{}{"peso": "154 lb"},
+			want:  map[string]float64{domains.VitalWeight: 69.85},
+		},
+		{
+			name:  "decimal comma",
+			input: map[string]interface{}{"temperatura": "36,8"},
+			want:  map[string]float64{domains.VitalTemperature: 36.8},
+		},
+		{
+			name:  "blood pressure as text",
+			input: map[string]interface{}{"bp": "120/80 mmHg"},
+			want:  map[string]float64{domains.VitalSystolicBP: 120, domains.VitalDiastolicBP: 80},
+		},
+		{
+			name:  "blood pressure as object",
+			input: map[string]interface{}{"blood_pressure": map[string]interface{}{"systolic": 130.0, "diastolic": "85"}},
+			want:  map[string]float64{domains.VitalSystolicBP: 130, domains.VitalDiastolicBP: 85},
+		},
+		{
+			name:  "unknown keys are kept",
+			input: map[string]interface{}{"glucosa": "110 mg/dL", "hr": nil},
+			want:  map[string]float64{},
+			extra: []string{"glucosa"},
+		},
+		{name: "systolic equal to diastolic", input: map[string]interface{}{"bp": "80/80"}, invalid: true},
+		{name: "systolic below diastolic", input: map[string]interface{}{"systolic": 70.0, "diastolic": 90.0}, invalid: true},
+		{name: "blood pressure without diastolic", input: map[string]interface{}{"bp": "120"}, invalid: true},
+		{name: "blood pressure of another type", input: map[string]interface{}{"bp": 120.0}, invalid: true},
+		{name: "decimal pain scale", input: map[string]interface{}{"pain_scale": 4.5}, invalid: true},
+		{name: "pain scale out of range", input: map[string]interface{}{"eva": "11"}, invalid: true},
+		{name: "impossible heart rate", input: map[string]interface{}{"heart_rate": 900.0}, invalid: true},
+		{name: "text that is not a number", input: map[string]interface{}{"heart_rate": "normal"}, invalid: true},
+		{name: "unsupported unit", input: map[string]interface{}{"weight": "70 stone"}, invalid: true},
+		{name: "object without value", input: map[string]interface{}{"temperature": map[string]interface{}{"unit": "C"}}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vitals, err := NormalizeVitals(tt.input)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidVitals) {
+					t.Fatalf("err = %v, want ErrInvalidVitals", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range domains.VitalNames {
+				got := vitals.Get(name)
+				want, ok := tt.want[name]
+				switch {
+				case ok && got == nil:
+					t.Errorf("%s missing, want %g", name, want)
+				case ok && *got != want:
+					t.Errorf("%s = %g, want %g", name, *got, want)
+				case !ok && got != nil:
+					t.Errorf("%s = %g, want unset", name, *got)
+				}
+			}
+			for _, key := range tt.extra {
+				if _, ok := vitals.Extra[key]; !ok {
+					t.Errorf("extra %q not kept: %v", key, vitals.Extra)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVitalValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		vital   string
+		raw     interface{}
+		want    float64
+		invalid bool
+	}{
+		{name: "number", vital: domains.VitalHeartRate, raw: 80.0, want: 80},
+		{name: "text with canonical unit", vital: domains.VitalHeartRate, raw: "80 bpm", want: 80},
+		{name: "celsius", vital: domains.VitalTemperature, raw: "37.2 C", want: 37.2},
+		{name: "fahrenheit object", vital: domains.VitalTemperature, raw: map[string]interface{}{"value": 212.0, "unit": "fahrenheit"}, want: 100},
+		{name: "grams", vital: domains.VitalWeight, raw: "3500 g", want: 3.5},
+		{name: "pounds rounded to two decimals", vital: domains.VitalWeight, raw: map[string]interface{}{"value": 1.0, "unit": "lbs"}, want: 0.45},
+		{name: "unit of another vital", vital: domains.VitalHeartRate, raw: "80 kg", invalid: true},
+		{name: "boolean", vital: domains.VitalSpO2, raw: true, invalid: true},
+		{name: "value as text inside an object", vital: domains.VitalSpO2, raw: map[string]interface{}{"value": "97"}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVitalValue(tt.vital, tt.raw)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidVitals) {
+					t.Errorf("err = %v, want ErrInvalidVitals", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseVitalValue = %g, %v; want %g", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBloodPressure(t *testing.T) {
+	tests := []struct {
+		name                string
+		raw                 interface{}
+		systolic, diastolic float64
+		invalid             bool
+	}{
+		{name: "text", raw: "120/80", systolic: 120, diastolic: 80},
+		{name: "text with spaces and unit", raw: " 135 / 90 mmHg", systolic: 135, diastolic: 90},
+		{name: "object", raw: map[string]interface{}{"systolic": 110.0, "diastolic": 70.0}, systolic: 110, diastolic: 70},
+		{name: "object with text values", raw: map[string]interface{}{"systolic": "110", "diastolic": "70 mmHg"}, systolic: 110, diastolic: 70},
+		{name: "three parts", raw: "120/80/60", invalid: true},
+		{name: "not a number", raw: "alta/baja", invalid: true},
+		{name: "unsupported unit", raw: "12/8 kPa", invalid: true},
+		{name: "list", raw: []interface{}{120.0, 80.0}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vitals domains.Vitals
+			err := setBloodPressure(&vitals, tt.raw)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidVitals) {
+					t.Errorf("err = %v, want ErrInvalidVitals", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vitals.SystolicBP == nil || vitals.DiastolicBP == nil || *vitals.SystolicBP != tt.systolic || *vitals.DiastolicBP != tt.diastolic {
+				t.Errorf("blood pressure = %v/%v, want %g/%g", vitals.SystolicBP, vitals.DiastolicBP, tt.systolic, tt.diastolic)
+			}
+		})
+	}
+
+	// null no cambia nada
+	var vitals domains.Vitals
+	if err := setBloodPressure(&vitals, nil); err != nil || vitals.SystolicBP != nil || vitals.DiastolicBP != nil {
+		t.Errorf("nil blood pressure = %v, %+v", err, vitals)
+	}
+}
+
+func TestNewVitalAlerts(t *testing.T) {
+	thresholds := map[string]config.VitalRange{
+		domains.VitalHeartRate: {Min: 50, Max: 120},
+		domains.VitalSpO2:      {Min: 92, Max: 100},
+	}
+	vitals := func(heartRate, spo2 float64) domains.Vitals {
+		return domains.Vitals{HeartRate: &heartRate, SpO2: &spo2}
+	}
+	names := func(alerts []VitalAlert) map[string]string {
+		out := map[string]string{}
+		for _, alert := range alerts {
+			out[alert.Name] = alert.Direction
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		before domains.Vitals
+		after  domains.Vitals
+		want   map[string]string
+	}{
+		{name: "new session", before: domains.Vitals{}, after: vitals(130, 88), want: map[string]string{domains.VitalHeartRate: VitalHigh, domains.VitalSpO2: VitalLow}},
+		{name: "unchanged abnormal values", before: vitals(130, 88), after: vitals(130, 88), want: map[string]string{}},
+		{name: "only the changed value", before: vitals(130, 88), after: vitals(130, 85), want: map[string]string{domains.VitalSpO2: VitalLow}},
+		{name: "value back to normal", before: vitals(130, 88), after: vitals(80, 97), want: map[string]string{}},
+		{name: "normal value becomes abnormal", before: vitals(80, 97), after: vitals(40, 97), want: map[string]string{domains.VitalHeartRate: VitalLow}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(NewVitalAlerts(tt.before, tt.after, thresholds))
+			if len(got) != len(tt.want) {
+				t.Fatalf("alerts = %v, want %v", got, tt.want)
+			}
+			for name, direction := range tt.want {
+				if got[name] != direction {
+					t.Errorf("alert %s = %q, want %q", name, got[name], direction)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSweepKeepsEvidenceRetainedByRevisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	f := newAccessFixture(t)
+	creator := f.users[actorCreator]
+	session := f.session(creator, f.patient.ID, false)
+	path := "/api/sessions/" + session.ID.String()
+
+	// La foto se agrega y luego se quita: la revisión guardada en la segunda edición la referencia
+	photo := f.pendingUpload(creator, services.BucketSessionEvidence)
+	neverUsed := f.pendingUpload(creator, services.BucketSessionEvidence)
+	for _, photos := range []string{`["` + photo + `"]`, `[]`} {
+		body := `{"patient_id":"` + f.patient.ID.String() + `","intervention_plan":"Marcha","description":"Sin novedades","photos":` + photos + `}`
+		if rec := f.do(creator, http.MethodPut, path, body); rec.Code != http.StatusOK {
+			t.Fatalf("update session: status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	cfg := config.LoadConfig()
+	purged, err := services.NewAttachmentService(cfg).SweepOrphans(context.Background(), time.Now().Add(cfg.AttachmentOrphanMaxAge+time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("purged %d attachments, want only the never used upload", purged)
+	}
+
+	var retained, unused domains.Attachment
+	f.db.First(&retained, "object_key = ?", photo)
+	f.db.First(&unused, "object_key = ?", neverUsed)
+	if retained.Status != domains.AttachmentUnlinked || retained.RetainedUntil == nil ||
+		retained.RetainedUntil.Before(time.Now().Add(cfg.SessionEvidenceRetention-time.Hour)) {
+		t.Errorf("revision photo = %s retained until %v, want UNLINKED and retained for the clinical record", retained.Status, retained.RetainedUntil)
+	}
+	if unused.Status != domains.AttachmentDeleted {
+		t.Errorf("unused upload status = %s, want DELETED", unused.Status)
+	}
+
+	// Vencido el plazo de retención, el sweeper sí la elimina
+	if _, err := services.NewAttachmentService(cfg).SweepOrphans(context.Background(), retained.RetainedUntil.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	f.db.First(&retained, "object_key = ?", photo)
+	if retained.Status != domains.AttachmentDeleted {
+		t.Errorf("revision photo status after retention = %s, want DELETED", retained.Status)
+	}
+}
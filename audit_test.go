@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestConsentChangesAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	f := newAccessFixture(t)
+	creator := f.users[actorCreator]
+	path := "/api/patients/" + f.patient.ID.String() + "/consents"
+
+	key := f.pendingUpload(creator, services.BucketPatientsConsent)
+	if rec := f.do(creator, http.MethodPost, path, `{"key":"`+key+`","signed_at":"2026-01-10"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create consent version: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.do(creator, http.MethodPost, path+"/revoke", `{"reason":"Solicitud de la familia"}`); rec.Code != http.StatusOK {
+		t.Fatalf("revoke consent: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var logs []domains.AuditLog
+	if err := f.db.Where("entity_type = ?", domains.AuditEntityConsent).Order("created_at ASC").Find(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d consent audit entries, want 2 (create and revoke)", len(logs))
+	}
+
+	created, revoked := logs[0], logs[1]
+	if created.Action != domains.AuditCreate || created.ActorID != creator.ID || created.PatientID == nil || *created.PatientID != f.patient.ID {
+		t.Errorf("create entry = %+v", created)
+	}
+	if revoked.Action != domains.AuditUpdate || revoked.EntityID != created.EntityID {
+		t.Errorf("revoke entry = %+v, want an UPDATE of consent %s", revoked, created.EntityID)
+	}
+
+	var changes map[string]map[string]interface{}
+	if err := json.Unmarshal(revoked.Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if change, ok := changes["Revoked"]; !ok || change["before"] != false || change["after"] != true {
+		t.Errorf("revoke changes = %v, want Revoked false -> true", changes)
+	}
+}
+
+func TestRecordExportAuditsEachPatient(t *testing.T) {
+	f := newAccessFixture(t)
+	creator := f.users[actorCreator]
+	actor := services.AuditActor{UserID: creator.ID, Email: creator.Email, Role: string(creator.Role)}
+
+	detail := services.AuditExportDetail{
+		ExportID: uuid.New(),
+		Format:   "csv",
+		Identity: services.PatientIdentityFull,
+		From:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Rows:     3,
+		Complete: true,
+	}
+	if err := services.NewAuditService().RecordExport(actor, domains.AuditEntityIncidentExport, detail, []uuid.UUID{f.patient.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs []domains.AuditLog
+	if err := f.db.Where("action = ?", domains.AuditExport).Find(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d export audit entries, want 2 (export and patient)", len(logs))
+	}
+	for _, log := range logs {
+		switch log.EntityType {
+		case domains.AuditEntityIncidentExport:
+			if log.EntityID != detail.ExportID || log.PatientID != nil {
+				t.Errorf("export entry = %+v", log)
+			}
+		case domains.AuditEntityPatient:
+			if log.EntityID != f.patient.ID || log.PatientID == nil || *log.PatientID != f.patient.ID {
+				t.Errorf("patient entry = %+v", log)
+			}
+		default:
+			t.Errorf("unexpected entry %+v", log)
+		}
+
+		var changes map[string]map[string]interface{}
+		if err := json.Unmarshal(log.Changes, &changes); err != nil {
+			t.Fatal(err)
+		}
+		if changes["ExportID"]["after"] != detail.ExportID.String() || changes["Identity"]["after"] != services.PatientIdentityFull {
+			t.Errorf("changes = %v", changes)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+func TestWeeklyDigestCatchesUpAfterAMissedMonday(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	cfg.DigestTimezone = "UTC"
+	cfg.DigestHour = 7
+
+	creator := f.users[actorCreator]
+	f.db.Model(&creator).Update("profile_data", datatypes.JSON(`{"digest_frequency":"WEEKLY"}`))
+	f.create(&domains.NotificationPreference{ID: uuid.New(), UserID: creator.ID, EventType: domains.NotifIncidentAlert, Channel: domains.ChannelDigest})
+	f.create(&domains.Notification{
+		ID: uuid.New(), UserID: creator.ID, Type: domains.NotifIncidentAlert, Message: "Caída en el baño",
+		CreatedAt: time.Date(2026, 10, 8, 12, 0, 0, 0, time.UTC),
+	})
+
+	// Miércoles: el lunes 12 no hubo ejecución, la semana del 5 al 12 sigue pendiente
+	service := services.NewDigestService(cfg)
+	wednesday := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	service.RunScheduled(wednesday)
+	service.RunScheduled(wednesday.Add(time.Hour))
+
+	var logs []domains.DigestLog
+	f.db.Where("user_id = ? AND frequency = ?", creator.ID, domains.DigestWeekly).Find(&logs)
+	if len(logs) != 1 || !logs[0].PeriodStart.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weekly digest logs = %+v, want one for the week starting 2026-10-05", logs)
+	}
+	var emails int64
+	f.db.Model(&domains.EmailOutbox{}).Where("to_address = ?", creator.Email).Count(&emails)
+	if emails != 1 {
+		t.Errorf("queued %d digest emails, want 1", emails)
+	}
+}
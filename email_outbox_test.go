@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEmailOutboxSendsEachEmailOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	for _, to := range []string{"a@test.local", "b@test.local"} {
+		if err := services.EnqueueEmail(f.db, nil, to, services.RenderedEmail{Subject: "Aviso", Text: "Hola"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mailer := services.NewMemoryMailer()
+	worker := services.NewEmailOutboxWorker(config.LoadConfig(), mailer)
+	worker.ProcessBatch()
+	worker.ProcessBatch()
+
+	if got := len(mailer.Messages()); got != 2 {
+		t.Fatalf("sent %d emails, want 2", got)
+	}
+	var emails []domains.EmailOutbox
+	f.db.Find(&emails)
+	for _, email := range emails {
+		if email.Status != domains.EmailSent || email.Attempts != 1 || email.SentAt == nil {
+			t.Errorf("email to %s = %s after %d attempts, want SENT after 1", email.ToAddress, email.Status, email.Attempts)
+		}
+	}
+
+	// Otra instancia reclama el correo apenas este worker lo arrienda (el lote tardó más que el arriendo):
+	// este worker no debe enviarlo ni pisar el estado que dejó la otra instancia
+	if err := services.EnqueueEmail(f.db, nil, "c@test.local", services.RenderedEmail{Subject: "Aviso", Text: "Hola"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Exec(`CREATE TRIGGER steal_lease AFTER UPDATE OF next_attempt_at ON email_outboxes
+		WHEN NEW.status = 'PENDING' AND NEW.last_error <> 'reclaimed'
+		BEGIN UPDATE email_outboxes SET next_attempt_at = datetime('now', '+1 hour'), last_error = 'reclaimed' WHERE id = NEW.id; END`).Error; err != nil {
+		t.Fatal(err)
+	}
+	mailer.Reset()
+	worker.ProcessBatch()
+
+	if got := len(mailer.Messages()); got != 0 {
+		t.Errorf("sent %d emails after losing the lease, want 0", got)
+	}
+	var reclaimed domains.EmailOutbox
+	f.db.First(&reclaimed, "to_address = ?", "c@test.local")
+	if reclaimed.Status != domains.EmailPending || reclaimed.Attempts != 0 || reclaimed.LastError != "reclaimed" {
+		t.Errorf("reclaimed email = %s, %d attempts, %q: the other instance's lease was overwritten", reclaimed.Status, reclaimed.Attempts, reclaimed.LastError)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestIncident(t *testing.T, f *accessFixture, service *services.IncidentService) domains.Incident {
+	t.Helper()
+	incident := domains.Incident{
+		PatientID: f.patient.ID, ReportedByID: f.users[actorCreator].ID, Description: "Caída en el baño",
+	}
+	if err := service.Create(f.db, &incident); err != nil {
+		t.Fatal(err)
+	}
+	return incident
+}
+
+func TestIncidentUpdateKeepsConcurrentChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	service := services.NewIncidentService(cfg)
+	incident := newTestIncident(t, f, service)
+
+	// Copia leída antes de que otro usuario resolviera el incidente
+	stale := incident
+	if err := service.Resolve(f.db, &incident, f.users[actorCreator], "Se revisó la zona"); err != nil {
+		t.Fatal(err)
+	}
+
+	category := domains.IncidentCategoryFall
+	before, err := service.Update(f.db, &stale, domains.UpdateIncidentInput{Category: &category})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Status != domains.IncidentResolved {
+		t.Errorf("before.Status = %s, want the locked RESOLVED row", before.Status)
+	}
+
+	var saved domains.Incident
+	f.db.First(&saved, "id = ?", incident.ID)
+	if saved.Status != domains.IncidentResolved || saved.ResolutionNotes != "Se revisó la zona" || saved.ResolvedAt == nil {
+		t.Errorf("resolution overwritten by the update: %+v", saved)
+	}
+	if saved.Category != domains.IncidentCategoryFall {
+		t.Errorf("category = %s, want %s", saved.Category, domains.IncidentCategoryFall)
+	}
+}
+
+func TestIncidentUpdateTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	service := services.NewIncidentService(cfg)
+
+	t.Run("severity recalculates the deadline from creation", func(t *testing.T) {
+		incident := newTestIncident(t, f, service)
+		severity := string(domains.SeverityCritical)
+		if _, err := service.Update(f.db, &incident, domains.UpdateIncidentInput{Severity: &severity}); err != nil {
+			t.Fatal(err)
+		}
+		want := incident.CreatedAt.Add(cfg.IncidentDeadlines[severity])
+		if !incident.DueAt.Equal(want) {
+			t.Errorf("DueAt = %v, want %v", incident.DueAt, want)
+		}
+	})
+
+	t.Run("reopening clears resolution and escalation", func(t *testing.T) {
+		incident := newTestIncident(t, f, service)
+		f.db.Model(&incident).Updates(map[string]interface{}{"escalation_level": 2, "last_escalated_at": time.Now()})
+		if err := service.Resolve(f.db, &incident, f.users[actorCreator], "Controlado"); err != nil {
+			t.Fatal(err)
+		}
+
+		status := string(domains.IncidentOpen)
+		if _, err := service.Update(f.db, &incident, domains.UpdateIncidentInput{Status: &status}); err != nil {
+			t.Fatal(err)
+		}
+		var saved domains.Incident
+		f.db.First(&saved, "id = ?", incident.ID)
+		if saved.Status != domains.IncidentOpen || saved.ResolvedAt != nil || saved.ResolvedByID != nil ||
+			saved.EscalationLevel != 0 || saved.LastEscalatedAt != nil || !saved.DueAt.After(time.Now()) {
+			t.Errorf("reopened incident = %+v", saved)
+		}
+	})
+
+	t.Run("assignee without access is rejected", func(t *testing.T) {
+		incident := newTestIncident(t, f, service)
+		outsider := f.users[actorOutsider].ID.String()
+		if _, err := service.Update(f.db, &incident, domains.UpdateIncidentInput{AssigneeID: &outsider}); err != services.ErrInvalidAssignee {
+			t.Errorf("err = %v, want ErrInvalidAssignee", err)
+		}
+	})
+}
+
+func TestEscalateOverdueIncidents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	service := services.NewIncidentService(cfg)
+
+	overdue := newTestIncident(t, f, service)
+	resolved := newTestIncident(t, f, service)
+	onTime := newTestIncident(t, f, service)
+	if err := service.Resolve(f.db, &resolved, f.users[actorCreator], "Controlado"); err != nil {
+		t.Fatal(err)
+	}
+	now := overdue.DueAt.Add(time.Hour)
+	f.db.Model(&onTime).Update("due_at", now.Add(time.Hour))
+
+	level := func(incident domains.Incident) int {
+		var saved domains.Incident
+		f.db.First(&saved, "id = ?", incident.ID)
+		return saved.EscalationLevel
+	}
+
+	service.EscalateOverdue(now)
+	if level(overdue) != 1 || level(resolved) != 0 || level(onTime) != 0 {
+		t.Fatalf("levels after first run = %d/%d/%d, want 1/0/0", level(overdue), level(resolved), level(onTime))
+	}
+
+	// Dentro del intervalo de repetición no se vuelve a escalar; pasado el intervalo, sí
+	service.EscalateOverdue(now.Add(time.Minute))
+	if got := level(overdue); got != 1 {
+		t.Errorf("level within the repeat window = %d, want 1", got)
+	}
+	service.EscalateOverdue(now.Add(cfg.IncidentEscalationRepeat + time.Minute))
+	if got := level(overdue); got != 2 {
+		t.Errorf("level after the repeat window = %d, want 2", got)
+	}
+
+	services.WaitForNotifications()
+	var notified int64
+	f.db.Model(&domains.Notification{}).
+		Where("user_id = ? AND type = ? AND related_id = ?", f.users[actorCreator].ID, domains.NotifIncidentEscalation, overdue.ID).
+		Count(&notified)
+	if notified != 2 {
+		t.Errorf("patient creator got %d escalation notifications for the overdue incident, want 2", notified)
+	}
+}
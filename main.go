@@ -1,160 +1,71 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"os"
+	"time"
 
 	"bitacora-medica-backend/api/config"
 	"bitacora-medica-backend/api/database"
-	"bitacora-medica-backend/api/domains"
-	"bitacora-medica-backend/api/handlers/admin"
-	"bitacora-medica-backend/api/handlers/auth"
-	"bitacora-medica-backend/api/handlers/collaborations"
-	"bitacora-medica-backend/api/handlers/common"
-	"bitacora-medica-backend/api/handlers/patients"
-	"bitacora-medica-backend/api/handlers/reports"
-	"bitacora-medica-backend/api/handlers/sessions"
-	"bitacora-medica-backend/api/handlers/support"
-	"time"
-
-	"github.com/gin-contrib/cors"
-
-	//"bitacora-medica-backend/api/domains"
-	"bitacora-medica-backend/api/middleware"
-
-	"github.com/gin-gonic/gin"
+	"bitacora-medica-backend/api/services"
 )
 
 func main() {
 	// 1. Cargar Configuración
 	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// 2. Conectar a BD
 	database.Connect(cfg.DBUrl)
 
-	// 4. Configurar Router
-	r := gin.Default()
-
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://tradelog-app.vercel.app", "https://cron-job.org", "http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Aumentar el límite de memoria para subida de archivos (ej: 8MB) si es necesario
-	r.MaxMultipartMemory = 8 << 20
-
-	r.GET("/ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "pong"})
-	})
-
-	api := r.Group("/api")
-
-	// Pasamos 'cfg' al middleware para validar JWT
-	api.Use(middleware.AuthMiddleware(cfg))
-	{
-		authGroup := api.Group("/auth")
-		{
-			authGroup.PUT("/profile", auth.UpdateProfileHandler())
-			authGroup.GET("/me", auth.GetMeHandler())
+	// Tareas únicas de mantenimiento: `go run . backfill-object-keys`
+	if len(os.Args) > 1 && os.Args[1] == "backfill-object-keys" {
+		result, err := services.BackfillObjectKeys(database.GetDB())
+		if err != nil {
+			slog.Error("Object key backfill failed", "error", err)
+			os.Exit(1)
 		}
+		slog.Info("Object key backfill finished", "patients", result.Patients, "sessions", result.Sessions)
+		return
+	}
 
-		// --- GRUPO DE PACIENTES ---
-		patientsGroup := api.Group("/patients")
-		{
-			patientsGroup.POST("/", patients.CreatePatientHandler(cfg))
-
-			patientsGroup.GET("/", patients.ListPatientsHandler())
+	// 3. Workers en segundo plano (cola de correos con reintentos + tareas programadas)
+	mailer := services.NewMailer(cfg)
+	go services.NewEmailOutboxWorker(cfg, mailer).Start(context.Background())
 
-			// NUEVO: Perfil Unificado (Ojo de Dios del Paciente)
-			patientsGroup.GET("/:id", patients.GetPatientProfileHandler())
+	digests := services.NewDigestService(cfg)
+	scheduler := services.NewScheduler()
+	scheduler.Every("notification-digest", time.Hour, func(ctx context.Context) {
+		digests.RunScheduled(time.Now())
+	})
 
-			patientsGroup.PUT("/:id", patients.UpdatePatientHandler())
+	attachments := services.NewAttachmentService(cfg)
+	scheduler.Every("attachment-sweeper", time.Hour, func(ctx context.Context) {
+		if _, err := attachments.SweepOrphans(ctx, time.Now()); err != nil {
+			slog.Error("Attachment sweep failed", "error", err)
 		}
+	})
 
-		sessionsGroup := api.Group("/sessions")
-		{
-			// CREATE
-			sessionsGroup.POST("/", sessions.CreateSessionHandler(cfg))
-
-			// READ (Listar con filtros: ?patient_id=...&has_incident=true)
-			sessionsGroup.GET("/", sessions.ListSessionsHandler())
-
-			// READ ONE (Detalle específico)
-			sessionsGroup.GET("/:id", sessions.GetSessionHandler())
-
-			// UPDATE (Solo autor)
-			sessionsGroup.PUT("/:id", sessions.UpdateSessionHandler())
-
-			// DELETE (Solo autor - Soft Delete)
-			sessionsGroup.DELETE("/:id", sessions.DeleteSessionHandler())
+	uploadSessions := services.NewUploadSessionService(cfg)
+	scheduler.Every("upload-session-sweeper", time.Hour, func(ctx context.Context) {
+		if _, err := uploadSessions.SweepExpired(time.Now()); err != nil {
+			slog.Error("Upload session sweep failed", "error", err)
 		}
+	})
 
-		uploads := api.Group("/uploads")
-		uploads.POST("/image", common.UploadImageHandler(cfg))
-
-		uploads.POST("/consent", common.UploadConsentHandler(cfg))
-	}
-
-	collabGroup := api.Group("/collaborations")
-	{
-		// Invitar: POST /api/collaborations/invite
-		collabGroup.POST("/invite", collaborations.InviteCollabHandler(cfg))
-
-		// Responder: PUT /api/collaborations/:id/respond
-		// :id es el ID de la COLABORACIÓN (no del paciente ni usuario)
-		collabGroup.PUT("/:id/respond", collaborations.RespondInvitationHandler(cfg))
-
-		collabGroup.GET("/pending", collaborations.GetPendingInvitationsHandler())
-	}
-
-	// --- GRUPO REPORTES ---
-	reportsGroup := api.Group("/reports")
-	{
-		// Individual: POST /api/reports/ (Kine sube su resumen mensual)
-		reportsGroup.POST("/", reports.CreateIndividualReportHandler())
-
-		// Maestro: GET /api/reports/master?patient_id=...&start_date=...&end_date=...
-		// (Admin/Dueño obtiene la visión global)
-		reportsGroup.GET("/master", reports.GenerateMasterReportHandler())
-	}
-
-	// --- GRUPO SOPORTE (Accesible para todos) ---
-	supportGroup := api.Group("/support")
-	{
-		supportGroup.POST("/", support.CreateTicketHandler())
-		supportGroup.GET("/", support.ListTicketsHandler()) // Admin ve todo, User ve suyo
-
-		// Responder ticket (Solo Admin)
-		supportGroup.PUT("/:id/reply", middleware.RequireAdmin(), support.ReplyTicketHandler())
-	}
-
-	// --- GRUPO ADMIN (Protegido por RequireAdmin) ---
-	adminGroup := api.Group("/admin")
-	adminGroup.Use(middleware.RequireAdmin())
-	{
-		// Gestión de Usuarios
-		adminGroup.GET("/users/pending", admin.ListPendingUsersHandler())
-		adminGroup.PUT("/users/:id/review", admin.ReviewUserHandler(cfg))
-
-		// Dashboard (KPIs simples)
-		adminGroup.GET("/dashboard", func(c *gin.Context) {
-			// Implementación rápida de KPIs [cite: 113]
-			var totalUsers, activePatients, incidentsToday int64
-			db := database.GetDB()
-			db.Model(&domains.User{}).Count(&totalUsers)
-			db.Model(&domains.Patient{}).Count(&activePatients)
-			db.Model(&domains.Session{}).Where("has_incident = ?", true).Count(&incidentsToday)
+	// Incidentes abiertos con plazo vencido: aviso al responsable y al creador del paciente
+	incidentService := services.NewIncidentService(cfg)
+	scheduler.Every("incident-escalation", 15*time.Minute, func(ctx context.Context) {
+		incidentService.EscalateOverdue(time.Now())
+	})
+	scheduler.Start(context.Background())
 
-			c.JSON(200, gin.H{
-				"total_users":        totalUsers,
-				"active_patients":    activePatients,
-				"incidents_all_time": incidentsToday,
-			})
-		})
-	}
+	// 4. Configurar Router
+	r := setupRouter(cfg, digests)
 
 	slog.Info("Server starting on port " + cfg.Port)
 	r.Run(":" + cfg.Port)
@@ -0,0 +1,20 @@
+-- Cola de correos salientes con reintentos (la procesa EmailOutboxWorker)
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS email_outboxes (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid,
+    to_address varchar(255) NOT NULL,
+    subject text NOT NULL,
+    body text NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'PENDING',
+    attempts bigint NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL,
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_outboxes_user_id ON email_outboxes (user_id);
+CREATE INDEX IF NOT EXISTS idx_email_outboxes_status ON email_outboxes (status);
+CREATE INDEX IF NOT EXISTS idx_email_outboxes_next_attempt_at ON email_outboxes (next_attempt_at);
@@ -0,0 +1 @@
+ALTER TABLE email_outboxes DROP COLUMN IF EXISTS html_body;
@@ -0,0 +1,2 @@
+-- Versión HTML de los correos renderizados desde plantillas
+ALTER TABLE email_outboxes ADD COLUMN IF NOT EXISTS html_body text;
@@ -0,0 +1,11 @@
+-- Canal elegido por cada usuario para cada tipo de evento
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    event_type varchar(50) NOT NULL,
+    channel varchar(20) NOT NULL DEFAULT 'EMAIL',
+    created_at timestamptz,
+    updated_at timestamptz
+);
+-- Upsert de preferencias (ON CONFLICT user_id, event_type)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_pref_user_event ON notification_preferences (user_id, event_type);
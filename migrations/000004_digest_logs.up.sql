@@ -0,0 +1,12 @@
+-- Registro de resúmenes enviados
+CREATE TABLE IF NOT EXISTS digest_logs (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    frequency varchar(10) NOT NULL,
+    period_start timestamptz NOT NULL,
+    period_end timestamptz NOT NULL,
+    item_count bigint NOT NULL DEFAULT 0,
+    sent_at timestamptz
+);
+-- Un resumen por usuario y periodo (evita duplicados si el scheduler corre dos veces)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_user_period ON digest_logs (user_id, frequency, period_start);
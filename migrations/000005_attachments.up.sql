@@ -0,0 +1,24 @@
+-- Registro de archivos subidos a los buckets privados (pendientes, vinculados o huérfanos)
+CREATE TABLE IF NOT EXISTS attachments (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bucket varchar(100) NOT NULL,
+    object_key text NOT NULL,
+    variant_keys text[],
+    owner_id uuid NOT NULL,
+    patient_id uuid,
+    entity_type varchar(20),
+    entity_id uuid,
+    status varchar(20) NOT NULL DEFAULT 'PENDING',
+    linked_at timestamptz,
+    unlinked_at timestamptz,
+    purged_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+-- Una fila por objeto: LinkToEntity y el sweeper dependen de esta unicidad
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachment_object ON attachments (bucket, object_key);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner_id ON attachments (owner_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_patient_id ON attachments (patient_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_entity_id ON attachments (entity_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_status ON attachments (status);
+CREATE INDEX IF NOT EXISTS idx_attachments_created_at ON attachments (created_at);
@@ -0,0 +1,16 @@
+-- Versiones del consentimiento informado de cada paciente
+CREATE TABLE IF NOT EXISTS consent_documents (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    patient_id uuid NOT NULL,
+    version bigint NOT NULL,
+    object_key text NOT NULL,
+    uploaded_by_id uuid NOT NULL,
+    signed_at date NOT NULL,
+    expires_at date,
+    revoked boolean NOT NULL DEFAULT false,
+    revoked_at timestamptz,
+    revoked_by_id uuid,
+    revocation_reason text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_consent_patient_version ON consent_documents (patient_id, version);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS upload_sessions;
+DROP INDEX IF EXISTS idx_attachments_sha256;
+ALTER TABLE attachments DROP COLUMN IF EXISTS size;
+ALTER TABLE attachments DROP COLUMN IF EXISTS sha256;
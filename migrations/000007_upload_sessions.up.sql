@@ -0,0 +1,21 @@
+-- Hash y tamaño de cada archivo (integridad y deduplicación) y subidas reanudables por partes
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS sha256 varchar(64);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS size bigint;
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments (sha256);
+
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id uuid NOT NULL,
+    kind varchar(20) NOT NULL,
+    total_size bigint NOT NULL,
+    received_size bigint NOT NULL DEFAULT 0,
+    chunk_keys text[],
+    status varchar(20) NOT NULL DEFAULT 'ACTIVE',
+    result_key text,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_owner_id ON upload_sessions (owner_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_status ON upload_sessions (status);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions (expires_at);
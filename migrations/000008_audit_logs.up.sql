@@ -0,0 +1,20 @@
+-- Registro de auditoría de datos clínicos (quién vio o cambió qué)
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id uuid NOT NULL,
+    actor_email varchar(255),
+    actor_role varchar(20),
+    action varchar(20) NOT NULL,
+    entity_type varchar(30) NOT NULL,
+    entity_id uuid NOT NULL,
+    patient_id uuid,
+    changes jsonb,
+    ip_address varchar(64),
+    user_agent text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_patient_id ON audit_logs (patient_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
@@ -0,0 +1,13 @@
+-- Historial inmutable de versiones de las sesiones
+CREATE TABLE IF NOT EXISTS session_revisions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id uuid NOT NULL,
+    version bigint NOT NULL,
+    snapshot jsonb NOT NULL,
+    change_type varchar(20) NOT NULL,
+    edited_by_id uuid NOT NULL,
+    reason text,
+    created_at timestamptz
+);
+-- Dos ediciones simultáneas no pueden tomar el mismo número de versión
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revision_session_version ON session_revisions (session_id, version);
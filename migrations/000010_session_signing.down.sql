@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS session_addendums;
+DROP INDEX IF EXISTS idx_sessions_signed_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS cosigned_by_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS cosigned_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS signed_by_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS signed_at;
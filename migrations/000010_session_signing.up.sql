@@ -0,0 +1,16 @@
+-- Firma clínica (firma del autor y co-firma del creador del paciente) y adendas
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS signed_at timestamptz;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS signed_by_id uuid;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS cosigned_at timestamptz;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS cosigned_by_id uuid;
+CREATE INDEX IF NOT EXISTS idx_sessions_signed_at ON sessions (signed_at);
+
+-- Tabla con el nombre que genera GORM para SessionAddendum
+CREATE TABLE IF NOT EXISTS session_addendums (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id uuid NOT NULL,
+    author_id uuid NOT NULL,
+    content text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_session_addendums_session_id ON session_addendums (session_id);
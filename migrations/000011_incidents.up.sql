@@ -0,0 +1,39 @@
+-- Seguimiento de incidentes (clasificación, responsable, plazos y acciones correctivas)
+CREATE TABLE IF NOT EXISTS incidents (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    patient_id uuid NOT NULL,
+    session_id uuid,
+    reported_by_id uuid NOT NULL,
+    category varchar(30) NOT NULL DEFAULT 'OTHER',
+    severity varchar(20) NOT NULL DEFAULT 'MEDIUM',
+    status varchar(20) NOT NULL DEFAULT 'OPEN',
+    description text NOT NULL,
+    photo text,
+    assignee_id uuid,
+    due_at timestamptz NOT NULL,
+    escalation_level bigint NOT NULL DEFAULT 0,
+    last_escalated_at timestamptz,
+    resolution_notes text,
+    resolved_at timestamptz,
+    resolved_by_id uuid,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_incidents_patient_id ON incidents (patient_id);
+-- Un incidente por sesión (CreateFromSession no lo duplica al editar)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_session_id ON incidents (session_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents (status);
+CREATE INDEX IF NOT EXISTS idx_incidents_assignee_id ON incidents (assignee_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_due_at ON incidents (due_at);
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON incidents (created_at);
+
+CREATE TABLE IF NOT EXISTS incident_actions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    incident_id uuid NOT NULL,
+    author_id uuid NOT NULL,
+    description text NOT NULL,
+    due_at timestamptz,
+    completed_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_incident_actions_incident_id ON incident_actions (incident_id);
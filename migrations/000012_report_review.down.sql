@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_professional_reports_deleted_at;
+DROP INDEX IF EXISTS idx_professional_reports_status;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS review_notes;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS reviewed_by_id;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS submitted_at;
+ALTER TABLE professional_reports DROP COLUMN IF EXISTS status;
@@ -0,0 +1,17 @@
+-- Ciclo de vida de los reportes individuales (borrador, enviado, aprobado) y soft delete.
+-- Los reportes existentes quedan como enviados, pendientes de aprobación.
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'SUBMITTED';
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS submitted_at timestamptz;
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS reviewed_at timestamptz;
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS reviewed_by_id uuid;
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS review_notes text;
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+ALTER TABLE professional_reports ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+UPDATE professional_reports
+SET submitted_at = COALESCE(submitted_at, created_at),
+    updated_at = COALESCE(updated_at, created_at)
+WHERE submitted_at IS NULL OR updated_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_professional_reports_status ON professional_reports (status);
+CREATE INDEX IF NOT EXISTS idx_professional_reports_deleted_at ON professional_reports (deleted_at);
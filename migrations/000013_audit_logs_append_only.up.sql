@@ -0,0 +1,29 @@
+-- La auditoría es solo de inserción también en la base de datos (no solo en los hooks de GORM):
+-- cualquier UPDATE, DELETE o TRUNCATE sobre audit_logs falla, venga de la app o de otra herramienta.
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only (% not allowed)', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_no_update_delete ON audit_logs;
+CREATE TRIGGER audit_logs_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
+
+-- Los roles de la API de Supabase solo pueden leer e insertar
+REVOKE UPDATE, DELETE, TRUNCATE ON audit_logs FROM PUBLIC;
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'anon') THEN
+        REVOKE UPDATE, DELETE, TRUNCATE ON audit_logs FROM anon;
+    END IF;
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'authenticated') THEN
+        REVOKE UPDATE, DELETE, TRUNCATE ON audit_logs FROM authenticated;
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_attachments_retained_until;
+ALTER TABLE attachments DROP COLUMN IF EXISTS retained_until;
@@ -0,0 +1,13 @@
+-- Fotos de sesión retenidas por la ficha clínica (revisiones y sesiones eliminadas): el sweeper
+-- no las elimina hasta retained_until. Ver SESSION_EVIDENCE_RETENTION_DAYS (15 años por defecto).
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS retained_until timestamptz;
+CREATE INDEX IF NOT EXISTS idx_attachments_retained_until ON attachments (retained_until);
+
+-- Única pasada sobre los datos existentes: fotos que ya referencian una revisión o una sesión eliminada
+UPDATE attachments SET retained_until = now() + interval '15 years'
+WHERE bucket = 'session-evidence' AND retained_until IS NULL AND (
+    EXISTS (SELECT 1 FROM sessions WHERE sessions.deleted_at IS NOT NULL
+        AND (sessions.incident_photo = attachments.object_key OR attachments.object_key = ANY(sessions.photos)))
+    OR EXISTS (SELECT 1 FROM session_revisions WHERE session_revisions.snapshot->>'IncidentPhoto' = attachments.object_key
+        OR session_revisions.snapshot->'Photos' @> jsonb_build_array(attachments.object_key))
+);
@@ -0,0 +1 @@
+ALTER TABLE professional_reports DROP CONSTRAINT IF EXISTS professional_reports_no_overlap;
@@ -0,0 +1,13 @@
+-- Un autor no puede tener dos reportes vigentes del mismo paciente con periodos que se crucen.
+-- La validación de la app (checkOverlap) no basta con dos creaciones en paralelo; la restricción
+-- sí, y la app traduce su violación (SQLSTATE 23P01) al mismo 409.
+-- Si ya existen reportes superpuestos, la restricción no se crea: hay que eliminar los duplicados antes.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE professional_reports DROP CONSTRAINT IF EXISTS professional_reports_no_overlap;
+ALTER TABLE professional_reports ADD CONSTRAINT professional_reports_no_overlap
+    EXCLUDE USING gist (
+        patient_id WITH =,
+        author_id WITH =,
+        daterange(date_range_start, date_range_end, '[]') WITH &&
+    ) WHERE (deleted_at IS NULL);
@@ -0,0 +1,2 @@
+-- Sin vuelta atrás: las versiones creadas aquí no se distinguen de las demás y pueden tener
+-- revocaciones o versiones posteriores que dependen de ellas.
@@ -0,0 +1,15 @@
+-- Pacientes registrados antes del historial de consentimientos: su PDF (Patient.consent_pdf_url)
+-- pasa a ser la versión 1, firmada por el creador en la fecha de alta. Así la API solo lee el
+-- historial y la revocación y las nuevas versiones no pierden el documento original.
+-- Las URLs antiguas se reducen a la clave del objeto, como hace ObjectKeyFromReference.
+INSERT INTO consent_documents (patient_id, version, object_key, uploaded_by_id, signed_at, created_at)
+SELECT p.id,
+       1,
+       regexp_replace(split_part(p.consent_pdf_url, '?', 1), '^[a-z]+://.*/patients-consent/', ''),
+       p.creator_id,
+       COALESCE(p.created_at, now())::date,
+       now()
+FROM patients p
+WHERE p.consent_pdf_url <> ''
+  AND NOT EXISTS (SELECT 1 FROM consent_documents d WHERE d.patient_id = p.id)
+ON CONFLICT (patient_id, version) DO NOTHING;
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestReportLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	service := services.NewReportService()
+	creator, collaborator := f.users[actorCreator], f.users[actorCollaborator]
+
+	status := func(report domains.ProfessionalReport) domains.ReportStatus {
+		var saved domains.ProfessionalReport
+		f.db.First(&saved, "id = ?", report.ID)
+		return saved.Status
+	}
+
+	t.Run("create rejects an overlapping period of the same author", func(t *testing.T) {
+		start, end, err := service.ParseRange("2026-05-01", "2026-05-31")
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := domains.ProfessionalReport{PatientID: f.patient.ID, AuthorID: collaborator.ID, DateRangeStart: start, DateRangeEnd: end, Content: "Mayo"}
+		if err := service.Create(f.db, &first, false); err != nil {
+			t.Fatal(err)
+		}
+		if first.Status != domains.ReportDraft {
+			t.Errorf("status = %s, want DRAFT", first.Status)
+		}
+
+		overlapping := domains.ProfessionalReport{PatientID: f.patient.ID, AuthorID: collaborator.ID,
+			DateRangeStart: end, DateRangeEnd: end.AddDate(0, 0, 10), Content: "Fin de mayo"}
+		if err := service.Create(f.db, &overlapping, true); err != services.ErrReportOverlap {
+			t.Errorf("overlapping create err = %v, want ErrReportOverlap", err)
+		}
+
+		// Otro autor sí puede cubrir el mismo periodo
+		other := domains.ProfessionalReport{PatientID: f.patient.ID, AuthorID: creator.ID, DateRangeStart: start, DateRangeEnd: end, Content: "Mayo"}
+		if err := service.Create(f.db, &other, true); err != nil {
+			t.Errorf("other author create err = %v", err)
+		}
+		if other.Status != domains.ReportSubmitted || other.SubmittedAt == nil {
+			t.Errorf("submitted create = %s (submitted_at %v), want SUBMITTED", other.Status, other.SubmittedAt)
+		}
+	})
+
+	t.Run("only the author edits and submits drafts", func(t *testing.T) {
+		report := f.report(collaborator, f.patient.ID, domains.ReportDraft)
+		content := "Avance con observaciones"
+		if err := service.Update(f.db, &report, creator, domains.UpdateReportInput{Content: &content}); err != services.ErrReportNotAuthor {
+			t.Errorf("update by another user err = %v, want ErrReportNotAuthor", err)
+		}
+		if err := service.Update(f.db, &report, collaborator, domains.UpdateReportInput{Content: &content}); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.Submit(f.db, &report, collaborator); err != nil {
+			t.Fatal(err)
+		}
+		if status(report) != domains.ReportSubmitted {
+			t.Errorf("status after submit = %s, want SUBMITTED", status(report))
+		}
+		if err := service.Submit(f.db, &report, collaborator); err != services.ErrReportNotDraft {
+			t.Errorf("second submit err = %v, want ErrReportNotDraft", err)
+		}
+		if err := service.Update(f.db, &report, collaborator, domains.UpdateReportInput{Content: &content}); err != services.ErrReportNotDraft {
+			t.Errorf("update after submit err = %v, want ErrReportNotDraft", err)
+		}
+	})
+
+	t.Run("the patient creator approves and returns", func(t *testing.T) {
+		approved := f.report(collaborator, f.patient.ID, domains.ReportSubmitted)
+		if err := service.Approve(f.db, &approved, creator); err != nil {
+			t.Fatal(err)
+		}
+		if status(approved) != domains.ReportApproved || approved.ReviewedByID == nil || *approved.ReviewedByID != creator.ID {
+			t.Errorf("approved report = %s reviewed by %v", status(approved), approved.ReviewedByID)
+		}
+		if err := service.Return(f.db, &approved, creator, "Tarde"); err != services.ErrReportNotSubmitted {
+			t.Errorf("return after approval err = %v, want ErrReportNotSubmitted", err)
+		}
+		if err := service.Delete(f.db, approved, collaborator); err != services.ErrReportApproved {
+			t.Errorf("delete approved err = %v, want ErrReportApproved", err)
+		}
+
+		returned := f.report(collaborator, f.patient.ID, domains.ReportSubmitted)
+		if err := service.Return(f.db, &returned, creator, "Falta el plan del próximo mes"); err != nil {
+			t.Fatal(err)
+		}
+		var saved domains.ProfessionalReport
+		f.db.First(&saved, "id = ?", returned.ID)
+		if saved.Status != domains.ReportDraft || saved.ReviewNotes != "Falta el plan del próximo mes" {
+			t.Errorf("returned report = %s %q, want DRAFT with notes", saved.Status, saved.ReviewNotes)
+		}
+	})
+
+	t.Run("nobody reviews their own report", func(t *testing.T) {
+		own := f.report(creator, f.patient.ID, domains.ReportSubmitted)
+		if err := service.Approve(f.db, &own, creator); err != services.ErrSelfReview {
+			t.Errorf("creator approving own report err = %v, want ErrSelfReview", err)
+		}
+		if err := service.Approve(f.db, &own, collaborator); err != services.ErrReviewNotAllowed {
+			t.Errorf("collaborator approving err = %v, want ErrReviewNotAllowed", err)
+		}
+		if err := service.Approve(f.db, &own, f.users[actorAdmin]); err != nil {
+			t.Fatalf("admin approving the creator's report err = %v", err)
+		}
+		if status(own) != domains.ReportApproved {
+			t.Errorf("status = %s, want APPROVED", status(own))
+		}
+
+		// Los reportes del equipo los revisa el creador, no un ADMIN
+		team := f.report(collaborator, f.patient.ID, domains.ReportSubmitted)
+		if err := service.Approve(f.db, &team, f.users[actorAdmin]); err != services.ErrReviewNotAllowed {
+			t.Errorf("admin approving a team report err = %v, want ErrReviewNotAllowed", err)
+		}
+	})
+}
+
+func TestReportSubmissionNotifiesTheReviewer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	notifications := services.NewNotificationService(config.LoadConfig())
+
+	count := func(userID uuid.UUID, report domains.ProfessionalReport) int64 {
+		services.WaitForNotifications()
+		var n int64
+		f.db.Model(&domains.Notification{}).
+			Where("user_id = ? AND type = ? AND related_id = ?", userID, domains.NotifReportReview, report.ID).
+			Count(&n)
+		return n
+	}
+
+	team := f.report(f.users[actorCollaborator], f.patient.ID, domains.ReportSubmitted)
+	notifications.NotifyReportReview(team, f.users[actorCollaborator])
+	if count(f.users[actorCreator].ID, team) != 1 || count(f.users[actorAdmin].ID, team) != 0 {
+		t.Errorf("team report: creator/admin notifications = %d/%d, want 1/0", count(f.users[actorCreator].ID, team), count(f.users[actorAdmin].ID, team))
+	}
+
+	own := f.report(f.users[actorCreator], f.patient.ID, domains.ReportSubmitted)
+	notifications.NotifyReportReview(own, f.users[actorCreator])
+	if count(f.users[actorCreator].ID, own) != 0 || count(f.users[actorAdmin].ID, own) != 1 {
+		t.Errorf("creator's report: creator/admin notifications = %d/%d, want 0/1", count(f.users[actorCreator].ID, own), count(f.users[actorAdmin].ID, own))
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/database"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/handlers/admin"
+	"bitacora-medica-backend/api/handlers/auth"
+	"bitacora-medica-backend/api/handlers/collaborations"
+	"bitacora-medica-backend/api/handlers/common"
+	"bitacora-medica-backend/api/handlers/incidents"
+	"bitacora-medica-backend/api/handlers/notifications"
+	"bitacora-medica-backend/api/handlers/patients"
+	"bitacora-medica-backend/api/handlers/reports"
+	"bitacora-medica-backend/api/handlers/sessions"
+	"bitacora-medica-backend/api/handlers/support"
+	"bitacora-medica-backend/api/middleware"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// setupRouter registra middlewares y rutas de la API (separado de main para probar las rutas reales)
+func setupRouter(cfg *config.Config, digests *services.DigestService) *gin.Engine {
+	// gin.Default() registra la URI completa; el logger propio omite el query string
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), gin.Recovery())
+
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"https://tradelog-app.vercel.app", "https://cron-job.org", "http://localhost:5173"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Límite de memoria de c.FormFile. Las subidas de archivos leen el multipart en streaming
+	// (ver handlers/common/upload.go), así que no dependen de este valor.
+	r.MaxMultipartMemory = 8 << 20
+
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "pong"})
+	})
+
+	// Links firmados del almacenamiento local (STORAGE_DRIVER=local, desarrollo / self-hosting)
+	if localStore, ok := services.GetBlobStore(cfg).(*services.LocalBlobStore); ok {
+		r.GET("/files/:bucket/*key", common.ServeLocalFileHandler(localStore))
+	}
+
+	api := r.Group("/api")
+
+	// Pasamos 'cfg' al middleware para validar JWT
+	api.Use(middleware.AuthMiddleware(cfg))
+	{
+		authGroup := api.Group("/auth")
+		{
+			authGroup.PUT("/profile", auth.UpdateProfileHandler())
+			authGroup.GET("/me", auth.GetMeHandler())
+		}
+
+		// --- GRUPO DE PACIENTES ---
+		patientsGroup := api.Group("/patients")
+		{
+			patientsGroup.POST("/", patients.CreatePatientHandler(cfg))
+
+			patientsGroup.GET("/", patients.ListPatientsHandler())
+
+			// NUEVO: Perfil Unificado (Ojo de Dios del Paciente)
+			// Solo creador, colaboradores aceptados o ADMIN
+			patientsGroup.GET("/:id", middleware.RequirePatientAccess("id"), patients.GetPatientProfileHandler())
+
+			patientsGroup.PUT("/:id", middleware.RequirePatientAccess("id"), patients.UpdatePatientHandler())
+
+			// Evolución de signos vitales para gráficos (?from=&to=&metrics=&bucket=day|week)
+			patientsGroup.GET("/:id/vitals", middleware.RequirePatientAccess("id"), patients.GetPatientVitalsHandler(cfg))
+
+			// Consentimiento informado: historial de versiones, nueva versión y revocación (creador o ADMIN)
+			patientsGroup.GET("/:id/consents", middleware.RequirePatientAccess("id"), patients.ListConsentsHandler(cfg))
+			patientsGroup.POST("/:id/consents", patients.CreateConsentVersionHandler(cfg))
+			patientsGroup.POST("/:id/consents/revoke", patients.RevokeConsentHandler(cfg))
+		}
+
+		sessionsGroup := api.Group("/sessions")
+		{
+			// CREATE
+			sessionsGroup.POST("/", sessions.CreateSessionHandler(cfg))
+
+			// READ (Listar con filtros: ?patient_id=...&has_incident=true)
+			sessionsGroup.GET("/", sessions.ListSessionsHandler())
+
+			// READ ONE (Detalle específico)
+			sessionsGroup.GET("/:id", sessions.GetSessionHandler())
+
+			// UPDATE (Solo autor)
+			sessionsGroup.PUT("/:id", sessions.UpdateSessionHandler(cfg))
+
+			// DELETE (Solo autor - Soft Delete)
+			sessionsGroup.DELETE("/:id", sessions.DeleteSessionHandler(cfg))
+
+			// HISTORIAL (versiones anteriores y diff: ?from=1&to=current)
+			sessionsGroup.GET("/:id/revisions", sessions.ListSessionRevisionsHandler())
+			sessionsGroup.GET("/:id/revisions/diff", sessions.DiffSessionRevisionsHandler())
+
+			// FIRMA CLÍNICA (autor firma, creador del paciente co-firma; luego solo adendas)
+			sessionsGroup.POST("/:id/sign", sessions.SignSessionHandler())
+			sessionsGroup.POST("/:id/cosign", sessions.CosignSessionHandler())
+			sessionsGroup.GET("/:id/addenda", sessions.ListAddendaHandler())
+			sessionsGroup.POST("/:id/addenda", sessions.CreateAddendumHandler())
+		}
+
+		// Incidentes: seguimiento, asignación y resolución (?patient_id=...&status=OPEN,UNDER_REVIEW&assigned=me)
+		incidentsGroup := api.Group("/incidents")
+		{
+			incidentsGroup.POST("/", incidents.CreateIncidentHandler(cfg))
+			incidentsGroup.GET("/", incidents.ListIncidentsHandler(cfg))
+
+			// Exportación regulatoria (?from=&to=&format=csv|jsonl&patient=pseudonym|full|none) y totales
+			incidentsGroup.GET("/export", incidents.ExportIncidentsHandler(cfg))
+			incidentsGroup.GET("/export/summary", incidents.IncidentsSummaryHandler(cfg))
+
+			incidentsGroup.GET("/:id", incidents.GetIncidentHandler(cfg))
+			incidentsGroup.PATCH("/:id", incidents.UpdateIncidentHandler(cfg))
+			incidentsGroup.POST("/:id/actions", incidents.AddIncidentActionHandler(cfg))
+			incidentsGroup.POST("/:id/actions/:actionId/complete", incidents.CompleteIncidentActionHandler(cfg))
+			incidentsGroup.POST("/:id/resolve", incidents.ResolveIncidentHandler(cfg))
+		}
+
+		uploads := api.Group("/uploads")
+		uploads.POST("/image", common.UploadImageHandler(cfg))
+
+		uploads.POST("/consent", common.UploadConsentHandler(cfg))
+
+		// Subidas reanudables por partes (archivos grandes):
+		// POST /sessions -> PUT /sessions/:id/chunks?offset=N (n veces) -> POST /sessions/:id/complete
+		uploads.POST("/sessions", common.StartUploadSessionHandler(cfg))
+		uploads.GET("/sessions/:id", common.GetUploadSessionHandler(cfg))
+		uploads.PUT("/sessions/:id/chunks", common.UploadChunkHandler(cfg))
+		uploads.POST("/sessions/:id/complete", common.CompleteUploadSessionHandler(cfg))
+		uploads.DELETE("/sessions/:id", common.AbortUploadSessionHandler(cfg))
+
+		// Archivos clínicos (buckets privados): link firmado o ?stream=true
+		api.GET("/files/:bucket/*key", common.GetFileHandler(cfg))
+
+		// --- BANDEJA DE NOTIFICACIONES (Ícono de campana) ---
+		notificationsGroup := api.Group("/notifications")
+		{
+			// Listar: GET /api/notifications?unread=true&page=1&limit=20
+			notificationsGroup.GET("/", notifications.ListNotificationsHandler())
+			notificationsGroup.GET("/unread-count", notifications.UnreadCountHandler())
+
+			// Tiempo real (SSE): POST /api/notifications/stream-ticket y luego
+			// GET /api/notifications/stream?ticket=... (ticket de un solo uso)
+			notificationsGroup.POST("/stream-ticket", notifications.IssueStreamTicketHandler())
+			notificationsGroup.GET("/stream", notifications.StreamNotificationsHandler())
+
+			// Preferencias por tipo de evento (IN_APP, EMAIL, DIGEST, OFF)
+			notificationsGroup.GET("/preferences", notifications.GetPreferencesHandler())
+			notificationsGroup.PUT("/preferences", notifications.UpdatePreferencesHandler())
+
+			notificationsGroup.PUT("/read-all", notifications.MarkAllAsReadHandler())
+			notificationsGroup.PUT("/:id/read", notifications.MarkAsReadHandler())
+
+			notificationsGroup.DELETE("/:id", notifications.DeleteNotificationHandler())
+		}
+	}
+
+	collabGroup := api.Group("/collaborations")
+	{
+		// Invitar: POST /api/collaborations/invite
+		collabGroup.POST("/invite", collaborations.InviteCollabHandler(cfg))
+
+		// Responder: PUT /api/collaborations/:id/respond
+		// :id es el ID de la COLABORACIÓN (no del paciente ni usuario)
+		collabGroup.PUT("/:id/respond", collaborations.RespondInvitationHandler(cfg))
+
+		collabGroup.GET("/pending", collaborations.GetPendingInvitationsHandler())
+	}
+
+	// --- GRUPO REPORTES ---
+	reportsGroup := api.Group("/reports")
+	{
+		// Individual: POST /api/reports/ (Kine sube su resumen mensual; queda como borrador)
+		reportsGroup.POST("/", reports.CreateIndividualReportHandler(cfg))
+		reportsGroup.GET("/", reports.ListReportsHandler())
+		reportsGroup.GET("/:id", reports.GetReportHandler())
+		reportsGroup.PUT("/:id", reports.UpdateReportHandler())
+		reportsGroup.DELETE("/:id", reports.DeleteReportHandler())
+
+		// Ciclo de vida: el autor envía, el creador del paciente aprueba o devuelve con observaciones
+		reportsGroup.POST("/:id/submit", reports.SubmitReportHandler(cfg))
+		reportsGroup.POST("/:id/approve", reports.ApproveReportHandler(cfg))
+		reportsGroup.POST("/:id/return", reports.ReturnReportHandler(cfg))
+
+		// Maestro: GET /api/reports/master?patient_id=...&start_date=...&end_date=...[&format=pdf]
+		// (Admin/Dueño obtiene la visión global; solo incluye reportes APPROVED)
+		reportsGroup.GET("/master", reports.GenerateMasterReportHandler(cfg))
+	}
+
+	// --- GRUPO SOPORTE (Accesible para todos) ---
+	supportGroup := api.Group("/support")
+	{
+		supportGroup.POST("/", support.CreateTicketHandler())
+		supportGroup.GET("/", support.ListTicketsHandler()) // Admin ve todo, User ve suyo
+
+		// Responder ticket (Solo Admin)
+		supportGroup.PUT("/:id/reply", middleware.RequireAdmin(), support.ReplyTicketHandler())
+	}
+
+	// --- GRUPO ADMIN (Protegido por RequireAdmin) ---
+	adminGroup := api.Group("/admin")
+	adminGroup.Use(middleware.RequireAdmin())
+	{
+		// Gestión de Usuarios
+		adminGroup.GET("/users/pending", admin.ListPendingUsersHandler())
+		adminGroup.PUT("/users/:id/review", admin.ReviewUserHandler(cfg))
+
+		// Cola de correos: inspeccionar y reintentar envíos fallidos
+		adminGroup.GET("/emails", admin.ListOutboxEmailsHandler())
+		adminGroup.POST("/emails/:id/retry", admin.RetryOutboxEmailHandler())
+
+		// Auditoría de datos clínicos (?patient_id=&user_id=&from=&to=)
+		adminGroup.GET("/audit", admin.ListAuditLogsHandler())
+
+		// Resumen de notificaciones: forzar ejecución (?frequency=DAILY|WEEKLY)
+		adminGroup.POST("/digests/run", admin.RunDigestHandler(digests))
+
+		// Dashboard (KPIs simples)
+		adminGroup.GET("/dashboard", func(c *gin.Context) {
+			// Implementación rápida de KPIs [cite: 113]
+			var totalUsers, activePatients, incidentsToday int64
+			db := database.GetDB()
+			db.Model(&domains.User{}).Count(&totalUsers)
+			db.Model(&domains.Patient{}).Count(&activePatients)
+			db.Model(&domains.Session{}).Where("has_incident = ?", true).Count(&incidentsToday)
+
+			c.JSON(200, gin.H{
+				"total_users":        totalUsers,
+				"active_patients":    activePatients,
+				"incidents_all_time": incidentsToday,
+			})
+		})
+	}
+
+	return r
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bitacora-medica-backend/api/config"
+	"bitacora-medica-backend/api/domains"
+	"bitacora-medica-backend/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// chunkedUpload registra una subida con sus partes ya guardadas en el almacenamiento
+func chunkedUpload(t *testing.T, f *accessFixture, cfg *config.Config, status domains.UploadSessionStatus, expiresAt time.Time, parts ...string) domains.UploadSession {
+	t.Helper()
+	store := services.GetBlobStore(cfg)
+	session := domains.UploadSession{
+		OwnerID: f.users[actorCreator].ID, Kind: domains.UploadKindConsent, Status: status, ExpiresAt: expiresAt,
+	}
+	for _, part := range parts {
+		key := "uploads/" + uuid.New().String()
+		if err := store.Put(context.Background(), services.BucketPatientsConsent, key, strings.NewReader(part), int64(len(part)), "application/octet-stream"); err != nil {
+			t.Fatal(err)
+		}
+		session.ChunkKeys = append(session.ChunkKeys, key)
+		session.TotalSize += int64(len(part))
+	}
+	session.ReceivedSize = session.TotalSize
+	f.create(&session)
+	return session
+}
+
+func chunksLeft(t *testing.T, cfg *config.Config, session domains.UploadSession) int {
+	t.Helper()
+	left := 0
+	for _, key := range session.ChunkKeys {
+		body, _, err := services.GetBlobStore(cfg).Get(context.Background(), services.BucketPatientsConsent, key)
+		if errors.Is(err, services.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+		left++
+	}
+	return left
+}
+
+func TestCompleteUploadSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	service := services.NewUploadSessionService(cfg)
+
+	session := chunkedUpload(t, f, cfg, domains.UploadSessionActive, time.Now().Add(time.Hour),
+		"%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\n", "endobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF")
+	file, err := service.Complete(context.Background(), f.users[actorCreator], session.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved domains.UploadSession
+	f.db.First(&saved, "id = ?", session.ID)
+	if saved.Status != domains.UploadSessionCompleted || saved.ResultKey != file.Key {
+		t.Errorf("session = %s with result %q, want COMPLETED with %q", saved.Status, saved.ResultKey, file.Key)
+	}
+	if left := chunksLeft(t, cfg, session); left != 0 {
+		t.Errorf("%d chunks left after completing, want 0", left)
+	}
+	if _, err := service.Complete(context.Background(), f.users[actorCreator], session.ID.String()); err != services.ErrUploadSessionClosed {
+		t.Errorf("second complete err = %v, want ErrUploadSessionClosed", err)
+	}
+}
+
+func TestSweepExpiresStalledProcessing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAccessFixture(t)
+	cfg := config.LoadConfig()
+	service := services.NewUploadSessionService(cfg)
+	now := time.Now()
+
+	// Un Complete que se cayó a mitad de camino deja la subida en PROCESSING con sus partes
+	stalled := chunkedUpload(t, f, cfg, domains.UploadSessionProcessing, now.Add(-time.Minute), "%PDF-1.7\n")
+	running := chunkedUpload(t, f, cfg, domains.UploadSessionProcessing, now.Add(cfg.UploadProcessingTimeout), "%PDF-1.7\n")
+
+	expired, err := service.SweepExpired(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 1 {
+		t.Errorf("expired %d sessions, want 1", expired)
+	}
+
+	status := func(session domains.UploadSession) domains.UploadSessionStatus {
+		var saved domains.UploadSession
+		f.db.First(&saved, "id = ?", session.ID)
+		return saved.Status
+	}
+	if status(stalled) != domains.UploadSessionExpired || chunksLeft(t, cfg, stalled) != 0 {
+		t.Errorf("stalled session = %s with %d chunks, want EXPIRED without chunks", status(stalled), chunksLeft(t, cfg, stalled))
+	}
+	if status(running) != domains.UploadSessionProcessing || chunksLeft(t, cfg, running) != 1 {
+		t.Errorf("session still within its processing deadline = %s with %d chunks, want untouched", status(running), chunksLeft(t, cfg, running))
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPatientVitalsQueryValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	f := newAccessFixture(t)
+	path := "/api/patients/" + f.patient.ID.String() + "/vitals"
+
+	// Se rechazan antes de consultar (la serie usa SQL propio de Postgres)
+	for _, query := range []string{"?bucket=month", "?bucket=", "?metrics=heart_rate,pulse", "?from=2026-13-01"} {
+		if rec := f.do(f.users[actorCreator], http.MethodGet, path+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400: %s", query, rec.Code, rec.Body.String())
+		}
+	}
+}